@@ -10,6 +10,7 @@ import (
 	"github.com/evergreen-ci/evergreen/model/patch"
 	"github.com/evergreen-ci/evergreen/plugin"
 	_ "github.com/evergreen-ci/evergreen/plugin/config"
+	"github.com/evergreen-ci/evergreen/plugin/external"
 	"github.com/evergreen-ci/evergreen/util"
 	"net/http"
 	"os"
@@ -46,6 +47,17 @@ const (
 	CompletedSuccess
 	// CompletedFailure indicates task successfully ran to completion but failed.
 	CompletedFailure
+	// DiskQuotaExceeded indicates the task used more disk than its distro's
+	// per-task quota.
+	DiskQuotaExceeded
+	// SetupFailure indicates the project's pre block ran past its timeout,
+	// or one of its commands failed in a project whose pre errors fail tasks.
+	SetupFailure
+	// ExecTimeout indicates the task ran for longer than its exec_timeout.
+	ExecTimeout
+	// IncompatibleAgent indicates the API server rejected the agent's
+	// protocol version.
+	IncompatibleAgent
 )
 
 const (
@@ -116,6 +128,10 @@ type SignalHandler struct {
 	Post *model.YAMLCommandSet
 	// Timeout is a set of commands to run if/when an IdleTimeout signal is received.
 	Timeout *model.YAMLCommandSet
+	// TimeoutDiagnostics configures what to collect about the task's
+	// processes if/when an IdleTimeout or ExecTimeout signal is received,
+	// before they're killed. If it's nil, only their stacks are collected.
+	TimeoutDiagnostics *model.TimeoutDiagnostics
 	// Channel on which to send/receive notifications from background tasks
 	// (timeouts, heartbeat failures, abort signals, etc).
 	signalChan chan Signal
@@ -147,6 +163,10 @@ type Agent struct {
 	// intervals, to the API server.
 	statsCollector *StatsCollector
 
+	// resourceSampler samples the resources in use on the host, and sends
+	// the samples to the API server to be kept with the task.
+	resourceSampler *ResourceSampler
+
 	// logger handles all the logging (task, system, execution, local)
 	// by appending log messages for each type to the correct stream.
 	logger *StreamLogger
@@ -170,8 +190,28 @@ type Agent struct {
 	// assigned task.
 	taskConfig *model.TaskConfig
 
+	// diskWatcher measures the disk used by the task's directory, and
+	// raises a signal if the task goes over its distro's quota.
+	diskWatcher *DiskWatcher
+
+	// taskDir is the directory the agent created for the task to run in,
+	// which is removed once the task is done.
+	taskDir string
+
+	// setupErr is why the project's pre block failed, if it did.
+	setupErr error
+
+	// keepWorkDir has the task run right in the distro's working directory,
+	// and leaves it in place afterwards, instead of running the task in a
+	// directory of its own.
+	keepWorkDir bool
+
 	// Registry manages plugins available for the agent.
 	Registry plugin.Registry
+
+	// PluginDir holds the executables of external plugins to make available
+	// alongside the ones compiled into the agent, if set.
+	PluginDir string
 }
 
 // finishAndAwaitCleanup sends the returned TaskEndResponse and error - as
@@ -184,16 +224,25 @@ func (agt *Agent) finishAndAwaitCleanup(status Signal, completed chan FinalTaskF
 	if agt.statsCollector.stop != nil {
 		agt.statsCollector.stop <- true
 	}
+	agt.resourceSampler.Stop()
 	if agt.idleTimeoutWatcher.stop != nil {
 		agt.idleTimeoutWatcher.stop <- true
 	}
 	if agt.maxExecTimeoutWatcher != nil && agt.maxExecTimeoutWatcher.stop != nil {
 		agt.maxExecTimeoutWatcher.stop <- true
 	}
+	if agt.diskWatcher != nil {
+		agt.diskWatcher.Stop()
+	}
 	agt.APILogger.FlushAndWait()
 	taskFinishFunc := <-completed // waiting for HandleSignals() to finish
 	ret, err := taskFinishFunc()  // calling taskCom.End(), or similar
 	agt.APILogger.FlushAndWait()  // any logs from HandleSignals() or End()
+	if agt.APILogger.Spool != nil {
+		if err := agt.APILogger.Spool.Close(); err != nil {
+			agt.logger.LogLocal(slogger.WARN, "Error removing log spool: %v", err)
+		}
+	}
 	return ret, err
 }
 
@@ -213,28 +262,45 @@ func (agt *Agent) getTaskEndDetail() *apimodels.TaskEndDetail {
 // HandleSignals listens on its signal channel and properly handles any signal received.
 func (sh *SignalHandler) HandleSignals(agt *Agent, completed chan FinalTaskFunc) {
 	receivedSignal := <-sh.signalChan
+	detail := agt.getTaskEndDetail()
+
+	if receivedSignal == IdleTimeout || receivedSignal == ExecTimeout {
+		diagnostics := sh.TimeoutDiagnostics
+		if diagnostics == nil {
+			diagnostics = &model.TimeoutDiagnostics{}
+		}
+		agt.logger.LogTask(slogger.INFO, "Task timed out; collecting diagnostics before stopping it.")
+		start := time.Now()
+		agt.collectTimeoutDiagnostics(diagnostics)
+		agt.logger.LogTask(slogger.INFO, "Finished collecting timeout diagnostics in %v.", time.Since(start).String())
+	}
 
 	// Stop any running commands.
 	close(sh.KillChan)
 
-	detail := agt.getTaskEndDetail()
-
 	switch receivedSignal {
 	case IncorrectSecret:
 		agt.logger.LogLocal(slogger.ERROR, "Secret doesn't match - exiting.")
 		os.Exit(1)
 	case HeartbeatMaxFailed:
 		agt.logger.LogExecution(slogger.ERROR, "Max heartbeats failed - stopping.")
+	case IncompatibleAgent:
+		agt.logger.LogExecution(slogger.ERROR, "API server rejected the agent - stopping.")
 	case AbortedByUser:
 		detail.Status = evergreen.TaskUndispatched
 		agt.logger.LogTask(slogger.WARN, "Received abort signal - stopping.")
-	case IdleTimeout:
-		agt.logger.LogTask(slogger.ERROR, "Task timed out: '%v'", detail.Description)
+	case IdleTimeout, ExecTimeout:
+		if receivedSignal == ExecTimeout {
+			agt.logger.LogTask(slogger.ERROR, "Task exceeded its exec timeout: '%v'", detail.Description)
+		} else {
+			agt.logger.LogTask(slogger.ERROR, "Task timed out: '%v'", detail.Description)
+		}
 		detail.TimedOut = true
 		if sh.Timeout != nil {
 			agt.logger.LogTask(slogger.INFO, "Running task-timeout commands.")
 			start := time.Now()
-			err := agt.RunCommands(sh.Timeout.List(), false, nil)
+			err := agt.runCommandBlock(sh.Timeout.List(), false, nil, apimodels.CommandBlockTimeout,
+				blockTimeout(agt.taskConfig.Project.CallbackTimeoutSecs, DefaultCallbackTimeout))
 			if err != nil {
 				agt.logger.LogExecution(slogger.ERROR, "Error running task-timeout command: %v", err)
 			}
@@ -245,17 +311,34 @@ func (sh *SignalHandler) HandleSignals(agt *Agent, completed chan FinalTaskFunc)
 		agt.logger.LogTask(slogger.INFO, "Task completed - SUCCESS.")
 	case CompletedFailure:
 		agt.logger.LogTask(slogger.INFO, "Task completed - FAILURE.")
+	case DiskQuotaExceeded:
+		detail.Type = model.SystemCommandType
+		detail.Description = agt.diskWatcher.QuotaMessage()
+		agt.logger.LogTask(slogger.ERROR, "Task failed: %v", detail.Description)
+	case SetupFailure:
+		detail.Type = model.SystemCommandType
+		if _, ok := agt.setupErr.(*blockTimeoutError); ok {
+			detail.TimedOut = true
+		}
+		agt.logger.LogTask(slogger.ERROR, "Task setup failed: %v", agt.setupErr)
+	}
+	// a failed pre block is recorded even if the task went on to run
+	if agt.setupErr != nil {
+		detail.SetupFailed = true
 	}
 
+	// whatever happens in the post block, the task's status stays as it is
 	if sh.Post != nil {
 		agt.logger.LogTask(slogger.INFO, "Running post-task commands.")
 		start := time.Now()
-		err := agt.RunCommands(sh.Post.List(), false, nil)
+		err := agt.runCommandBlock(sh.Post.List(), false, nil, apimodels.CommandBlockPost,
+			blockTimeout(agt.taskConfig.Project.PostTimeoutSecs, DefaultPostTimeout))
 		if err != nil {
 			agt.logger.LogExecution(slogger.ERROR, "Error running post-task command: %v", err)
 		}
 		agt.logger.LogTask(slogger.INFO, "Finished running post-task commands in %v.", time.Since(start).String())
 	}
+	agt.removeTaskDir()
 	agt.logger.LogExecution(slogger.INFO, "Sending final status as: %v", detail.Status)
 
 	// make the API call to end the task
@@ -324,8 +407,37 @@ func New(apiServerURL, taskId, taskSecret, logFile, cert string) (*Agent, error)
 		return nil, err
 	}
 
+	agt, err := newAgent(httpCommunicator, sigChan, logFile)
+	if err != nil {
+		return nil, err
+	}
+	httpCommunicator.Logger = agt.logger.Execution
+
+	// hold on to logs through any trouble reaching the API server
+	agt.APILogger.Spool, err = NewLogSpool(os.TempDir(), taskId, DefaultLogSpoolSize)
+	if err != nil {
+		return nil, err
+	}
+	return agt, nil
+}
+
+// NewLocal creates a new agent to run a task on the local machine, with no
+// API server; everything the task reports is handled by the communicator.
+func NewLocal(communicator *LocalCommunicator, logFile string) (*Agent, error) {
+	agt, err := newAgent(communicator, make(chan Signal, 1), logFile)
+	if err != nil {
+		return nil, err
+	}
+	// the task runs in the developer's own directory
+	agt.keepWorkDir = true
+	return agt, nil
+}
+
+// newAgent sets up an agent that reports everything about its task through
+// the given communicator.
+func newAgent(communicator TaskCommunicator, sigChan chan Signal, logFile string) (*Agent, error) {
 	// set up logger to API server
-	apiLogger := NewAPILogger(httpCommunicator)
+	apiLogger := NewAPILogger(communicator)
 	idleTimeoutWatcher := &TimeoutWatcher{duration: DefaultIdleTimeout}
 
 	// set up timeout logger, local and API logger streams
@@ -333,14 +445,13 @@ func New(apiServerURL, taskId, taskSecret, logFile, cert string) (*Agent, error)
 	if err != nil {
 		return nil, err
 	}
-	httpCommunicator.Logger = streamLogger.Execution
 
 	// set up the heartbeat ticker
 	hbTicker := &HeartbeatTicker{
 		MaxFailedHeartbeats: 10,
 		SignalChan:          sigChan,
-		TaskCommunicator:    httpCommunicator,
-		Logger:              httpCommunicator.Logger,
+		TaskCommunicator:    communicator,
+		Logger:              streamLogger.Execution,
 		Interval:            DefaultHeartbeatInterval,
 	}
 
@@ -354,9 +465,10 @@ func New(apiServerURL, taskId, taskSecret, logFile, cert string) (*Agent, error)
 
 	agt := &Agent{
 		logger:             streamLogger,
-		TaskCommunicator:   httpCommunicator,
+		TaskCommunicator:   communicator,
 		heartbeater:        hbTicker,
 		statsCollector:     statsCollector,
+		resourceSampler:    NewResourceSampler(communicator, streamLogger.System),
 		idleTimeoutWatcher: idleTimeoutWatcher,
 		APILogger:          apiLogger,
 		signalChan:         sigChan,
@@ -367,8 +479,18 @@ func New(apiServerURL, taskId, taskSecret, logFile, cert string) (*Agent, error)
 }
 
 // RunTask manages the process of running a task. It returns a response
-// indicating the end result of the task.
+// indicating the end result of the task, or ErrIncompatibleAgent once the
+// task has stopped if the API server rejected the agent along the way.
 func (agt *Agent) RunTask() (*apimodels.TaskEndResponse, error) {
+	resp, err := agt.runTask()
+	if httpComm, ok := agt.TaskCommunicator.(*HTTPCommunicator); ok && httpComm.Incompatible() {
+		return nil, ErrIncompatibleAgent
+	}
+	return resp, err
+}
+
+// runTask runs the task, and reports how it ended to the API server.
+func (agt *Agent) runTask() (*apimodels.TaskEndResponse, error) {
 	agt.CheckIn(InitialSetupCommand, InitialSetupTimeout)
 
 	agt.logger.LogLocal(slogger.INFO, "Local logger initialized.")
@@ -394,7 +516,7 @@ func (agt *Agent) RunTask() (*apimodels.TaskEndResponse, error) {
 	execTimeout := time.Duration(pt.ExecTimeout) * time.Second
 	// Set master task timeout, only if included in the taskConfig
 	if execTimeout != 0 {
-		agt.maxExecTimeoutWatcher = &TimeoutWatcher{duration: execTimeout}
+		agt.maxExecTimeoutWatcher = &TimeoutWatcher{duration: execTimeout, signal: ExecTimeout}
 	}
 
 	agt.logger.LogExecution(slogger.INFO, "Fetching expansions for project %v...", taskConfig.Task.Project)
@@ -405,16 +527,42 @@ func (agt *Agent) RunTask() (*apimodels.TaskEndResponse, error) {
 		return nil, err
 	}
 
-	taskConfig.Expansions.Update(*expVars)
+	if !agt.keepWorkDir {
+		taskConfig.WorkDir = taskDirectory(taskConfig.Distro.WorkDir, taskConfig.Task)
+		taskConfig.Expansions.Put("workdir", taskConfig.WorkDir)
+	}
+	taskConfig.Expansions.Update(expVars.Vars)
+
+	// keep private variables out of the logs from here on
+	privateVars := map[string]string{}
+	taskConfig.PrivateExpansions = map[string]bool{}
+	for name, private := range expVars.PrivateVars {
+		if private {
+			privateVars[name] = expVars.Vars[name]
+			taskConfig.PrivateExpansions[name] = true
+		}
+	}
+	agt.logger.RedactValues(privateVars)
 
 	agt.taskConfig = taskConfig
 
+	// the task's disk use is watched once its directory is ready, and
+	// included in the resource samples from then on
+	agt.diskWatcher = &DiskWatcher{
+		Dir:      taskConfig.WorkDir,
+		Quota:    int64(taskConfig.Distro.DiskQuota) * megabyte,
+		Interval: DefaultDiskCheckInterval,
+		Logger:   agt.logger.System,
+	}
+	agt.resourceSampler.DiskWatcher = agt.diskWatcher
+
 	// initialize agent's signal handler to listen for signals
 	signalHandler := &SignalHandler{
-		KillChan:   make(chan bool),
-		signalChan: agt.signalChan,
-		Post:       agt.taskConfig.Project.Post,
-		Timeout:    agt.taskConfig.Project.Timeout,
+		KillChan:           make(chan bool),
+		signalChan:         agt.signalChan,
+		Post:               agt.taskConfig.Project.Post,
+		Timeout:            agt.taskConfig.Project.Timeout,
+		TimeoutDiagnostics: agt.taskConfig.Project.TimeoutDiagnostics,
 	}
 
 	agt.signalHandler = signalHandler
@@ -428,6 +576,7 @@ func (agt *Agent) RunTask() (*apimodels.TaskEndResponse, error) {
 		agt.logger.LogExecution(slogger.ERROR, "error initializing agent plugins: %v", err)
 		return agt.finishAndAwaitCleanup(CompletedFailure, completed)
 	}
+	agt.registerExternalPlugins()
 
 	// notify API server that the task has been started.
 	agt.logger.LogExecution(slogger.INFO, "Reporting task started.")
@@ -436,11 +585,29 @@ func (agt *Agent) RunTask() (*apimodels.TaskEndResponse, error) {
 		return agt.finishAndAwaitCleanup(CompletedFailure, completed)
 	}
 
+	agt.CheckIn(DiskCheckCommand, InitialSetupTimeout)
+	if err = agt.prepareTaskDir(); err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "error preparing task directory: %v", err)
+		return agt.finishAndAwaitCleanup(CompletedFailure, completed)
+	}
+
+	// unless the project says otherwise, the task still runs if commands in
+	// the pre block fail, but not if the block times out
 	if agt.taskConfig.Project.Pre != nil {
 		agt.logger.LogExecution(slogger.INFO, "Running pre-task commands.")
-		err = agt.RunCommands(agt.taskConfig.Project.Pre.List(), false, nil)
+		preErrorFailsTask := agt.taskConfig.Project.PreErrorFailsTask
+		err = agt.runCommandBlock(agt.taskConfig.Project.Pre.List(), preErrorFailsTask, agt.signalHandler.KillChan,
+			apimodels.CommandBlockPre, blockTimeout(agt.taskConfig.Project.PreTimeoutSecs, DefaultPreTimeout))
 		if err != nil {
 			agt.logger.LogExecution(slogger.ERROR, "Running pre-task script failed: %v", err)
+			agt.setupErr = err
+			_, timedOut := err.(*blockTimeoutError)
+			_, stopped := err.(*blockStoppedError)
+			if preErrorFailsTask || timedOut || stopped {
+				return agt.finishAndAwaitCleanup(SetupFailure, completed)
+			}
+			agt.logger.LogTask(slogger.WARN, "Task setup failed, but the project doesn't fail"+
+				" tasks for that: %v", err)
 		}
 		agt.logger.LogExecution(slogger.INFO, "Finished running pre-task commands.")
 	}
@@ -456,9 +623,16 @@ func (agt *Agent) RunTaskCommands(completed chan FinalTaskFunc) (*apimodels.Task
 		return agt.finishAndAwaitCleanup(CompletedFailure, completed)
 	}
 
+	// the task's own timeout leaves out the time spent in the pre block,
+	// which has a timeout of its own. Default action is not to include a
+	// master timeout.
+	if agt.maxExecTimeoutWatcher != nil {
+		agt.maxExecTimeoutWatcher.NotifyTimeouts(agt.signalChan)
+	}
+
 	agt.logger.LogExecution(slogger.INFO, "Running task commands.")
 	start := time.Now()
-	err := agt.RunCommands(task.Commands, true, agt.signalHandler.KillChan)
+	err := agt.RunCommands(task.Commands, true, agt.signalHandler.KillChan, apimodels.CommandBlockTask)
 	agt.logger.LogExecution(slogger.INFO, "Finished running task commands in %v.", time.Since(start).String())
 
 	if err != nil {
@@ -469,33 +643,56 @@ func (agt *Agent) RunTaskCommands(completed chan FinalTaskFunc) (*apimodels.Task
 }
 
 // RunCommands takes a slice of commands and executes then sequentially.
-// If returnOnError is set, it returns immediately if one of the commands fails.
+// If returnOnError is set, it returns immediately if one of the commands fails;
+// otherwise it runs the rest of them, and returns the error of the first one
+// that failed.
 // All plugins listen on the stop channel and must terminate immediately when a
-// value is received.
-func (agt *Agent) RunCommands(commands []model.PluginCommandConf, returnOnError bool, stop chan bool) error {
+// value is received. A record of each command is reported to the API server
+// as being part of the given block of the task's commands.
+func (agt *Agent) RunCommands(commands []model.PluginCommandConf, returnOnError bool, stop chan bool, block string) error {
+	var firstErr error
 	for i, commandInfo := range commands {
+		record := &apimodels.CommandRecord{
+			Name:        commandInfo.Command,
+			DisplayName: commandInfo.DisplayName,
+			Function:    commandInfo.Function,
+			Block:       block,
+		}
+
 		parsedCommands, err := agt.Registry.ParseCommandConf(commandInfo, agt.taskConfig.Project.Functions)
 		if err != nil {
 			agt.logger.LogTask(slogger.ERROR, "Couldn't parse plugin command '%v': %v", commandInfo.Command, err)
+			agt.reportCommand(record, time.Now(), err)
 			if returnOnError {
+				agt.reportSkipped(commands[i+1:], block)
 				return err
 			}
+			if firstErr == nil {
+				firstErr = err
+			}
 			continue
 		}
 
 		cmds, err := agt.Registry.GetCommands(commandInfo, agt.taskConfig.Project.Functions)
 		if err != nil {
 			agt.logger.LogTask(slogger.ERROR, "Don't know how to run plugin action %s: %v", commandInfo.Command, err)
+			agt.reportCommand(record, time.Now(), err)
 			if returnOnError {
+				agt.reportSkipped(commands[i+1:], block)
 				return err
 			}
+			if firstErr == nil {
+				firstErr = err
+			}
 			continue
 		}
+		records := newCommandRecords(commandInfo, parsedCommands, cmds, block)
 
 		for j, cmd := range cmds {
 			fullCommandName := cmd.Plugin() + "." + cmd.Name()
 
 			parsedCommand := parsedCommands[j]
+			record = records[j]
 
 			if commandInfo.Function != "" {
 				fullCommandName = fmt.Sprintf(`'%v' in "%v"`, fullCommandName, commandInfo.Function)
@@ -509,6 +706,8 @@ func (agt *Agent) RunCommands(commands []model.PluginCommandConf, returnOnError
 			if !commandInfo.RunOnVariant(agt.taskConfig.BuildVariant.Name) {
 				agt.logger.LogTask(slogger.INFO, "Skipping command %v on variant %v (step %v of %v)",
 					fullCommandName, agt.taskConfig.BuildVariant.Name, i+1, len(commands))
+				record.Skipped = true
+				agt.reportCommand(record, time.Now(), nil)
 				continue
 			}
 
@@ -539,7 +738,14 @@ func (agt *Agent) RunCommands(commands []model.PluginCommandConf, returnOnError
 				for key, val := range commandInfo.Vars {
 					newVal, err := agt.taskConfig.Expansions.ExpandString(val)
 					if err != nil {
-						return fmt.Errorf("Can't expand '%v': %v", val, err)
+						err = fmt.Errorf("Can't expand '%v': %v", val, err)
+						agt.reportCommand(record, time.Now(), err)
+						for _, skipped := range records[j+1:] {
+							skipped.Skipped = true
+							agt.reportCommand(skipped, time.Now(), nil)
+						}
+						agt.reportSkipped(commands[i+1:], block)
+						return err
 					}
 					agt.taskConfig.Expansions.Put(key, newVal)
 				}
@@ -553,17 +759,97 @@ func (agt *Agent) RunCommands(commands []model.PluginCommandConf, returnOnError
 			err = cmd.Execute(commandLogger, pluginCom, agt.taskConfig, stop)
 
 			agt.logger.LogExecution(slogger.INFO, "Finished %v in %v", fullCommandName, time.Since(start).String())
+			agt.reportCommand(record, start, err)
 
 			if err != nil {
 				agt.logger.LogTask(slogger.ERROR, "Command failed: %v", err)
 				if returnOnError {
+					for _, skipped := range records[j+1:] {
+						skipped.Skipped = true
+						agt.reportCommand(skipped, time.Now(), nil)
+					}
+					agt.reportSkipped(commands[i+1:], block)
 					return err
 				}
+				if firstErr == nil {
+					firstErr = err
+				}
 				continue
 			}
 		}
 	}
-	return nil
+	return firstErr
+}
+
+// newCommandRecords returns a record for each of the commands that the
+// command in the project, which may be a function, runs.
+func newCommandRecords(commandInfo model.PluginCommandConf, parsedCommands []model.PluginCommandConf,
+	cmds []plugin.Command, block string) []*apimodels.CommandRecord {
+	records := []*apimodels.CommandRecord{}
+	for j, cmd := range cmds {
+		records = append(records, &apimodels.CommandRecord{
+			Name:        cmd.Plugin() + "." + cmd.Name(),
+			DisplayName: parsedCommands[j].DisplayName,
+			Function:    commandInfo.Function,
+			Block:       block,
+		})
+	}
+	return records
+}
+
+// reportSkipped reports the commands as skipped, since an earlier command in
+// their block failed.
+func (agt *Agent) reportSkipped(commands []model.PluginCommandConf, block string) {
+	for _, commandInfo := range commands {
+		parsedCommands, err := agt.Registry.ParseCommandConf(commandInfo, agt.taskConfig.Project.Functions)
+		if err == nil {
+			var cmds []plugin.Command
+			if cmds, err = agt.Registry.GetCommands(commandInfo, agt.taskConfig.Project.Functions); err == nil {
+				for _, record := range newCommandRecords(commandInfo, parsedCommands, cmds, block) {
+					record.Skipped = true
+					agt.reportCommand(record, time.Now(), nil)
+				}
+				continue
+			}
+		}
+
+		// commands that can't be made are reported as the project names them
+		agt.reportCommand(&apimodels.CommandRecord{
+			Name:        commandInfo.Command,
+			DisplayName: commandInfo.DisplayName,
+			Function:    commandInfo.Function,
+			Block:       block,
+			Skipped:     true,
+		}, time.Now(), nil)
+	}
+}
+
+// reportCommand sends the API server a record of a command that started at
+// the given time and has just finished with the given error. Failing to
+// report a command doesn't affect the task.
+func (agt *Agent) reportCommand(record *apimodels.CommandRecord, start time.Time, err error) {
+	record.StartTime = start
+	record.EndTime = time.Now()
+	if record.Skipped {
+		record.Status = apimodels.CommandSkipped
+	} else {
+		record.Status = evergreen.TaskSucceeded
+		if err != nil {
+			record.Status = evergreen.TaskFailed
+			record.Error = agt.logger.Redact(err.Error())
+		}
+	}
+
+	resp, err := agt.tryPostJSON("command", record)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		agt.logger.LogExecution(slogger.WARN, "Error reporting command %v: %v", record.Name, err)
+	} else if resp != nil && resp.StatusCode != http.StatusOK {
+		agt.logger.LogExecution(slogger.WARN, "Error reporting command %v: unexpected"+
+			" status code %v", record.Name, resp.StatusCode)
+	}
 }
 
 // registerPlugins makes plugins available for use by the agent.
@@ -577,18 +863,40 @@ func registerPlugins(registry plugin.Registry, plugins []plugin.Plugin, logger *
 	return nil
 }
 
+// registerExternalPlugins makes the external plugins in the agent's plugin
+// directory available for use. A plugin that can't be loaded or registered
+// is left out, so that only tasks using it fail.
+func (agt *Agent) registerExternalPlugins() {
+	// tasks can write to the working directory, so plugins in it can't be
+	// trusted to be the ones the distro was set up with
+	if agt.PluginDir != "" && agt.taskConfig.Distro.InWorkDir(agt.PluginDir) {
+		agt.logger.LogExecution(slogger.ERROR, "Not loading external plugins from %v: it is inside"+
+			" the working directory %v", agt.PluginDir, agt.taskConfig.Distro.WorkDir)
+		return
+	}
+	plugins, err := external.Load(agt.PluginDir)
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error loading external plugins: %v", err)
+	}
+	for _, pl := range plugins {
+		if err = agt.Registry.Register(pl); err != nil {
+			agt.logger.LogExecution(slogger.ERROR, "Failed to register external plugin %v"+
+				" from %v: %v", pl.Name(), pl.Path, err)
+			continue
+		}
+		agt.logger.LogExecution(slogger.INFO, "Registered external plugin %v from %v",
+			pl.Name(), pl.Path)
+	}
+}
+
 // StartBackgroundActions spawns goroutines that monitor various parts of the
 // execution - heartbeats, timeouts, logging, etc.
 func (agt *Agent) StartBackgroundActions(signalHandler TerminateHandler) chan FinalTaskFunc {
 	completed := make(chan FinalTaskFunc)
 	agt.heartbeater.StartHeartbeating()
 	agt.statsCollector.LogStats(agt.taskConfig.Expansions)
+	agt.resourceSampler.Start()
 	agt.idleTimeoutWatcher.NotifyTimeouts(agt.signalChan)
-
-	// Default action is not to include a master timeout
-	if agt.maxExecTimeoutWatcher != nil {
-		agt.maxExecTimeoutWatcher.NotifyTimeouts(agt.signalChan)
-	}
 	go signalHandler.HandleSignals(agt, completed)
 
 	// listen for SIGQUIT and dump a stack trace to system logs if received.
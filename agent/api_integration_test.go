@@ -561,6 +561,59 @@ func TestTaskEndEndpoint(t *testing.T) {
 	}
 }
 
+func TestTaskEndEndpointWithoutNextTask(t *testing.T) {
+	setupTlsConfigs(t)
+	for tlsString, tlsConfig := range tlsConfigs {
+		Convey("With a live api server, agent, and test task over "+tlsString, t, func() {
+			testTask, _, err := setupAPITestData(testConfig, "random", "linux-64", false, t)
+			testutil.HandleTestingErr(err, t, "Couldn't make test data: %v", err)
+			testServer, err := apiserver.CreateTestServer(testConfig, tlsConfig, plugin.Published, Verbose)
+			testutil.HandleTestingErr(err, t, "Couldn't create apiserver: %v", err)
+			testAgent, err := createAgent(testServer, testTask)
+			testutil.HandleTestingErr(err, t, "failed to create agent: %v")
+			testHost, err := host.FindOne(host.ById(testTask.HostId))
+			testutil.HandleTestingErr(err, t, "failed to find test host")
+
+			// calling end() should finish the task but hand out no next task
+			endWithoutNextTask := func() {
+				details := &apimodels.TaskEndDetail{Status: evergreen.TaskSucceeded}
+				taskEndResp, err := testAgent.End(details)
+				So(err, ShouldBeNil)
+				So(taskEndResp, ShouldNotBeNil)
+				So(taskEndResp.RunNext, ShouldBeFalse)
+
+				taskUpdate, err := model.FindTask(testTask.Id)
+				So(err, ShouldBeNil)
+				So(taskUpdate.Status, ShouldEqual, evergreen.TaskSucceeded)
+
+				testHost, err := host.FindOne(host.ById(testTask.HostId))
+				So(err, ShouldBeNil)
+				So(testHost.RunningTask, ShouldEqual, "")
+
+				taskUpdate, err = model.FindTask(testTask.Id + "Two")
+				So(err, ShouldBeNil)
+				So(taskUpdate.Status, ShouldEqual, evergreen.TaskUndispatched)
+			}
+
+			Convey("a draining host should not be given the next task", func() {
+				So(testHost.SetDraining(true, "test"), ShouldBeNil)
+				endWithoutNextTask()
+			})
+
+			Convey("a host of a draining distro should not be given the next task", func() {
+				d := &distro.Distro{Id: testHost.Distro.Id, Draining: true}
+				So(d.Insert(), ShouldBeNil)
+				endWithoutNextTask()
+			})
+
+			Convey("a retiring host should not be given the next task", func() {
+				So(testHost.SetRetiring(), ShouldBeNil)
+				endWithoutNextTask()
+			})
+		})
+	}
+}
+
 func scanLogsForTask(taskId string, scanFor string) bool {
 	taskLogs, err := model.FindAllTaskLogs(taskId, 0)
 	if err != nil {
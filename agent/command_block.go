@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen/model"
+	"time"
+)
+
+const (
+	// DefaultPreTimeout is how long a project's pre block may run, if the
+	// project doesn't say.
+	DefaultPreTimeout = 2 * time.Hour
+	// DefaultPostTimeout is how long a project's post block may run, if the
+	// project doesn't say.
+	DefaultPostTimeout = 30 * time.Minute
+	// DefaultCallbackTimeout is how long the commands run when a task times
+	// out may run, if the project doesn't say.
+	DefaultCallbackTimeout = 15 * time.Minute
+	// BlockStopWait is how long the commands of a block that ran past its
+	// timeout are given to stop before the agent warns that they haven't.
+	BlockStopWait = 10 * time.Second
+)
+
+// blockTimeoutError is returned for a block of commands that ran past its
+// timeout.
+type blockTimeoutError struct {
+	block   string
+	timeout time.Duration
+}
+
+func (e *blockTimeoutError) Error() string {
+	return fmt.Sprintf("%v block timed out after %v", e.block, e.timeout)
+}
+
+// blockStoppedError is returned for a block of commands that was stopped
+// because the task is being ended.
+type blockStoppedError struct {
+	block string
+}
+
+func (e *blockStoppedError) Error() string {
+	return fmt.Sprintf("%v block was stopped", e.block)
+}
+
+// blockTimeout returns how long a block of commands may run, given the
+// number of seconds the project allows it, if any.
+func blockTimeout(secs int, defaultTimeout time.Duration) time.Duration {
+	if secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return defaultTimeout
+}
+
+// runCommandBlock runs one of the project's blocks of commands, stopping them
+// if they run for longer than the timeout or a value is received on kill. If
+// returnOnError is set, the block stops at the first command that fails. It
+// returns a *blockTimeoutError if the block timed out, and a
+// *blockStoppedError if it was stopped. A block that is stopped is waited on
+// until its commands have returned, so that none of them is still using the
+// task's expansions once the agent moves on.
+func (agt *Agent) runCommandBlock(commands []model.PluginCommandConf, returnOnError bool,
+	kill chan bool, block string, timeout time.Duration) error {
+	stop := make(chan bool)
+	done := make(chan error, 1)
+	go func() {
+		done <- agt.RunCommands(commands, returnOnError, stop, block)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case blockErr := <-done:
+		return blockErr
+	case <-timer.C:
+		err = &blockTimeoutError{block, timeout}
+		agt.logger.LogTask(slogger.ERROR, "Stopping %v block: it ran for longer than its timeout of %v",
+			block, timeout)
+	case <-kill:
+		err = &blockStoppedError{block}
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(BlockStopWait):
+		agt.logger.LogExecution(slogger.WARN, "Commands in the %v block did not stop within %v;"+
+			" still waiting for them", block, BlockStopWait)
+		<-done
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var commandBlockProject = `
+tasks:
+  - name: compile
+    commands:
+      - command: shell.exec
+        params:
+          script: "echo compiling"
+buildvariants:
+  - name: linux
+    tasks:
+      - name: compile
+`
+
+// runBlockTestTask runs the compile task of commandBlockProject locally,
+// with the given blocks added to the project, and returns the communicator
+// it reported to.
+func runBlockTestTask(dir, blocks string) *LocalCommunicator {
+	project := &model.Project{}
+	So(model.LoadProjectInto([]byte(commandBlockProject+blocks), "blocks", project), ShouldBeNil)
+	communicator := &LocalCommunicator{
+		Task: &model.Task{
+			Id:           "blocks_linux_compile",
+			DisplayName:  "compile",
+			BuildVariant: "linux",
+			Project:      "blocks",
+			Requester:    evergreen.RepotrackerVersionRequester,
+		},
+		Distro:     &distro.Distro{Id: "local", WorkDir: dir},
+		Project:    project,
+		ProjectRef: &model.ProjectRef{Identifier: "blocks"},
+		Dir:        filepath.Join(dir, "output"),
+	}
+	agt, err := NewLocal(communicator, "")
+	So(err, ShouldBeNil)
+	_, err = agt.RunTask()
+	So(err, ShouldBeNil)
+	return communicator
+}
+
+func TestCommandBlockTimeouts(t *testing.T) {
+	Convey("With a task run locally", t, func() {
+		dir, err := ioutil.TempDir("", "command_blocks")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		Convey("a failing pre block should not fail the task by default", func() {
+			communicator := runBlockTestTask(dir, `
+pre:
+  - command: shell.exec
+    params:
+      script: "exit 1"
+`)
+			So(communicator.EndDetail.Status, ShouldEqual, evergreen.TaskSucceeded)
+			// but the failure should still be recorded
+			So(communicator.EndDetail.SetupFailed, ShouldBeTrue)
+		})
+
+		Convey("a pre block that succeeds should not be recorded as failed", func() {
+			communicator := runBlockTestTask(dir, `
+pre:
+  - command: shell.exec
+    params:
+      script: "echo setting up"
+`)
+			So(communicator.EndDetail.Status, ShouldEqual, evergreen.TaskSucceeded)
+			So(communicator.EndDetail.SetupFailed, ShouldBeFalse)
+		})
+
+		Convey("a failing pre block should fail the task as a setup failure"+
+			" if the project says so", func() {
+			communicator := runBlockTestTask(dir, `
+pre_error_fails_task: true
+pre:
+  - command: shell.exec
+    params:
+      script: "exit 1"
+`)
+			So(communicator.EndDetail.Status, ShouldEqual, evergreen.TaskFailed)
+			So(communicator.EndDetail.SetupFailed, ShouldBeTrue)
+			So(communicator.EndDetail.TimedOut, ShouldBeFalse)
+			So(communicator.EndDetail.Type, ShouldEqual, model.SystemCommandType)
+
+			// the task's own commands never ran
+			for _, record := range readCommandRecords(communicator.Dir) {
+				So(record.Block, ShouldNotEqual, apimodels.CommandBlockTask)
+			}
+		})
+
+		Convey("a pre block that runs past its timeout should be stopped", func() {
+			start := time.Now()
+			communicator := runBlockTestTask(dir, `
+pre_timeout_secs: 1
+pre:
+  - command: shell.exec
+    params:
+      script: "sleep 30"
+`)
+			So(time.Since(start), ShouldBeLessThan, 20*time.Second)
+			So(communicator.EndDetail.Status, ShouldEqual, evergreen.TaskFailed)
+			So(communicator.EndDetail.SetupFailed, ShouldBeTrue)
+			So(communicator.EndDetail.TimedOut, ShouldBeTrue)
+		})
+
+		Convey("a post block that runs past its timeout should leave the task's status alone", func() {
+			start := time.Now()
+			communicator := runBlockTestTask(dir, `
+post_timeout_secs: 1
+post:
+  - command: shell.exec
+    params:
+      script: "sleep 30"
+`)
+			So(time.Since(start), ShouldBeLessThan, 20*time.Second)
+			So(communicator.EndDetail.Status, ShouldEqual, evergreen.TaskSucceeded)
+			So(communicator.EndDetail.TimedOut, ShouldBeFalse)
+		})
+	})
+
+	Convey("Block timeouts should fall back to the default", t, func() {
+		So(blockTimeout(0, DefaultPostTimeout), ShouldEqual, DefaultPostTimeout)
+		So(blockTimeout(90, DefaultPostTimeout), ShouldEqual, 90*time.Second)
+	})
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var commandRecordProject = `
+pre:
+  - command: shell.exec
+    params:
+      script: "echo pre"
+tasks:
+  - name: compile
+    commands:
+      - command: shell.exec
+        display_name: "first step"
+        params:
+          script: "echo one"
+      - command: shell.exec
+        variants: ["other"]
+        params:
+          script: "echo skipped"
+      - command: shell.exec
+        params:
+          script: "exit 1"
+      - command: shell.exec
+        params:
+          script: "echo never"
+buildvariants:
+  - name: linux
+    tasks:
+      - name: compile
+`
+
+// readCommandRecords reads in the records a local communicator has written
+// to its directory, in the order they were posted.
+func readCommandRecords(dir string) []apimodels.CommandRecord {
+	records := []apimodels.CommandRecord{}
+	for i := 1; ; i++ {
+		data, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("command_%v.json", i)))
+		if err != nil {
+			return records
+		}
+		record := apimodels.CommandRecord{}
+		So(json.Unmarshal(data, &record), ShouldBeNil)
+		records = append(records, record)
+	}
+}
+
+func TestCommandRecords(t *testing.T) {
+	Convey("With a task run locally", t, func() {
+		dir, err := ioutil.TempDir("", "command_records")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		project := &model.Project{}
+		So(model.LoadProjectInto([]byte(commandRecordProject), "records", project), ShouldBeNil)
+		communicator := &LocalCommunicator{
+			Task: &model.Task{
+				Id:           "records_linux_compile",
+				DisplayName:  "compile",
+				BuildVariant: "linux",
+				Project:      "records",
+				Requester:    evergreen.RepotrackerVersionRequester,
+			},
+			Distro:     &distro.Distro{Id: "local", WorkDir: dir},
+			Project:    project,
+			ProjectRef: &model.ProjectRef{Identifier: "records"},
+			Dir:        filepath.Join(dir, "output"),
+		}
+		agt, err := NewLocal(communicator, "")
+		So(err, ShouldBeNil)
+		_, err = agt.RunTask()
+		So(err, ShouldBeNil)
+
+		Convey("a record should be posted for each command run or skipped", func() {
+			records := readCommandRecords(communicator.Dir)
+			So(len(records), ShouldEqual, 5)
+
+			So(records[0].Block, ShouldEqual, apimodels.CommandBlockPre)
+			So(records[0].Status, ShouldEqual, evergreen.TaskSucceeded)
+
+			So(records[1].Block, ShouldEqual, apimodels.CommandBlockTask)
+			So(records[1].Name, ShouldEqual, "shell.exec")
+			So(records[1].DisplayName, ShouldEqual, "first step")
+			So(records[1].Status, ShouldEqual, evergreen.TaskSucceeded)
+			So(records[1].EndTime.Before(records[1].StartTime), ShouldBeFalse)
+
+			So(records[2].Skipped, ShouldBeTrue)
+			So(records[2].Status, ShouldEqual, apimodels.CommandSkipped)
+
+			So(records[3].Status, ShouldEqual, evergreen.TaskFailed)
+			So(records[3].Error, ShouldNotEqual, "")
+
+			// the command after the failed one is never run
+			So(records[4].Name, ShouldEqual, "shell.exec")
+			So(records[4].Skipped, ShouldBeTrue)
+			So(records[4].Status, ShouldEqual, apimodels.CommandSkipped)
+		})
+	})
+}
@@ -46,6 +46,13 @@ func (hbt *HeartbeatTicker) StartHeartbeating() {
 				} else {
 					hbt.numFailed = 0
 				}
+				if err == ErrIncompatibleAgent {
+					hbt.Logger.Logf(slogger.ERROR, "API server rejected the agent - trying to stop...")
+					hbt.SignalChan <- IncompatibleAgent
+					ticker.Stop()
+					hbt.stop = nil
+					return
+				}
 				if hbt.numFailed == hbt.MaxFailedHeartbeats+1 {
 					hbt.Logger.Logf(slogger.ERROR, "Max heartbeats failed - trying to stop...")
 					hbt.SignalChan <- HeartbeatMaxFailed
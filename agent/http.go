@@ -18,11 +18,18 @@ import (
 	"github.com/evergreen-ci/evergreen/util"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 var HTTPConflictError = errors.New("Conflict")
 
+// ErrIncompatibleAgent is returned when the API server turns the agent away
+// for speaking a protocol version it doesn't support.
+var ErrIncompatibleAgent = errors.New("the API server doesn't support the agent's protocol version")
+
 // HTTPCommunicator handles communication with the API server. An HTTPCommunicator
 // is scoped to a single task, and all communication performed by it is
 // only relevant to that running task.
@@ -36,6 +43,8 @@ type HTTPCommunicator struct {
 	Logger        *slogger.Logger
 	HttpsCert     string
 	httpClient    *http.Client
+	// set to 1 once the API server has rejected the agent's protocol version
+	rejected int32
 }
 
 // NewHTTPCommunicator returns an initialized HTTPCommunicator.
@@ -51,20 +60,48 @@ func NewHTTPCommunicator(serverURL, taskId, taskSecret, cert string, sigChan cha
 		SignalChan:    sigChan,
 	}
 
-	if agentCommunicator.HttpsCert != "" {
-		pool := x509.NewCertPool()
-		if !pool.AppendCertsFromPEM([]byte(agentCommunicator.HttpsCert)) {
-			return nil, errors.New("failed to append HttpsCert to new cert pool")
-		}
-		tc := &tls.Config{RootCAs: pool}
-		tr := &http.Transport{TLSClientConfig: tc}
-		agentCommunicator.httpClient = &http.Client{Transport: tr}
-	} else {
-		agentCommunicator.httpClient = &http.Client{}
+	httpClient, err := newHTTPClient(cert)
+	if err != nil {
+		return nil, err
 	}
+	agentCommunicator.httpClient = httpClient
 	return agentCommunicator, nil
 }
 
+// newHTTPClient returns an http.Client that trusts the given PEM encoded
+// certificate. If cert is blank, the default system certificates are used.
+func newHTTPClient(cert string) (*http.Client, error) {
+	if cert == "" {
+		return &http.Client{}, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(cert)) {
+		return nil, errors.New("failed to append HttpsCert to new cert pool")
+	}
+	tc := &tls.Config{RootCAs: pool}
+	tr := &http.Transport{TLSClientConfig: tc}
+	return &http.Client{Transport: tr}, nil
+}
+
+// doAgentRequest sends the request along with the protocol version the agent
+// speaks, and returns ErrIncompatibleAgent if the API server rejects that
+// version.
+func doAgentRequest(client *http.Client, req *http.Request, logger *slogger.Logger) (*http.Response, error) {
+	req.Header.Add(evergreen.AgentProtocolHeader, strconv.Itoa(evergreen.AgentProtocolVersion))
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUpgradeRequired {
+		return resp, err
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	message := fmt.Sprintf("API server rejected the agent: %v", strings.TrimSpace(string(body)))
+	if logger != nil {
+		logger.Logf(slogger.ERROR, message)
+	}
+	return nil, ErrIncompatibleAgent
+}
+
 // Heartbeat encapsulates heartbeat behavior (i.e., pinging the API server at regular
 // intervals to ensure that communication hasn't broken down).
 type Heartbeat interface {
@@ -272,6 +309,11 @@ func (h *HTTPCommunicator) Log(messages []model.LogMessage) error {
 	if err != nil {
 		return err
 	}
+	// the server not being able to store the logs is worth trying again
+	// later; any other response means they'll never be accepted
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status code: %v", resp.StatusCode)
+	}
 	return nil
 }
 
@@ -530,7 +572,19 @@ func (h *HTTPCommunicator) tryRequest(path string, method string,
 	}
 	req.Header.Add(evergreen.TaskSecretHeader, h.TaskSecret)
 	req.Header.Add("Content-Type", "application/json")
-	return h.httpClient.Do(req)
+	resp, err := doAgentRequest(h.httpClient, req, h.Logger)
+	if err == ErrIncompatibleAgent {
+		atomic.StoreInt32(&h.rejected, 1)
+	}
+	return resp, err
+}
+
+// Incompatible returns whether the API server has turned the agent away for
+// speaking a protocol version it doesn't support. The API server turns away
+// the agent's attempt to end its task too, so the task is left for the
+// monitor to reset once its heartbeat times out.
+func (h *HTTPCommunicator) Incompatible() bool {
+	return atomic.LoadInt32(&h.rejected) == 1
 }
 
 func (h *HTTPCommunicator) postJSON(path string, data interface{}) (
@@ -545,6 +599,9 @@ func (h *HTTPCommunicator) postJSON(path string, data interface{}) (
 				h.Logger.Logf(slogger.ERROR, "received 409 conflict error")
 				return HTTPConflictError
 			}
+			if err == ErrIncompatibleAgent {
+				return err
+			}
 			if err != nil {
 				h.Logger.Logf(slogger.ERROR, "HTTP Post failed on '%v': %v",
 					path, err)
@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -34,6 +35,7 @@ func TestCommunicatorServerDown(t *testing.T) {
 			logger,                 // logger to use for logging retry attempts
 			"",                     // cert
 			&http.Client{},
+			0,
 		}
 		Convey("Calling start() should return err after max retries", func() {
 			So(agentCommunicator.Start("1"), ShouldNotBeNil)
@@ -62,6 +64,7 @@ func TestCommunicatorServerUp(t *testing.T) {
 			logger,
 			"",
 			&http.Client{},
+			0,
 		}
 
 		Convey("Calls to start() or end() should not return err", func() {
@@ -224,18 +227,78 @@ func TestCommunicatorServerUp(t *testing.T) {
 		})
 
 		Convey("fetching expansions should work", func() {
-			test_vars := apimodels.ExpansionVars{}
-			test_vars["test_key"] = "test_value"
-			test_vars["second_fetch"] = "more_one"
+			test_vars := apimodels.ExpansionVars{
+				Vars:        map[string]string{},
+				PrivateVars: map[string]bool{},
+			}
+			test_vars.Vars["test_key"] = "test_value"
+			test_vars.Vars["second_fetch"] = "more_one"
+			test_vars.PrivateVars["second_fetch"] = true
 			serveMux.HandleFunc("/task/mocktaskid/fetch_vars", func(w http.ResponseWriter, req *http.Request) {
 				util.WriteJSON(&w, test_vars, http.StatusOK)
 			})
 			resultingVars, err := agentCommunicator.FetchExpansionVars()
 			So(err, ShouldBeNil)
-			So(len(*resultingVars), ShouldEqual, 2)
-			So((*resultingVars)["test_key"], ShouldEqual, "test_value")
-			So((*resultingVars)["second_fetch"], ShouldEqual, "more_one")
+			So(len(resultingVars.Vars), ShouldEqual, 2)
+			So(resultingVars.Vars["test_key"], ShouldEqual, "test_value")
+			So(resultingVars.Vars["second_fetch"], ShouldEqual, "more_one")
+			So(resultingVars.PrivateVars["second_fetch"], ShouldBeTrue)
+
+		})
+	})
+}
+
+func TestCommunicatorProtocolVersion(t *testing.T) {
+	Convey("With an HTTP communicator and live HTTP server", t, func() {
+		serveMux := http.NewServeMux()
+		ts := httptest.NewServer(serveMux)
+		Reset(func() { ts.Close() })
+
+		agentCommunicator, err := NewHTTPCommunicator(ts.URL, "mocktaskid", "mocktasksecret", "", make(chan Signal))
+		So(err, ShouldBeNil)
+		agentCommunicator.ServerURLRoot = ts.URL
+		agentCommunicator.Logger = &slogger.Logger{Prefix: "test", Appenders: []slogger.Appender{}}
+		agentCommunicator.MaxAttempts = 1
+		agentCommunicator.RetrySleep = time.Millisecond
 
+		Convey("every request should carry the agent's protocol version", func() {
+			sentVersion := ""
+			serveMux.HandleFunc("/task/mocktaskid/start", func(w http.ResponseWriter, r *http.Request) {
+				sentVersion = r.Header.Get(evergreen.AgentProtocolHeader)
+				util.WriteJSON(&w, apimodels.TaskStartRequest{}, http.StatusOK)
+			})
+			So(agentCommunicator.Start("1"), ShouldBeNil)
+			So(sentVersion, ShouldEqual, strconv.Itoa(evergreen.AgentProtocolVersion))
+			So(agentCommunicator.Incompatible(), ShouldBeFalse)
+		})
+
+		Convey("requests should fail if the server rejects its protocol version", func() {
+			serveMux.HandleFunc("/task/mocktaskid/start", func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "agent protocol version '1' is not supported", http.StatusUpgradeRequired)
+			})
+			So(agentCommunicator.Start("1"), ShouldEqual, ErrIncompatibleAgent)
+			So(agentCommunicator.Incompatible(), ShouldBeTrue)
+		})
+
+		Convey("the heartbeat ticker should signal if the server rejects its protocol version", func() {
+			serveMux.HandleFunc("/task/mocktaskid/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "agent protocol version '1' is not supported", http.StatusUpgradeRequired)
+			})
+			sigChan := make(chan Signal, 1)
+			ticker := &HeartbeatTicker{
+				MaxFailedHeartbeats: 10,
+				Interval:            10 * time.Millisecond,
+				SignalChan:          sigChan,
+				TaskCommunicator:    agentCommunicator,
+				Logger:              agentCommunicator.Logger,
+			}
+			ticker.StartHeartbeating()
+			select {
+			case sig := <-sigChan:
+				So(sig, ShouldEqual, IncompatibleAgent)
+			case <-time.After(time.Second):
+				So("no signal received", ShouldBeNil)
+			}
 		})
 	})
 }
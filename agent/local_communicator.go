@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/patch"
+	"github.com/evergreen-ci/evergreen/util"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// the files under a LocalCommunicator's directory that task logs are
+	// written to, by log type
+	LocalTaskLogFile      = "task.log"
+	LocalExecutionLogFile = "execution.log"
+	LocalSystemLogFile    = "system.log"
+)
+
+// LocalCommunicator is a TaskCommunicator for running a task on a
+// developer's machine, without an API server. It serves the task's
+// configuration from memory, and writes the logs, test results, file
+// attachments and anything else the task would send to the API server into
+// files under Dir.
+type LocalCommunicator struct {
+	Task       *model.Task
+	Distro     *distro.Distro
+	Project    *model.Project
+	ProjectRef *model.ProjectRef
+	Expansions map[string]string
+	Dir        string
+
+	// EndDetail holds the task's final status, once it has ended
+	EndDetail *apimodels.TaskEndDetail
+
+	mutex sync.Mutex
+	// how many times each endpoint has been posted to
+	posts map[string]int
+}
+
+func (lc *LocalCommunicator) Start(pid string) error {
+	return nil
+}
+
+func (lc *LocalCommunicator) End(detail *apimodels.TaskEndDetail) (*apimodels.TaskEndResponse, error) {
+	lc.mutex.Lock()
+	lc.EndDetail = detail
+	lc.mutex.Unlock()
+	if _, err := lc.writeJSON("end", detail); err != nil {
+		return nil, err
+	}
+	return &apimodels.TaskEndResponse{}, nil
+}
+
+func (lc *LocalCommunicator) GetTask() (*model.Task, error) {
+	return lc.Task, nil
+}
+
+func (lc *LocalCommunicator) GetProjectRef() (*model.ProjectRef, error) {
+	return lc.ProjectRef, nil
+}
+
+func (lc *LocalCommunicator) GetDistro() (*distro.Distro, error) {
+	return lc.Distro, nil
+}
+
+func (lc *LocalCommunicator) GetProjectConfig() (*model.Project, error) {
+	return lc.Project, nil
+}
+
+// GetPatch returns nil, since local runs aren't of patches.
+func (lc *LocalCommunicator) GetPatch() (*patch.Patch, error) {
+	return nil, nil
+}
+
+// Log appends the messages to the log file for their type.
+func (lc *LocalCommunicator) Log(messages []model.LogMessage) error {
+	lines := map[string]*bytes.Buffer{}
+	for _, message := range messages {
+		fileName := LocalTaskLogFile
+		switch message.Type {
+		case model.AgentLogPrefix:
+			fileName = LocalExecutionLogFile
+		case model.SystemLogPrefix:
+			fileName = LocalSystemLogFile
+		}
+		if lines[fileName] == nil {
+			lines[fileName] = &bytes.Buffer{}
+		}
+		fmt.Fprintf(lines[fileName], "[%v] [%v] %v\n",
+			message.Timestamp.Format("2006/01/02 15:04:05.000"), message.Severity,
+			message.Message)
+	}
+
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	for fileName, buffer := range lines {
+		file, err := util.GetAppendingFile(filepath.Join(lc.Dir, fileName))
+		if err != nil {
+			return err
+		}
+		_, err = buffer.WriteTo(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Heartbeat always succeeds; a local task can only be stopped by killing it.
+func (lc *LocalCommunicator) Heartbeat() (bool, error) {
+	return false, nil
+}
+
+func (lc *LocalCommunicator) FetchExpansionVars() (*apimodels.ExpansionVars, error) {
+	vars := &apimodels.ExpansionVars{
+		Vars:        map[string]string{},
+		PrivateVars: map[string]bool{},
+	}
+	for key, value := range lc.Expansions {
+		vars.Vars[key] = value
+	}
+	return vars, nil
+}
+
+// tryGet responds that nothing was found, since there is no server to
+// fetch anything from.
+func (lc *LocalCommunicator) tryGet(path string) (*http.Response, error) {
+	return localResponse(http.StatusNotFound, fmt.Sprintf("%v is not available"+
+		" when running a task locally", path)), nil
+}
+
+// tryPostJSON writes the data to a file named for the endpoint.
+func (lc *LocalCommunicator) tryPostJSON(path string, data interface{}) (*http.Response, error) {
+	fileName, err := lc.writeJSON(path, data)
+	if err != nil {
+		return nil, err
+	}
+	// test logs are referred to by the id they're stored under
+	reply, err := json.Marshal(map[string]string{"_id": fileName})
+	if err != nil {
+		return nil, err
+	}
+	return localResponse(http.StatusOK, string(reply)), nil
+}
+
+// writeJSON writes the data as JSON to a new file under the communicator's
+// directory, named for the endpoint and how many times it has been written
+// to, and returns the file's name.
+func (lc *LocalCommunicator) writeJSON(endpoint string, data interface{}) (string, error) {
+	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	if lc.posts == nil {
+		lc.posts = map[string]int{}
+	}
+	lc.posts[endpoint]++
+	fileName := fmt.Sprintf("%v_%v.json", strings.Replace(endpoint, "/", "_", -1),
+		lc.posts[endpoint])
+	if err := os.MkdirAll(lc.Dir, 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(lc.Dir, fileName), jsonBytes, 0644); err != nil {
+		return "", err
+	}
+	return fileName, nil
+}
+
+// localResponse builds a response as though it came from the API server.
+func localResponse(statusCode int, body string) *http.Response {
+	return &http.Response{
+		Status:     http.StatusText(statusCode),
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"encoding/json"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLocalCommunicator(t *testing.T) {
+	Convey("With a local communicator", t, func() {
+		dir, err := ioutil.TempDir("", "local_task")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+		communicator := &LocalCommunicator{
+			Dir:        dir,
+			Expansions: map[string]string{"key": "value"},
+		}
+
+		Convey("logs should be written to a file for each type", func() {
+			So(communicator.Log([]model.LogMessage{
+				{Type: model.TaskLogPrefix, Severity: model.LogInfoPrefix, Message: "task", Timestamp: time.Now()},
+				{Type: model.AgentLogPrefix, Severity: model.LogInfoPrefix, Message: "agent", Timestamp: time.Now()},
+				{Type: model.SystemLogPrefix, Severity: model.LogInfoPrefix, Message: "system", Timestamp: time.Now()},
+			}), ShouldBeNil)
+			So(communicator.Log([]model.LogMessage{
+				{Type: model.TaskLogPrefix, Severity: model.LogErrorPrefix, Message: "more", Timestamp: time.Now()},
+			}), ShouldBeNil)
+
+			taskLog, err := ioutil.ReadFile(filepath.Join(dir, LocalTaskLogFile))
+			So(err, ShouldBeNil)
+			So(string(taskLog), ShouldContainSubstring, "[I] task\n")
+			So(string(taskLog), ShouldContainSubstring, "[E] more\n")
+			So(string(taskLog), ShouldNotContainSubstring, "agent")
+			executionLog, err := ioutil.ReadFile(filepath.Join(dir, LocalExecutionLogFile))
+			So(err, ShouldBeNil)
+			So(string(executionLog), ShouldContainSubstring, "agent")
+			systemLog, err := ioutil.ReadFile(filepath.Join(dir, LocalSystemLogFile))
+			So(err, ShouldBeNil)
+			So(string(systemLog), ShouldContainSubstring, "system")
+		})
+
+		Convey("posted data should be written to a new file each time", func() {
+			pluginCom := &TaskJSONCommunicator{"attach", communicator}
+			results := &model.TestResults{Results: []model.TestResult{{TestFile: "test1"}}}
+			So(pluginCom.TaskPostResults(results), ShouldBeNil)
+			So(pluginCom.TaskPostResults(results), ShouldBeNil)
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "results_2.json"))
+			So(err, ShouldBeNil)
+			written := &model.TestResults{}
+			So(json.Unmarshal(data, written), ShouldBeNil)
+			So(written.Results[0].TestFile, ShouldEqual, "test1")
+
+			resp, err := pluginCom.TaskPostJSON("custom", map[string]string{"a": "b"})
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			_, err = os.Stat(filepath.Join(dir, "attach_custom_1.json"))
+			So(err, ShouldBeNil)
+		})
+
+		Convey("test logs should be identified by the file they're in", func() {
+			pluginCom := &TaskJSONCommunicator{"attach", communicator}
+			logId, err := pluginCom.TaskPostTestLog(&model.TestLog{Name: "log"})
+			So(err, ShouldBeNil)
+			So(logId, ShouldEqual, "test_logs_1.json")
+		})
+
+		Convey("fetching anything from the server should find nothing", func() {
+			pluginCom := &TaskJSONCommunicator{"git", communicator}
+			resp, err := pluginCom.TaskGetJSON("patch")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("expansions and the task's final status should be kept", func() {
+			vars, err := communicator.FetchExpansionVars()
+			So(err, ShouldBeNil)
+			So(vars.Vars["key"], ShouldEqual, "value")
+
+			_, err = communicator.End(&apimodels.TaskEndDetail{Status: evergreen.TaskSucceeded})
+			So(err, ShouldBeNil)
+			So(communicator.EndDetail.Status, ShouldEqual, evergreen.TaskSucceeded)
+		})
+	})
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// DefaultLogSpoolSize is the most bytes of log messages an agent holds on
+// to while the API server can't be reached.
+const DefaultLogSpoolSize = 64 * 1024 * 1024
+
+// LogSpool keeps batches of log messages that couldn't be sent to the API
+// server in a local file, so that they can be sent in order once the server
+// can be reached again. The file is capped in size; the messages in batches
+// that don't fit are counted as lost, and the server is told how many there
+// were once the spool is replayed.
+type LogSpool struct {
+	// the file batches are spooled to, one JSON array per batch
+	Path string
+	// the most bytes the file may hold
+	MaxBytes int64
+
+	// the number of bytes in the file
+	size int64
+	// the number of batches at the start of the file that have already
+	// been sent
+	replayed int
+	// the number of messages in the file that haven't been sent
+	unsent int
+	// the number of messages that were lost because the spool was full or
+	// its file couldn't be read back
+	dropped int
+}
+
+// NewLogSpool creates a spool for a task's logs in a new file under the
+// given directory, which only the agent's user can read. The file should be
+// removed with Close once the task is done.
+func NewLogSpool(dir, taskId string, maxBytes int64) (*LogSpool, error) {
+	file, err := ioutil.TempFile(dir, fmt.Sprintf("evergreen_log_spool_%v_", taskId))
+	if err != nil {
+		return nil, fmt.Errorf("error creating log spool file: %v", err)
+	}
+	if err = file.Close(); err != nil {
+		return nil, fmt.Errorf("error creating log spool file: %v", err)
+	}
+	return &LogSpool{Path: file.Name(), MaxBytes: maxBytes}, nil
+}
+
+// Close removes the spool file. Anything still in it is lost.
+func (ls *LogSpool) Close() error {
+	return os.Remove(ls.Path)
+}
+
+// Pending returns whether the spool has anything the server hasn't been
+// sent yet.
+func (ls *LogSpool) Pending() bool {
+	return ls.size > 0 || ls.dropped > 0
+}
+
+// Add appends the batch to the spool, or counts its messages as lost if it
+// doesn't fit.
+func (ls *LogSpool) Add(messages []model.LogMessage) {
+	if len(messages) == 0 {
+		return
+	}
+	data, err := json.Marshal(messages)
+	if err == nil {
+		data = append(data, '\n')
+		if ls.size+int64(len(data)) <= ls.MaxBytes {
+			if err = appendToFile(ls.Path, data); err == nil {
+				ls.size += int64(len(data))
+				ls.unsent += len(messages)
+				return
+			}
+		}
+	}
+	ls.dropped += len(messages)
+}
+
+// Replay sends the spooled batches in the order they were added, followed
+// by a message saying how many messages were lost, if any were. It stops at
+// the first batch that fails to send, leaving it and the batches after it
+// to be sent by the next replay.
+func (ls *LogSpool) Replay(send func([]model.LogMessage) error) error {
+	if ls.size > 0 {
+		if err := ls.replayFile(send); err != nil {
+			return err
+		}
+	}
+
+	if ls.dropped > 0 {
+		lost := model.LogMessage{
+			Type:      model.AgentLogPrefix,
+			Severity:  model.LogWarnPrefix,
+			Version:   evergreen.LogmessageCurrentVersion,
+			Timestamp: time.Now(),
+			Message: fmt.Sprintf("%v log messages were lost while the API server"+
+				" could not be reached", ls.dropped),
+		}
+		if err := send([]model.LogMessage{lost}); err != nil {
+			return err
+		}
+		ls.dropped = 0
+	}
+	return nil
+}
+
+// replayFile sends the batches in the spool file that haven't been sent
+// yet, and empties the file once they all have been.
+func (ls *LogSpool) replayFile(send func([]model.LogMessage) error) error {
+	file, err := os.Open(ls.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	for batchNum := 0; ; batchNum++ {
+		batch := []model.LogMessage{}
+		err := decoder.Decode(&batch)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// the rest of the file can't be read, so there's no way
+			// to send it
+			ls.dropped += ls.unsent
+			ls.reset()
+			return fmt.Errorf("error reading log spool: %v", err)
+		}
+		if batchNum < ls.replayed {
+			continue
+		}
+		if err := send(batch); err != nil {
+			return err
+		}
+		ls.replayed++
+		ls.unsent -= len(batch)
+	}
+	ls.reset()
+	return nil
+}
+
+// reset empties the spool file.
+func (ls *LogSpool) reset() {
+	os.Truncate(ls.Path, 0)
+	ls.size = 0
+	ls.replayed = 0
+	ls.unsent = 0
+}
+
+// appendToFile appends to a file that must already exist, so that nothing
+// else can have put a file of its own at the path.
+func appendToFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
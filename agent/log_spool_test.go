@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/evergreen-ci/evergreen/model"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func testBatch(messages ...string) []model.LogMessage {
+	batch := []model.LogMessage{}
+	for _, message := range messages {
+		batch = append(batch, model.LogMessage{Message: message})
+	}
+	return batch
+}
+
+func TestLogSpool(t *testing.T) {
+	Convey("With a log spool", t, func() {
+		dir, err := ioutil.TempDir("", "log_spool")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+		spool, err := NewLogSpool(dir, "task", 1024)
+		So(err, ShouldBeNil)
+
+		sent := []string{}
+		send := func(batch []model.LogMessage) error {
+			for _, message := range batch {
+				sent = append(sent, message.Message)
+			}
+			return nil
+		}
+
+		Convey("spooled batches should be replayed in order", func() {
+			So(spool.Pending(), ShouldBeFalse)
+			spool.Add(testBatch("one", "two"))
+			spool.Add(testBatch("three"))
+			So(spool.Pending(), ShouldBeTrue)
+
+			So(spool.Replay(send), ShouldBeNil)
+			So(sent, ShouldResemble, []string{"one", "two", "three"})
+			So(spool.Pending(), ShouldBeFalse)
+			info, err := os.Stat(spool.Path)
+			So(err, ShouldBeNil)
+			So(info.Size(), ShouldEqual, 0)
+		})
+
+		Convey("the spool file should only be readable by the agent's user", func() {
+			info, err := os.Stat(spool.Path)
+			So(err, ShouldBeNil)
+			So(info.Mode().Perm(), ShouldEqual, 0600)
+
+			So(spool.Close(), ShouldBeNil)
+			_, err = os.Stat(spool.Path)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("batches that can't be read back should be counted as lost", func() {
+			spool.Add(testBatch("one", "two"))
+			So(ioutil.WriteFile(spool.Path, []byte("not json\n"), 0600), ShouldBeNil)
+
+			So(spool.Replay(send), ShouldNotBeNil)
+			So(spool.Pending(), ShouldBeTrue)
+			So(spool.Replay(send), ShouldBeNil)
+			So(len(sent), ShouldEqual, 1)
+			So(sent[0], ShouldStartWith, "2 log messages were lost")
+		})
+
+		Convey("a failed replay should pick up where it left off", func() {
+			spool.Add(testBatch("one"))
+			spool.Add(testBatch("two"))
+			spool.Add(testBatch("three"))
+
+			failOn := "two"
+			flakySend := func(batch []model.LogMessage) error {
+				if batch[0].Message == failOn {
+					return fmt.Errorf("server unreachable")
+				}
+				return send(batch)
+			}
+			So(spool.Replay(flakySend), ShouldNotBeNil)
+			So(sent, ShouldResemble, []string{"one"})
+			So(spool.Pending(), ShouldBeTrue)
+
+			spool.Add(testBatch("four"))
+			failOn = ""
+			So(spool.Replay(flakySend), ShouldBeNil)
+			So(sent, ShouldResemble, []string{"one", "two", "three", "four"})
+		})
+
+		Convey("batches past the cap should be counted as lost", func() {
+			spool.MaxBytes = 100
+			spool.Add(testBatch("fits"))
+			spool.Add(testBatch("does", "not", "fit"))
+
+			So(spool.Replay(send), ShouldBeNil)
+			So(len(sent), ShouldEqual, 2)
+			So(sent[0], ShouldEqual, "fits")
+			So(sent[1], ShouldStartWith, "3 log messages were lost")
+			So(spool.Pending(), ShouldBeFalse)
+		})
+
+		Convey("an API logger should spool what it fails to send", func() {
+			communicator := &MockCommunicator{
+				logChan:       make(chan []model.LogMessage, 100),
+				shouldFailEnd: true,
+			}
+			apiLogger := NewAPILogger(communicator)
+			apiLogger.Spool = spool
+
+			apiLogger.sendLogs(testBatch("one"))
+			So(len(communicator.logChan), ShouldEqual, 0)
+			So(spool.Pending(), ShouldBeTrue)
+
+			communicator.shouldFailEnd = false
+			apiLogger.sendLogs(testBatch("two"))
+			So(len(communicator.logChan), ShouldEqual, 2)
+			So((<-communicator.logChan)[0].Message, ShouldEqual, "one")
+			So((<-communicator.logChan)[0].Message, ShouldEqual, "two")
+			So(spool.Pending(), ShouldBeFalse)
+		})
+	})
+}
@@ -1,11 +1,13 @@
 package agent
 
 import (
+	"fmt"
 	"github.com/10gen-labs/slogger/v1"
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/util"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,6 +31,10 @@ type StreamLogger struct {
 
 	// apiLogger is used to send data back to the API server.
 	apiLogger APILogger
+
+	// redactor keeps the values of private variables out of anything sent
+	// to the API server.
+	redactor *Redactor
 }
 
 // GetTaskLogWriter returns an io.Writer of the given level. Useful for
@@ -82,6 +88,24 @@ func (lgr *StreamLogger) FlushAndWait() int {
 	return lgr.apiLogger.FlushAndWait()
 }
 
+// RedactValues makes sure the values of the given private variables, keyed by
+// name, are replaced with a placeholder in all logs sent to the API server.
+// Values too short to redact are left alone, with a warning.
+func (lgr *StreamLogger) RedactValues(vars map[string]string) {
+	for name, value := range vars {
+		if !lgr.redactor.Add(name, value) && value != "" {
+			lgr.LogExecution(slogger.WARN, "Not redacting private variable '%v' from logs: its value"+
+				" is shorter than %v characters", name, MinRedactedLength)
+		}
+	}
+}
+
+// Redact returns the message with the values of any private variables
+// replaced.
+func (lgr *StreamLogger) Redact(message string) string {
+	return lgr.redactor.Redact(message)
+}
+
 // Wraps an Logger, with additional context about which command is currently being run.
 type CommandLogger struct {
 	commandName string
@@ -138,7 +162,13 @@ func NewStreamLogger(timeoutWatcher *TimeoutWatcher, apiLgr *APILogger, logFile
 
 	timeoutLogger := &TimeoutResetLogger{timeoutWatcher, apiLgr}
 
+	if apiLgr.Redactor == nil {
+		apiLgr.Redactor = &Redactor{}
+	}
+
 	return &StreamLogger{
+		redactor: apiLgr.Redactor,
+
 		Local: &slogger.Logger{
 			Prefix:    "local",
 			Appenders: localLoggers,
@@ -207,6 +237,14 @@ type APILogger struct {
 	// it must send IncorrectSecret on the channel.
 	signalChan chan Signal
 
+	// Redactor, if set, replaces the values of private variables in
+	// messages before they are buffered.
+	Redactor *Redactor
+
+	// Spool, if set, holds on to messages that couldn't be sent until the
+	// remote endpoint can be reached again. Without it, they are dropped.
+	Spool *LogSpool
+
 	// The mechanism for communicating with the remote endpoint.
 	TaskCommunicator
 }
@@ -230,6 +268,9 @@ func NewAPILogger(tc TaskCommunicator) *APILogger {
 // remote endpoint.
 func (apiLgr *APILogger) Append(log *slogger.Log) error {
 	message := strings.TrimRight(log.Message(), "\r\n \t")
+	if apiLgr.Redactor != nil {
+		message = apiLgr.Redactor.Redact(message)
+	}
 
 	// MCI-972: ensure message is valid UTF-8
 	if !utf8.ValidString(message) {
@@ -261,10 +302,27 @@ func (apiLgr *APILogger) Append(log *slogger.Log) error {
 func (apiLgr *APILogger) sendLogs(flushMsgs []model.LogMessage) int {
 	apiLgr.flushLock.Lock()
 	defer apiLgr.flushLock.Unlock()
+	if apiLgr.Spool == nil {
+		if len(flushMsgs) == 0 {
+			return 0
+		}
+		apiLgr.TaskCommunicator.Log(flushMsgs)
+		return len(flushMsgs)
+	}
+
+	// anything spooled has to be sent first to keep the logs in order
+	if apiLgr.Spool.Pending() {
+		if err := apiLgr.Spool.Replay(apiLgr.TaskCommunicator.Log); err != nil {
+			apiLgr.Spool.Add(flushMsgs)
+			return len(flushMsgs)
+		}
+	}
 	if len(flushMsgs) == 0 {
 		return 0
 	}
-	apiLgr.TaskCommunicator.Log(flushMsgs)
+	if err := apiLgr.TaskCommunicator.Log(flushMsgs); err != nil {
+		apiLgr.Spool.Add(flushMsgs)
+	}
 	return len(flushMsgs)
 }
 
@@ -273,7 +331,8 @@ func (apiLgr *APILogger) FlushAndWait() int {
 	defer apiLgr.appendLock.Unlock()
 
 	apiLgr.lastFlush = time.Now()
-	if len(apiLgr.messages) == 0 {
+	// with a spool, there may be messages to send from before
+	if len(apiLgr.messages) == 0 && apiLgr.Spool == nil {
 		return 0
 	}
 
@@ -300,6 +359,79 @@ func (apiLgr *APILogger) Flush() {
 	apiLgr.flushInternal()
 }
 
+// MinRedactedLength is the length of the shortest value a Redactor redacts.
+// Shorter values would turn up in too many unrelated messages, so that
+// replacing them would mangle the logs and give the values away anyway.
+const MinRedactedLength = model.MinPrivateVarLength
+
+// Redactor replaces the values of private variables in log messages with a
+// placeholder naming the variable.
+type Redactor struct {
+	lock sync.RWMutex
+	// names of the variables, keyed by their values
+	names map[string]string
+	// the values, longest first, so that a value containing another is
+	// replaced whole
+	values []string
+}
+
+// Add registers the value of a private variable to be redacted, and returns
+// whether it was. Values shorter than MinRedactedLength are ignored. Since
+// each line of output is logged on its own, every line of a value that spans
+// several, such as a key, is registered too.
+func (r *Redactor) Add(name, value string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	added := r.add(name, value)
+	if strings.Contains(value, "\n") {
+		for _, line := range strings.Split(value, "\n") {
+			if r.add(name, strings.TrimSpace(line)) {
+				added = true
+			}
+		}
+	}
+	return added
+}
+
+// add registers a single value, if it's long enough to redact.
+func (r *Redactor) add(name, value string) bool {
+	if len(value) < MinRedactedLength {
+		return false
+	}
+	if r.names == nil {
+		r.names = map[string]string{}
+	}
+	if _, ok := r.names[value]; !ok {
+		r.values = append(r.values, value)
+		sort.Sort(byLengthDesc(r.values))
+	}
+	r.names[value] = name
+	return true
+}
+
+// Redact returns the message with the value of every registered variable
+// replaced with a placeholder.
+func (r *Redactor) Redact(message string) string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, value := range r.values {
+		message = strings.Replace(message, value, redactedPlaceholder(r.names[value]), -1)
+	}
+	return message
+}
+
+// redactedPlaceholder is what the value of the named private variable is
+// replaced with.
+func redactedPlaceholder(name string) string {
+	return fmt.Sprintf("<REDACTED:%v>", name)
+}
+
+type byLengthDesc []string
+
+func (s byLengthDesc) Len() int           { return len(s) }
+func (s byLengthDesc) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
+func (s byLengthDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 func levelToString(level slogger.Level) string {
 	switch level {
 	case slogger.DEBUG:
@@ -318,6 +450,7 @@ func levelToString(level slogger.Level) string {
 // stores everything in memory.
 func NewTestLogger(appender slogger.Appender) *StreamLogger {
 	return &StreamLogger{
+		redactor: &Redactor{},
 		Local: &slogger.Logger{
 			Prefix:    "local",
 			Appenders: []slogger.Appender{appender},
@@ -111,3 +111,51 @@ func TestCommandLogger(t *testing.T) {
 
 	})
 }
+
+func TestRedaction(t *testing.T) {
+	Convey("With a redactor for some private values", t, func() {
+		redactor := &Redactor{}
+		redactor.Add("password", "hunter2")
+		redactor.Add("token", "hunter2-token")
+		So(redactor.Add("empty", ""), ShouldBeFalse)
+		So(redactor.Add("short", "abc"), ShouldBeFalse)
+
+		Convey("every occurrence of a value should be replaced with its name", func() {
+			So(redactor.Redact("login hunter2 hunter2"), ShouldEqual,
+				"login <REDACTED:password> <REDACTED:password>")
+			So(redactor.Redact("nothing private"), ShouldEqual, "nothing private")
+		})
+
+		Convey("values that are too short should be left alone", func() {
+			So(redactor.Redact("abc abcd"), ShouldEqual, "abc abcd")
+		})
+
+		Convey("a value containing another should be replaced whole", func() {
+			So(redactor.Redact("auth hunter2-token"), ShouldEqual, "auth <REDACTED:token>")
+		})
+
+		Convey("each line of a value spanning several should be replaced on its own", func() {
+			So(redactor.Add("key", "-----BEGIN KEY-----\nMIIEpAIBAAKCAQEA\nab\n-----END KEY-----\n"), ShouldBeTrue)
+			So(redactor.Redact("MIIEpAIBAAKCAQEA"), ShouldEqual, "<REDACTED:key>")
+			So(redactor.Redact("-----END KEY-----"), ShouldEqual, "<REDACTED:key>")
+			// lines too short to redact are left alone, as other values are
+			So(redactor.Redact("ab"), ShouldEqual, "ab")
+		})
+
+		Convey("messages sent to the API server should be redacted", func() {
+			taskCommunicator := &MockCommunicator{
+				logChan: make(chan []model.LogMessage, 100),
+			}
+			apiLogger := NewAPILogger(taskCommunicator)
+			streamLogger, err := NewStreamLogger(&TimeoutWatcher{duration: time.Hour}, apiLogger, "")
+			So(err, ShouldBeNil)
+			streamLogger.RedactValues(map[string]string{"password": "hunter2"})
+
+			streamLogger.LogTask(slogger.INFO, "logging in with %v", "hunter2")
+			apiLogger.FlushAndWait()
+			receivedMsgs := <-taskCommunicator.logChan
+			So(len(receivedMsgs), ShouldEqual, 1)
+			So(receivedMsgs[0].Message, ShouldEqual, "logging in with <REDACTED:password>")
+		})
+	})
+}
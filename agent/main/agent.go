@@ -7,12 +7,14 @@ import (
 	"github.com/evergreen-ci/evergreen/agent"
 	"io/ioutil"
 	"os"
+	"time"
 )
 
 func init() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s pulls tasks from the API server and runs them.\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "This program is designed to be started by the Evergreen taskrunner, not manually.\n\n")
+		fmt.Fprintf(os.Stderr, "This program is designed to be started by the Evergreen taskrunner, or by\n")
+		fmt.Fprintf(os.Stderr, "the setup script of a pull-dispatch distro, not manually.\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n  %s [flags]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Supported flags are:\n")
 		flag.PrintDefaults()
@@ -23,9 +25,13 @@ func main() {
 	// Get the basic info needed to run the agent from command line flags.
 	taskId := flag.String("task_id", "", "id of task to run")
 	taskSecret := flag.String("task_secret", "", "secret of task to run")
+	hostId := flag.String("host_id", "", "id of the host, for agents that request their own tasks")
+	hostSecret := flag.String("host_secret", "", "secret of the host, for agents that request their own tasks")
 	apiServer := flag.String("api_server", "", "URL of API server")
 	httpsCertFile := flag.String("https_cert", "", "path to a self-signed private cert")
 	logFile := flag.String("log_file", "", "log file for agent")
+	pluginDir := flag.String("plugin_dir", "", "directory of external plugin executables, outside the"+
+		" distro's working directory; none are loaded if blank")
 	flag.Parse()
 
 	httpsCert, err := getHTTPSCertFile(*httpsCertFile)
@@ -34,34 +40,90 @@ func main() {
 		os.Exit(1)
 	}
 
-	agt, err := agent.New(*apiServer, *taskId, *taskSecret, *logFile, httpsCert)
+	if *hostId == "" {
+		if err = runTasks(*apiServer, *taskId, *taskSecret, *logFile, *pluginDir, httpsCert); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// with a host id, keep asking the API server for work until told to stop
+	hostCom, err := agent.NewHostCommunicator(*apiServer, *hostId, *hostSecret, httpsCert)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not create new agent: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Could not create host communicator: %v\n", err)
 		os.Exit(1)
 	}
+	failures, conflicts := 0, 0
+	for {
+		next, err := hostCom.GetNextTask()
+		if err == agent.ErrIncompatibleAgent {
+			fmt.Fprintf(os.Stderr, "exiting: %v\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error requesting next task: %v\n", err)
+			if err == agent.ErrWrongHostSecret {
+				conflicts++
+				if conflicts >= agent.MaxHostSecretConflicts {
+					fmt.Fprintf(os.Stderr, "exiting: host secret rejected %v times\n", conflicts)
+					os.Exit(1)
+				}
+			} else {
+				conflicts = 0
+			}
+			failures++
+			time.Sleep(agent.NextTaskInterval(failures))
+			continue
+		}
+		failures, conflicts = 0, 0
+		if next.ShouldExit {
+			fmt.Fprintf(os.Stderr, "exiting: %v\n", next.Message)
+			os.Exit(0)
+		}
+		if next.TaskId == "" {
+			time.Sleep(agent.DefaultNextTaskInterval)
+			continue
+		}
+		err = runTasks(*apiServer, next.TaskId, next.TaskSecret, *logFile, *pluginDir, httpsCert)
+		if err == agent.ErrIncompatibleAgent {
+			// the task has been stopped, and the API server won't hand out
+			// any more work to this agent
+			fmt.Fprintf(os.Stderr, "exiting: %v\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+}
 
-	// run all tasks until an API server's response has RunNext set to false
+// runTasks runs the given task, and then any further tasks the API server
+// hands out in its responses, until a response has RunNext set to false.
+func runTasks(apiServer, taskId, taskSecret, logFile, pluginDir, httpsCert string) error {
 	for {
+		agt, err := agent.New(apiServer, taskId, taskSecret, logFile, httpsCert)
+		if err != nil {
+			return fmt.Errorf("Could not create new agent: %v", err)
+		}
+		agt.PluginDir = pluginDir
+
 		resp, err := agt.RunTask()
+		if err == agent.ErrIncompatibleAgent {
+			return err
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error running task: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("error running task: %v", err)
 		}
 
 		if resp == nil {
-			fmt.Fprintf(os.Stderr, "received nil response from API server\n")
-			os.Exit(1)
+			return fmt.Errorf("received nil response from API server")
 		}
 
 		if !resp.RunNext {
-			os.Exit(0)
-		}
-
-		agt, err = agent.New(*apiServer, resp.TaskId, resp.TaskSecret, *logFile, httpsCert)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Could not create new agent: %v\n", err)
-			os.Exit(1)
+			return nil
 		}
+		taskId, taskSecret = resp.TaskId, resp.TaskSecret
 	}
 }
 
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/util"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultNextTaskInterval is how long the agent on a pull-dispatch host
+	// waits before asking for work again when no task is available.
+	DefaultNextTaskInterval = 15 * time.Second
+	// MaxNextTaskInterval is the longest the agent waits before asking for
+	// work again after failing to get an answer.
+	MaxNextTaskInterval = 5 * time.Minute
+	// MaxHostSecretConflicts is how many times in a row the API server may
+	// reject the host's secret before the agent gives up and exits.
+	MaxHostSecretConflicts = 5
+)
+
+// ErrWrongHostSecret is returned when the API server rejects the host's
+// secret.
+var ErrWrongHostSecret = errors.New("conflict - wrong host secret")
+
+// NextTaskInterval returns how long the agent waits before asking for work
+// again, after failing to get an answer the given number of times in a row.
+func NextTaskInterval(failures int) time.Duration {
+	interval := DefaultNextTaskInterval
+	for i := 1; i < failures && interval < MaxNextTaskInterval; i++ {
+		interval *= 2
+	}
+	if interval > MaxNextTaskInterval {
+		return MaxNextTaskInterval
+	}
+	return interval
+}
+
+// HostCommunicator handles the communication between the long-lived agent
+// of a pull-dispatch host and the API server. Unlike an HTTPCommunicator,
+// it is scoped to a host rather than a task.
+type HostCommunicator struct {
+	ServerURLRoot string
+	HostId        string
+	HostSecret    string
+	MaxAttempts   int
+	RetrySleep    time.Duration
+	httpClient    *http.Client
+}
+
+// NewHostCommunicator returns an initialized HostCommunicator.
+// The cert parameter may be blank if default system certificates are being used.
+func NewHostCommunicator(serverURL, hostId, hostSecret, cert string) (*HostCommunicator, error) {
+	httpClient, err := newHTTPClient(cert)
+	if err != nil {
+		return nil, err
+	}
+	return &HostCommunicator{
+		ServerURLRoot: fmt.Sprintf("%v/api/%v", serverURL, APIVersion),
+		HostId:        hostId,
+		HostSecret:    hostSecret,
+		MaxAttempts:   10,
+		RetrySleep:    time.Second * 3,
+		httpClient:    httpClient,
+	}, nil
+}
+
+// GetNextTask asks the API server for the next task this host should run.
+// A response with an empty TaskId means no task is available right now.
+func (h *HostCommunicator) GetNextTask() (*apimodels.NextTaskResponse, error) {
+	nextTask := &apimodels.NextTaskResponse{}
+	retriableGet := util.RetriableFunc(
+		func() error {
+			resp, err := h.tryGet("agent/next_task")
+			if resp != nil {
+				defer resp.Body.Close()
+			}
+			if err == ErrIncompatibleAgent {
+				return err
+			}
+			if err != nil {
+				// Some generic error trying to connect - try again
+				return util.RetriableError{err}
+			}
+			if resp.StatusCode == http.StatusConflict {
+				// Something very wrong, fail now with no retry.
+				return ErrWrongHostSecret
+			}
+			if resp.StatusCode != http.StatusOK {
+				body, _ := ioutil.ReadAll(resp.Body)
+				return util.RetriableError{fmt.Errorf("unexpected status code "+
+					"requesting next task (%v): %v", resp.StatusCode, string(body))}
+			}
+			if err = util.ReadJSONInto(resp.Body, nextTask); err != nil {
+				return util.RetriableError{err}
+			}
+			return nil
+		},
+	)
+
+	retryFail, err := util.Retry(retriableGet, h.MaxAttempts, h.RetrySleep)
+	if retryFail {
+		return nil, fmt.Errorf("getting next task failed after %v tries: %v",
+			h.MaxAttempts, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nextTask, nil
+}
+
+func (h *HostCommunicator) tryGet(path string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s", h.ServerURLRoot, path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add(evergreen.HostHeader, h.HostId)
+	req.Header.Add(evergreen.HostSecretHeader, h.HostSecret)
+	return doAgentRequest(h.httpClient, req, nil)
+}
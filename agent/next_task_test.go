@@ -0,0 +1,18 @@
+package agent
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestNextTaskInterval(t *testing.T) {
+	Convey("The agent should back off after failing to get its next task", t, func() {
+		So(NextTaskInterval(0), ShouldEqual, DefaultNextTaskInterval)
+		So(NextTaskInterval(1), ShouldEqual, DefaultNextTaskInterval)
+		So(NextTaskInterval(2), ShouldEqual, 2*DefaultNextTaskInterval)
+		So(NextTaskInterval(3), ShouldEqual, 4*DefaultNextTaskInterval)
+		So(NextTaskInterval(100), ShouldEqual, MaxNextTaskInterval)
+		So(MaxNextTaskInterval, ShouldBeGreaterThan, time.Minute)
+	})
+}
@@ -0,0 +1,369 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultResourceSampleInterval is how often the agent samples the
+	// resources in use on its host.
+	DefaultResourceSampleInterval = 10 * time.Second
+	// ResourceSampleBatchSize is how many samples the agent sends to the API
+	// server at once.
+	ResourceSampleBatchSize = 6
+	// MaxPendingResourceSamples is the most samples the agent holds on to
+	// while they can't be sent; older ones are dropped to make room.
+	MaxPendingResourceSamples = 1000
+
+	// the size of a sector in /proc/diskstats
+	diskSectorSize = 512
+)
+
+// procCounters holds the cumulative counters read from /proc that samples'
+// rates are computed from.
+type procCounters struct {
+	time      time.Time
+	cpuBusy   uint64
+	cpuTotal  uint64
+	diskRead  uint64
+	diskWrite uint64
+	netRecv   uint64
+	netSend   uint64
+}
+
+// ResourceSampler samples the CPU, memory, disk and network use of the
+// agent's host from /proc at regular intervals, along with the disk used by
+// the task's directory, and sends the samples to the API server in batches.
+// It only works on Linux.
+type ResourceSampler struct {
+	TaskCommunicator
+	Logger   *slogger.Logger
+	Interval time.Duration
+	// the directory procfs is mounted at
+	ProcDir string
+	// measures the disk used by the task's directory, if set
+	DiskWatcher *DiskWatcher
+
+	// the counters read for the previous sample
+	last *procCounters
+	// samples that haven't been sent yet
+	pending []apimodels.ResourceSample
+	// when sent a value, this stops the sampler
+	stop chan bool
+	// closed once the sampler has stopped
+	done chan bool
+}
+
+// NewResourceSampler creates a sampler that sends its samples through the
+// given communicator.
+func NewResourceSampler(communicator TaskCommunicator, logger *slogger.Logger) *ResourceSampler {
+	return &ResourceSampler{
+		TaskCommunicator: communicator,
+		Logger:           logger,
+		Interval:         DefaultResourceSampleInterval,
+		ProcDir:          "/proc",
+	}
+}
+
+// Start begins sampling in the background, if the host has a procfs to
+// sample.
+func (rs *ResourceSampler) Start() {
+	if _, err := os.Stat(filepath.Join(rs.ProcDir, "stat")); err != nil {
+		rs.Logger.Logf(slogger.INFO, "Not sampling resource usage: %v", err)
+		return
+	}
+	rs.stop = make(chan bool)
+	rs.done = make(chan bool)
+
+	go func() {
+		defer close(rs.done)
+		ticker := time.NewTicker(rs.Interval)
+		defer ticker.Stop()
+		rs.sample()
+		for {
+			select {
+			case <-ticker.C:
+				rs.sample()
+				if len(rs.pending) >= ResourceSampleBatchSize {
+					rs.send()
+				}
+			case <-rs.stop:
+				rs.send()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sampler once it has sent the samples it has.
+func (rs *ResourceSampler) Stop() {
+	if rs.stop == nil {
+		return
+	}
+	rs.stop <- true
+	<-rs.done
+	rs.stop = nil
+}
+
+// sample reads the counters in /proc and adds a sample of the resources used
+// since the previous reading to the ones waiting to be sent. The first
+// reading only sets the counters the next one is measured against.
+func (rs *ResourceSampler) sample() {
+	counters, err := rs.readCounters()
+	if err != nil {
+		rs.Logger.Logf(slogger.WARN, "Error sampling resource usage: %v", err)
+		return
+	}
+	memUsed, memTotal, err := rs.readMemory()
+	if err != nil {
+		rs.Logger.Logf(slogger.WARN, "Error sampling memory usage: %v", err)
+		return
+	}
+
+	last := rs.last
+	rs.last = counters
+	if last == nil {
+		return
+	}
+	sample := computeSample(last, counters)
+	sample.MemoryUsed = memUsed
+	sample.MemoryTotal = memTotal
+	if rs.DiskWatcher != nil {
+		sample.DiskUsed = rs.DiskWatcher.Usage()
+	}
+
+	if len(rs.pending) >= MaxPendingResourceSamples {
+		rs.pending = rs.pending[1:]
+	}
+	rs.pending = append(rs.pending, sample)
+}
+
+// send posts the pending samples to the API server, keeping them to try
+// again later if that fails.
+func (rs *ResourceSampler) send() {
+	if len(rs.pending) == 0 {
+		return
+	}
+	resp, err := rs.tryPostJSON("resource_stats", rs.pending)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		rs.Logger.Logf(slogger.WARN, "Error sending resource samples: %v", err)
+		return
+	}
+	if resp != nil && resp.StatusCode != http.StatusOK {
+		rs.Logger.Logf(slogger.WARN, "Error sending resource samples: unexpected"+
+			" status code %v", resp.StatusCode)
+		return
+	}
+	rs.pending = nil
+}
+
+// computeSample works out the CPU use and the disk and network rates between
+// two readings of the counters.
+func computeSample(last, current *procCounters) apimodels.ResourceSample {
+	sample := apimodels.ResourceSample{Time: current.time}
+	if current.cpuTotal > last.cpuTotal {
+		sample.CPUPercent = 100 * float64(current.cpuBusy-last.cpuBusy) /
+			float64(current.cpuTotal-last.cpuTotal)
+	}
+	seconds := current.time.Sub(last.time).Seconds()
+	if seconds <= 0 {
+		return sample
+	}
+	rate := func(last, current uint64) int64 {
+		if current < last {
+			// the counter wrapped or was reset
+			return 0
+		}
+		return int64(float64(current-last) / seconds)
+	}
+	sample.DiskReadRate = rate(last.diskRead, current.diskRead)
+	sample.DiskWriteRate = rate(last.diskWrite, current.diskWrite)
+	sample.NetworkRecvRate = rate(last.netRecv, current.netRecv)
+	sample.NetworkSendRate = rate(last.netSend, current.netSend)
+	return sample
+}
+
+// readCounters reads the cumulative CPU, disk and network counters.
+func (rs *ResourceSampler) readCounters() (*procCounters, error) {
+	counters := &procCounters{time: time.Now()}
+	var err error
+
+	err = rs.readProcFile("stat", func(r io.Reader) error {
+		counters.cpuBusy, counters.cpuTotal, err = parseCPUStat(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// only count whole disks, since partitions' I/O is counted in their disk's
+	isDisk := func(name string) bool {
+		_, err := os.Stat(filepath.Join("/sys/block", name))
+		return err == nil
+	}
+	err = rs.readProcFile("diskstats", func(r io.Reader) error {
+		counters.diskRead, counters.diskWrite, err = parseDiskStats(r, isDisk)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = rs.readProcFile("net/dev", func(r io.Reader) error {
+		counters.netRecv, counters.netSend, err = parseNetDev(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// readMemory reads how many bytes of memory are in use, and how many there
+// are in all.
+func (rs *ResourceSampler) readMemory() (int64, int64, error) {
+	var used, total int64
+	err := rs.readProcFile("meminfo", func(r io.Reader) error {
+		var err error
+		used, total, err = parseMemInfo(r)
+		return err
+	})
+	return used, total, err
+}
+
+// readProcFile opens the file under the procfs for parse to read.
+func (rs *ResourceSampler) readProcFile(name string, parse func(io.Reader) error) error {
+	file, err := os.Open(filepath.Join(rs.ProcDir, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = parse(file); err != nil {
+		return fmt.Errorf("error parsing %v: %v", name, err)
+	}
+	return nil
+}
+
+// parseCPUStat returns the busy and total time, in ticks, of all the CPUs
+// together, from the "cpu" line of /proc/stat.
+func parseCPUStat(r io.Reader) (uint64, uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		var busy, total uint64
+		for i, field := range fields[1:] {
+			ticks, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			// guest time is already counted in user time
+			if i >= 8 {
+				break
+			}
+			total += ticks
+			// idle and iowait are the fourth and fifth columns
+			if i != 3 && i != 4 {
+				busy += ticks
+			}
+		}
+		return busy, total, nil
+	}
+	return 0, 0, fmt.Errorf("no cpu line")
+}
+
+// parseMemInfo returns the bytes of memory in use, and in all, from
+// /proc/meminfo.
+func parseMemInfo(r io.Reader) (int64, int64, error) {
+	values := map[string]int64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kilobytes, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = kilobytes * 1024
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return 0, 0, fmt.Errorf("no MemTotal")
+	}
+	available, ok := values["MemAvailable"]
+	if !ok {
+		// older kernels don't estimate what's available
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return total - available, total, nil
+}
+
+// parseDiskStats returns the bytes read from and written to the disks that
+// match isDisk, from /proc/diskstats.
+func parseDiskStats(r io.Reader, isDisk func(string) bool) (uint64, uint64, error) {
+	var read, written uint64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || !isDisk(fields[2]) {
+			continue
+		}
+		sectorsRead, err := strconv.ParseUint(fields[5], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		sectorsWritten, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		read += sectorsRead * diskSectorSize
+		written += sectorsWritten * diskSectorSize
+	}
+	return read, written, nil
+}
+
+// parseNetDev returns the bytes received and sent by all network interfaces
+// other than loopback, from /proc/net/dev.
+func parseNetDev(r io.Reader) (uint64, uint64, error) {
+	var recv, sent uint64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "lo" {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 9 {
+			continue
+		}
+		recvBytes, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		sentBytes, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		recv += recvBytes
+		sent += sentBytes
+	}
+	return recv, sent, nil
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"github.com/10gen-labs/slogger/v1"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testProcStat = `cpu  100 20 30 800 50 0 0 0 10 0
+cpu0 50 10 15 400 25 0 0 0 5 0
+intr 12345
+`
+	testMemInfo = `MemTotal:        2048000 kB
+MemFree:          512000 kB
+MemAvailable:    1024000 kB
+Buffers:          100000 kB
+`
+	testDiskStats = `   8       0 sda 100 0 2000 50 200 0 4000 100 0 150 150
+   8       1 sda1 90 0 1800 40 180 0 3600 90 0 130 130
+   7       0 loop0 10 0 80 1 0 0 0 0 0 1 1
+`
+	testNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    5000      50    0    0    0     0          0         0     5000      50    0    0    0     0       0          0
+  eth0:   10000     100    0    0    0     0          0         0     3000      30    0    0    0     0       0          0
+`
+)
+
+func TestResourceStatsParsing(t *testing.T) {
+	Convey("When parsing the contents of /proc", t, func() {
+
+		Convey("CPU time should be split into busy and total", func() {
+			busy, total, err := parseCPUStat(strings.NewReader(testProcStat))
+			So(err, ShouldBeNil)
+			So(busy, ShouldEqual, 150)
+			So(total, ShouldEqual, 1000)
+
+			_, _, err = parseCPUStat(strings.NewReader("intr 12345\n"))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("memory in use should exclude what is available", func() {
+			used, total, err := parseMemInfo(strings.NewReader(testMemInfo))
+			So(err, ShouldBeNil)
+			So(used, ShouldEqual, 1024000*1024)
+			So(total, ShouldEqual, 2048000*1024)
+
+			_, _, err = parseMemInfo(strings.NewReader("MemFree: 512000 kB\n"))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("disk I/O should only be counted for whole disks", func() {
+			isDisk := func(name string) bool { return name == "sda" }
+			read, written, err := parseDiskStats(strings.NewReader(testDiskStats), isDisk)
+			So(err, ShouldBeNil)
+			So(read, ShouldEqual, 2000*512)
+			So(written, ShouldEqual, 4000*512)
+		})
+
+		Convey("network traffic should leave out loopback", func() {
+			recv, sent, err := parseNetDev(strings.NewReader(testNetDev))
+			So(err, ShouldBeNil)
+			So(recv, ShouldEqual, 10000)
+			So(sent, ShouldEqual, 3000)
+		})
+	})
+
+	Convey("A sample should be computed from the change in the counters", t, func() {
+		start := time.Now()
+		last := &procCounters{time: start, cpuBusy: 100, cpuTotal: 1000,
+			diskRead: 1000, diskWrite: 2000, netRecv: 500, netSend: 100}
+		current := &procCounters{time: start.Add(10 * time.Second), cpuBusy: 150, cpuTotal: 1200,
+			diskRead: 11000, diskWrite: 2000, netRecv: 100, netSend: 1100}
+		sample := computeSample(last, current)
+		So(sample.Time, ShouldResemble, current.time)
+		So(sample.CPUPercent, ShouldEqual, 25)
+		So(sample.DiskReadRate, ShouldEqual, 1000)
+		So(sample.DiskWriteRate, ShouldEqual, 0)
+		// a counter that went backwards was reset, so gives no rate
+		So(sample.NetworkRecvRate, ShouldEqual, 0)
+		So(sample.NetworkSendRate, ShouldEqual, 100)
+	})
+}
+
+func TestResourceSampling(t *testing.T) {
+	Convey("With a sampler reading a procfs and a task's disk watcher", t, func() {
+		procDir, err := ioutil.TempDir("", "proc")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(procDir) })
+		So(os.MkdirAll(filepath.Join(procDir, "net"), 0755), ShouldBeNil)
+		for name, contents := range map[string]string{
+			"stat":      testProcStat,
+			"meminfo":   testMemInfo,
+			"diskstats": testDiskStats,
+			"net/dev":   testNetDev,
+		} {
+			So(ioutil.WriteFile(filepath.Join(procDir, name), []byte(contents), 0644), ShouldBeNil)
+		}
+
+		taskDir, err := ioutil.TempDir("", "task")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(taskDir) })
+		So(ioutil.WriteFile(filepath.Join(taskDir, "data"), make([]byte, 3000), 0644), ShouldBeNil)
+
+		logger := &slogger.Logger{Appenders: []slogger.Appender{}}
+		watcher := &DiskWatcher{Dir: taskDir, Logger: logger}
+		sampler := &ResourceSampler{Logger: logger, ProcDir: procDir, DiskWatcher: watcher}
+
+		Convey("samples should include the disk the task was last measured using", func() {
+			sampler.sample()
+			So(watcher.check(), ShouldBeFalse)
+			sampler.sample()
+			So(len(sampler.pending), ShouldEqual, 1)
+			So(sampler.pending[0].DiskUsed, ShouldEqual, 3000)
+			So(sampler.pending[0].MemoryTotal, ShouldEqual, 2048000*1024)
+		})
+	})
+}
@@ -10,6 +10,8 @@ type TimeoutWatcher struct {
 	timer    *time.Timer
 	stop     chan bool
 	disabled bool
+	// the signal sent on a timeout, if it isn't IdleTimeout
+	signal Signal
 }
 
 // SetDuration sets the duration after which a timeout is triggered.
@@ -37,7 +39,11 @@ func (tw *TimeoutWatcher) NotifyTimeouts(sigChan chan Signal) {
 		case <-tw.timer.C:
 			// if execution reaches here, it's timed out.
 			// send the time out signal on sigChan
-			sigChan <- IdleTimeout
+			if tw.signal == ExecTimeout {
+				sigChan <- ExecTimeout
+			} else {
+				sigChan <- IdleTimeout
+			}
 			return
 		case <-tw.stop:
 			return
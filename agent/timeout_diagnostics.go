@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// StackDumpWait is how long the agent spends getting the stack of each
+	// of a timed out task's processes before it moves on.
+	StackDumpWait = 5 * time.Second
+
+	// MaxDiagnosticsFileSize is the largest file collected on a timeout
+	// that is attached to the task. Anything bigger, like a core dump,
+	// should be uploaded by the diagnostics commands themselves.
+	MaxDiagnosticsFileSize = 8 * 1024 * 1024
+
+	// TimeoutDiagnosticsDirExpansion is the expansion holding the directory
+	// that timeout diagnostics commands should write what they collect to.
+	TimeoutDiagnosticsDirExpansion = "timeout_diagnostics_dir"
+
+	// the file the task's process tree is written to
+	processTreeFile = "processes.txt"
+	// the files each process's stack is written to, by pid
+	stackDumpFile = "stack_%v.txt"
+)
+
+// process is a single process, as listed by ps.
+type process struct {
+	Pid  int
+	PPid int
+	// the line ps listed the process on
+	Line string
+}
+
+// collectTimeoutDiagnostics gathers what it can about a task that has timed
+// out while its processes are still running: it lists the task's process
+// tree, gets the stack of each process, then runs the project's diagnostics
+// commands. Whatever is collected is attached to the task as test logs.
+func (agt *Agent) collectTimeoutDiagnostics(diagnostics *model.TimeoutDiagnostics) {
+	dir, err := ioutil.TempDir("", "timeout_diagnostics")
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error creating timeout diagnostics directory: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if !diagnostics.DisableStackDumps {
+		agt.dumpProcessStacks(dir)
+	}
+
+	if diagnostics.Commands != nil {
+		agt.logger.LogTask(slogger.INFO, "Running timeout diagnostics commands.")
+		agt.taskConfig.Expansions.Put(TimeoutDiagnosticsDirExpansion, dir)
+		err = agt.runCommandBlock(diagnostics.Commands.List(), false, nil, apimodels.CommandBlockTimeoutDiagnostics,
+			blockTimeout(agt.taskConfig.Project.CallbackTimeoutSecs, DefaultCallbackTimeout))
+		if err != nil {
+			agt.logger.LogExecution(slogger.ERROR, "Error running timeout diagnostics command: %v", err)
+		}
+	}
+
+	if err = agt.attachDiagnostics(dir); err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error attaching timeout diagnostics: %v", err)
+	}
+}
+
+// dumpProcessStacks writes the agent's process tree to a file under dir,
+// then the stack of each of the processes in it to a file of its own.
+func (agt *Agent) dumpProcessStacks(dir string) {
+	ps := exec.Command("ps", "-A", "-o", "pid,ppid,etime,args")
+	out, err := ps.CombinedOutput()
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error listing processes: %v", err)
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+
+	listing := []string{lines[0]}
+	tree := []process{}
+	for _, proc := range descendants(parseProcesses(lines[1:]), os.Getpid()) {
+		// ps lists itself
+		if proc.Pid == ps.Process.Pid {
+			continue
+		}
+		listing = append(listing, proc.Line)
+		tree = append(tree, proc)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, processTreeFile),
+		[]byte(strings.Join(listing, "\n")+"\n"), 0644)
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error writing process tree: %v", err)
+	}
+
+	for _, proc := range tree {
+		stack, err := processStack(proc)
+		if err != nil {
+			agt.logger.LogExecution(slogger.WARN, "Error getting the stack of process %v: %v",
+				proc.Pid, err)
+			continue
+		}
+		if strings.TrimSpace(stack) == "" {
+			continue
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf(stackDumpFile, proc.Pid)),
+			[]byte(agt.logger.Redact(proc.Line+"\n\n"+stack)), 0644)
+		if err != nil {
+			agt.logger.LogExecution(slogger.ERROR, "Error writing stack of process %v: %v",
+				proc.Pid, err)
+		}
+	}
+}
+
+// attachDiagnostics attaches each file under dir to the task as a test log,
+// adding a test result for it alongside any the task already has.
+func (agt *Agent) attachDiagnostics(dir string) error {
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	pluginCom := &TaskJSONCommunicator{"timeout_diagnostics", agt.TaskCommunicator}
+
+	results := []model.TestResult{}
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.Mode().IsRegular() {
+			continue
+		}
+		if fileInfo.Size() > MaxDiagnosticsFileSize {
+			agt.logger.LogTask(slogger.WARN, "Not attaching %v: it is %v bytes, more than"+
+				" the limit of %v", fileInfo.Name(), fileInfo.Size(), MaxDiagnosticsFileSize)
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, fileInfo.Name()))
+		if err != nil {
+			return err
+		}
+		name := "timeout_diagnostics/" + fileInfo.Name()
+		logId, err := pluginCom.TaskPostTestLog(&model.TestLog{
+			Name:          name,
+			Task:          agt.taskConfig.Task.Id,
+			TaskExecution: agt.taskConfig.Task.Execution,
+			Lines:         strings.Split(string(data), "\n"),
+		})
+		if err != nil {
+			return err
+		}
+		now := float64(time.Now().Unix())
+		results = append(results, model.TestResult{
+			// not a failing test in its own right
+			Status:    evergreen.TestSkippedStatus,
+			TestFile:  name,
+			LogId:     logId,
+			StartTime: now,
+			EndTime:   now,
+		})
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	agt.logger.LogTask(slogger.INFO, "Attaching %v timeout diagnostics files", len(results))
+
+	// results replace the ones the task already has, so keep those
+	task, err := agt.GetTask()
+	if err != nil {
+		return err
+	}
+	if task != nil {
+		results = append(task.TestResults, results...)
+	}
+	return pluginCom.TaskPostResults(&model.TestResults{Results: results})
+}
+
+// parseProcesses parses lines of ps output, in the format given by
+// "-o pid,ppid,...".
+func parseProcesses(lines []string) []process {
+	procs := []process{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		procs = append(procs, process{Pid: pid, PPid: ppid, Line: line})
+	}
+	return procs
+}
+
+// descendants returns the processes that are descended from the one with
+// the given pid, in the order they were listed.
+func descendants(procs []process, pid int) []process {
+	inTree := map[int]bool{pid: true}
+	tree := []process{}
+	// ps can list a child before its parent, so keep making passes until
+	// no more processes are found to be in the tree
+	for found := true; found; {
+		found = false
+		for _, proc := range procs {
+			if !inTree[proc.Pid] && inTree[proc.PPid] {
+				inTree[proc.Pid] = true
+				found = true
+			}
+		}
+	}
+	for _, proc := range procs {
+		if proc.Pid != pid && inTree[proc.Pid] {
+			tree = append(tree, proc)
+		}
+	}
+	return tree
+}
+
+// processStack returns the stack of the process, from a tool that prints
+// it without disturbing the process or anything else: jstack for Java
+// processes, and gdb for the rest. Without those, it falls back on the
+// kernel stack the process is blocked in, where /proc has it.
+func processStack(proc process) (string, error) {
+	pid := strconv.Itoa(proc.Pid)
+	var cmd *exec.Cmd
+	if isJava(proc) {
+		if path, err := exec.LookPath("jstack"); err == nil {
+			cmd = exec.Command(path, "-l", pid)
+		}
+	}
+	if cmd == nil {
+		if path, err := exec.LookPath("gdb"); err == nil {
+			cmd = exec.Command(path, "-p", pid, "-batch", "-ex", "thread apply all bt")
+		}
+	}
+	if cmd == nil {
+		data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%v/stack", proc.Pid))
+		return string(data), err
+	}
+
+	out := &bytes.Buffer{}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", fmt.Errorf("%v: %v", err, strings.TrimSpace(out.String()))
+		}
+		return out.String(), nil
+	case <-time.After(StackDumpWait):
+		cmd.Process.Kill()
+		<-done
+		return "", fmt.Errorf("%v did not finish within %v", filepath.Base(cmd.Path), StackDumpWait)
+	}
+}
+
+// isJava returns whether the process runs a Java program.
+func isJava(proc process) bool {
+	fields := strings.Fields(proc.Line)
+	// pid, ppid and elapsed time come before the command
+	if len(fields) < 4 {
+		return false
+	}
+	return filepath.Base(fields[3]) == "java"
+}
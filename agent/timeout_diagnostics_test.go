@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"encoding/json"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var timeoutDiagnosticsProject = `
+timeout_diagnostics:
+  commands:
+    - command: shell.exec
+      params:
+        script: "echo collected > ${timeout_diagnostics_dir}/extra.txt"
+tasks:
+  - name: hang
+    commands:
+      - command: shell.exec
+        timeout_secs: 1
+        params:
+          script: "sleep 30"
+buildvariants:
+  - name: linux
+    tasks:
+      - name: hang
+`
+
+func TestProcessTree(t *testing.T) {
+	Convey("With a listing of processes", t, func() {
+		procs := parseProcesses([]string{
+			"    1     0 10:00 /sbin/init",
+			"  300   200 00:05 sleep 30",
+			"  100     1 05:00 evergreen agent",
+			"  200   100 00:10 /bin/sh -c sleep 30",
+			"  400     1 01:00 /usr/bin/java -jar other.jar",
+			"  bad line",
+		})
+		So(len(procs), ShouldEqual, 5)
+		So(procs[0].Pid, ShouldEqual, 1)
+		So(procs[1].PPid, ShouldEqual, 200)
+
+		Convey("descendants should include children listed before their parents", func() {
+			tree := descendants(procs, 100)
+			So(len(tree), ShouldEqual, 2)
+			So(tree[0].Pid, ShouldEqual, 300)
+			So(tree[1].Pid, ShouldEqual, 200)
+		})
+
+		Convey("java processes should be told apart from others", func() {
+			So(isJava(procs[4]), ShouldBeTrue)
+			So(isJava(procs[1]), ShouldBeFalse)
+		})
+	})
+}
+
+// runTimingOutTask runs the hang task of the project, which must time out,
+// and returns the lines of each timeout diagnostics file attached to it.
+func runTimingOutTask(projectYAML string) map[string][]string {
+	dir, err := ioutil.TempDir("", "timeout_diagnostics")
+	So(err, ShouldBeNil)
+	Reset(func() { os.RemoveAll(dir) })
+
+	project := &model.Project{}
+	So(model.LoadProjectInto([]byte(projectYAML), "diagnostics", project), ShouldBeNil)
+	communicator := &LocalCommunicator{
+		Task: &model.Task{
+			Id:           "diagnostics_linux_hang",
+			DisplayName:  "hang",
+			BuildVariant: "linux",
+			Project:      "diagnostics",
+			Requester:    evergreen.RepotrackerVersionRequester,
+		},
+		Distro:     &distro.Distro{Id: "local", WorkDir: dir},
+		Project:    project,
+		ProjectRef: &model.ProjectRef{Identifier: "diagnostics"},
+		Dir:        filepath.Join(dir, "output"),
+	}
+	agt, err := NewLocal(communicator, "")
+	So(err, ShouldBeNil)
+	_, err = agt.RunTask()
+	So(err, ShouldBeNil)
+	So(communicator.EndDetail.TimedOut, ShouldBeTrue)
+
+	data, err := ioutil.ReadFile(filepath.Join(communicator.Dir, "results_1.json"))
+	So(err, ShouldBeNil)
+	results := &model.TestResults{}
+	So(json.Unmarshal(data, results), ShouldBeNil)
+
+	files := map[string][]string{}
+	for _, result := range results.Results {
+		data, err = ioutil.ReadFile(filepath.Join(communicator.Dir, result.LogId))
+		So(err, ShouldBeNil)
+		testLog := &model.TestLog{}
+		So(json.Unmarshal(data, testLog), ShouldBeNil)
+		files[strings.TrimPrefix(result.TestFile, "timeout_diagnostics/")] = testLog.Lines
+	}
+	return files
+}
+
+func TestTimeoutDiagnostics(t *testing.T) {
+	Convey("With a task that times out", t, func() {
+		files := runTimingOutTask(timeoutDiagnosticsProject)
+
+		Convey("the process tree and the commands' files should be attached", func() {
+			So(files["extra.txt"], ShouldContain, "collected")
+			So(strings.Join(files[processTreeFile], "\n"), ShouldContainSubstring, "sleep 30")
+		})
+
+		Convey("each process's stack should be in a file of its own", func() {
+			for name, lines := range files {
+				if strings.HasPrefix(name, "stack_") {
+					So(len(lines), ShouldBeGreaterThan, 0)
+					So(strings.TrimSpace(lines[0]), ShouldStartWith, strings.TrimSuffix(
+						strings.TrimPrefix(name, "stack_"), ".txt"))
+				}
+			}
+		})
+	})
+
+	Convey("With a task that exceeds its exec timeout in a project without"+
+		" timeout diagnostics", t, func() {
+		project := strings.Replace(timeoutDiagnosticsProject, "timeout_diagnostics:", "unused:", 1)
+		project = strings.Replace(project, "        timeout_secs: 1\n", "", 1)
+		project = strings.Replace(project, "    commands:", "    exec_timeout: 1\n    commands:", 1)
+		files := runTimingOutTask(project)
+
+		Convey("the process tree should still be attached", func() {
+			So(strings.Join(files[processTreeFile], "\n"), ShouldContainSubstring, "sleep 30")
+			So(files["extra.txt"], ShouldBeNil)
+		})
+	})
+}
@@ -26,5 +26,11 @@ func TestTimeoutWatcher(t *testing.T) {
 			So(outSignal, ShouldEqual, IdleTimeout)
 			So(ended, ShouldNotHappenWithin, 3900*time.Millisecond, started)
 		})
+
+		Convey("an exec timeout watcher should send its own signal", func() {
+			tw.signal = ExecTimeout
+			go tw.NotifyTimeouts(signalChan)
+			So(<-signalChan, ShouldEqual, ExecTimeout)
+		})
 	})
 }
@@ -0,0 +1,321 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen/model"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMinFreeDisk is how many megabytes must be free in a distro's
+	// working directory to start a task, if the distro doesn't say.
+	DefaultMinFreeDisk = 1024
+	// DefaultDiskCheckInterval is how often the agent measures the disk
+	// used by a task's directory.
+	DefaultDiskCheckInterval = 60 * time.Second
+
+	// the prefix of the directories tasks run in, under a distro's working
+	// directory
+	taskDirPrefix = "task_"
+
+	megabyte = 1024 * 1024
+)
+
+var (
+	// DiskCheckCommand is a placeholder command for the period during which
+	// the agent makes sure there's enough disk to run a task.
+	DiskCheckCommand = model.PluginCommandConf{
+		DisplayName: "disk space check",
+		Type:        model.SystemCommandType,
+	}
+)
+
+// taskDirectory returns the directory under the working directory that an
+// execution of a task runs in.
+func taskDirectory(workDir string, task *model.Task) string {
+	return filepath.Join(workDir, fmt.Sprintf("%v%v_%v", taskDirPrefix, task.Id, task.Execution))
+}
+
+// prepareTaskDir gets the task's directory ready for it to run in: it
+// clears out anything left behind by earlier tasks, checks that there's
+// enough free disk, creates the directory, and starts the agent's disk
+// watcher on it.
+func (agt *Agent) prepareTaskDir() error {
+	d := agt.taskConfig.Distro
+	if !agt.keepWorkDir {
+		agt.removeStaleTaskDirs(d.WorkDir)
+	}
+
+	minFree := int64(d.MinFreeDisk)
+	if minFree == 0 {
+		minFree = DefaultMinFreeDisk
+	}
+	free, err := freeDisk(d.WorkDir)
+	if err != nil {
+		// not knowing how much is free shouldn't stop the task
+		agt.logger.LogExecution(slogger.WARN, "Error checking free disk space: %v", err)
+	} else if free < minFree*megabyte {
+		agt.logger.LogTask(slogger.ERROR, "Only %v MB of disk is free in %v, but %v MB are"+
+			" needed to start a task", free/megabyte, d.WorkDir, minFree)
+		return fmt.Errorf("not enough free disk space")
+	}
+
+	if !agt.keepWorkDir {
+		if err = os.MkdirAll(agt.taskConfig.WorkDir, 0777); err != nil {
+			return fmt.Errorf("error creating task directory: %v", err)
+		}
+		agt.taskDir = agt.taskConfig.WorkDir
+	}
+
+	agt.diskWatcher.Watch(agt.signalChan)
+	return nil
+}
+
+// removeStaleTaskDirs removes the directories of earlier tasks from the
+// working directory, in case the agents that ran them couldn't.
+func (agt *Agent) removeStaleTaskDirs(workDir string) {
+	fileInfos, err := ioutil.ReadDir(workDir)
+	if err != nil {
+		agt.logger.LogExecution(slogger.WARN, "Error reading working directory: %v", err)
+		return
+	}
+	for _, fileInfo := range fileInfos {
+		if fileInfo.IsDir() && strings.HasPrefix(fileInfo.Name(), taskDirPrefix) {
+			agt.logger.LogExecution(slogger.INFO, "Removing directory %v left by an earlier task",
+				fileInfo.Name())
+			agt.removeDir(filepath.Join(workDir, fileInfo.Name()))
+		}
+	}
+}
+
+// removeTaskDir removes the task's directory, if it has one of its own.
+func (agt *Agent) removeTaskDir() {
+	if agt.taskDir == "" {
+		return
+	}
+	agt.logger.LogExecution(slogger.INFO, "Removing task directory %v", agt.taskDir)
+	agt.removeDir(agt.taskDir)
+}
+
+// removeDir kills the task processes still holding files open in the
+// directory, so that nothing stops it from being removed, then removes it.
+// Only processes in the agent's process group, which the processes it runs
+// for tasks inherit, are killed; anything else using the directory is left
+// alone.
+func (agt *Agent) removeDir(dir string) {
+	agentGroup, err := processGroup(os.Getpid())
+	if err != nil {
+		agt.logger.LogExecution(slogger.WARN, "Error finding the agent's process group: %v", err)
+	}
+	for _, pid := range processesUsingDir(dir) {
+		group, err := processGroup(pid)
+		if err != nil || agentGroup == 0 || group != agentGroup {
+			agt.logger.LogExecution(slogger.WARN, "Not killing process %v, which has files in %v"+
+				" open, since it isn't one of the task's", pid, dir)
+			continue
+		}
+		proc, err := os.FindProcess(pid)
+		if err == nil {
+			err = proc.Kill()
+		}
+		if err != nil {
+			agt.logger.LogExecution(slogger.WARN, "Error killing process %v: %v", pid, err)
+			continue
+		}
+		agt.logger.LogExecution(slogger.INFO, "Killed process %v, which had files in %v open", pid, dir)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error removing %v: %v", dir, err)
+	}
+}
+
+// processesUsingDir returns the processes, other than the agent, that have
+// the directory or anything in it as their working directory or open. It
+// can only find them where there's /proc; elsewhere it finds none.
+func processesUsingDir(dir string) []int {
+	procDirs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	inDir := func(path string) bool {
+		return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+	}
+
+	pids := []int{}
+	for _, procDir := range procDirs {
+		pid, err := strconv.Atoi(procDir.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		procPath := filepath.Join("/proc", procDir.Name())
+		using := false
+		if cwd, err := os.Readlink(filepath.Join(procPath, "cwd")); err == nil && inDir(cwd) {
+			using = true
+		}
+		fds, _ := ioutil.ReadDir(filepath.Join(procPath, "fd"))
+		for i := 0; i < len(fds) && !using; i++ {
+			target, err := os.Readlink(filepath.Join(procPath, "fd", fds[i].Name()))
+			using = err == nil && inDir(target)
+		}
+		if using {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// processGroup returns the id of the process group the process is in, as
+// listed in its /proc/<pid>/stat.
+func processGroup(pid int) (int, error) {
+	data, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// the command comes second, in parentheses, and may contain spaces; the
+	// state, parent pid and process group follow it
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 3 {
+		return 0, fmt.Errorf("unexpected contents of /proc/%v/stat: %v", pid, stat)
+	}
+	return strconv.Atoi(fields[2])
+}
+
+// freeDisk returns how many bytes are free on the filesystem the directory
+// is on, as reported by df.
+func freeDisk(dir string) (int64, error) {
+	out, err := exec.Command("df", "-Pk", dir).Output()
+	if err != nil {
+		return 0, fmt.Errorf("error running df: %v", err)
+	}
+	return parseFreeDisk(string(out))
+}
+
+// parseFreeDisk parses the number of bytes available out of the output of
+// "df -Pk".
+func parseFreeDisk(out string) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(lines) < 2 || len(fields) < 4 {
+		return 0, fmt.Errorf("unexpected output from df: %v", out)
+	}
+	kilobytes, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected output from df: %v", out)
+	}
+	return kilobytes * 1024, nil
+}
+
+// diskUsage returns the total size, in bytes, of the files under the
+// directory.
+func diskUsage(dir string) (int64, error) {
+	var total int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files can disappear while the task is running
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// DiskWatcher measures the disk used by a task's directory at regular
+// intervals, reporting it in the system logs, and raises a signal if the
+// task goes over its quota.
+type DiskWatcher struct {
+	Dir string
+	// the most bytes the task may use; zero means there's no quota
+	Quota    int64
+	Interval time.Duration
+	Logger   *slogger.Logger
+
+	// the bytes the task was using when last measured
+	usage int64
+	// guards usage, which the resource sampler also reads
+	usageLock sync.Mutex
+	// when sent a value, this stops the watcher
+	stop chan bool
+}
+
+// Watch starts measuring the directory in the background, sending
+// DiskQuotaExceeded on sigChan if the task goes over its quota.
+func (dw *DiskWatcher) Watch(sigChan chan Signal) {
+	dw.stop = make(chan bool, 1)
+	go func() {
+		ticker := time.NewTicker(dw.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if dw.check() {
+					// wait for the signal to be taken, unless the agent
+					// stops the watcher first
+					select {
+					case sigChan <- DiskQuotaExceeded:
+					case <-dw.stop:
+					}
+					return
+				}
+			case <-dw.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the watcher.
+func (dw *DiskWatcher) Stop() {
+	if dw.stop != nil {
+		dw.stop <- true
+	}
+}
+
+// check measures the directory, and returns whether the task is over its
+// quota.
+func (dw *DiskWatcher) check() bool {
+	usage, err := diskUsage(dw.Dir)
+	if err != nil {
+		dw.Logger.Logf(slogger.WARN, "Error measuring disk usage of %v: %v", dw.Dir, err)
+		return false
+	}
+	dw.usageLock.Lock()
+	dw.usage = usage
+	dw.usageLock.Unlock()
+	if dw.Quota == 0 {
+		dw.Logger.Logf(slogger.INFO, "Task directory %v is using %v MB of disk",
+			dw.Dir, usage/megabyte)
+		return false
+	}
+	dw.Logger.Logf(slogger.INFO, "Task directory %v is using %v MB of disk, of its %v MB quota",
+		dw.Dir, usage/megabyte, dw.Quota/megabyte)
+	return usage > dw.Quota
+}
+
+// Usage returns the bytes the task was using when last measured, or zero if
+// it hasn't been measured yet.
+func (dw *DiskWatcher) Usage() int64 {
+	dw.usageLock.Lock()
+	defer dw.usageLock.Unlock()
+	return dw.usage
+}
+
+// QuotaMessage describes how far over its quota the task went.
+func (dw *DiskWatcher) QuotaMessage() string {
+	return fmt.Sprintf("disk quota exceeded: task used %v MB, more than its quota of %v MB",
+		dw.Usage()/megabyte, dw.Quota/megabyte)
+}
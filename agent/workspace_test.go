@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var workspaceProject = `
+tasks:
+  - name: write
+    commands:
+      - command: shell.exec
+        params:
+          script: "echo workdir is ${workdir} && echo data > data.txt"
+buildvariants:
+  - name: linux
+    tasks:
+      - name: write
+`
+
+func TestDiskMeasurement(t *testing.T) {
+	Convey("With a directory of files", t, func() {
+		dir, err := ioutil.TempDir("", "workspace")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+		So(os.MkdirAll(filepath.Join(dir, "sub"), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "one"), make([]byte, 1000), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "sub", "two"), make([]byte, 500), 0644), ShouldBeNil)
+
+		Convey("its usage should be the total size of the files in it", func() {
+			usage, err := diskUsage(dir)
+			So(err, ShouldBeNil)
+			So(usage, ShouldEqual, 1500)
+		})
+
+		Convey("free space should be parsed out of df's output", func() {
+			free, err := parseFreeDisk("Filesystem 1024-blocks Used Available Capacity Mounted on\n" +
+				"/dev/sda1 10000 6000 4000 60% /\n")
+			So(err, ShouldBeNil)
+			So(free, ShouldEqual, 4000*1024)
+			_, err = parseFreeDisk("df: no such file or directory")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("a disk watcher should signal once the quota is exceeded", func() {
+			sigChan := make(chan Signal, 1)
+			watcher := &DiskWatcher{
+				Dir:      dir,
+				Quota:    2000,
+				Interval: 10 * time.Millisecond,
+				Logger:   &slogger.Logger{Appenders: []slogger.Appender{}},
+			}
+			watcher.Watch(sigChan)
+			Reset(watcher.Stop)
+
+			time.Sleep(50 * time.Millisecond)
+			So(len(sigChan), ShouldEqual, 0)
+
+			So(ioutil.WriteFile(filepath.Join(dir, "three"), make([]byte, 1000), 0644), ShouldBeNil)
+			select {
+			case sig := <-sigChan:
+				So(sig, ShouldEqual, DiskQuotaExceeded)
+			case <-time.After(time.Second):
+				So("no signal received", ShouldBeNil)
+			}
+			So(watcher.QuotaMessage(), ShouldContainSubstring, "disk quota exceeded")
+		})
+
+		Convey("a disk watcher should wait for its signal to be taken", func() {
+			sigChan := make(chan Signal)
+			watcher := &DiskWatcher{
+				Dir:      dir,
+				Quota:    1000,
+				Interval: 10 * time.Millisecond,
+				Logger:   &slogger.Logger{Appenders: []slogger.Appender{}},
+			}
+			watcher.Watch(sigChan)
+			Reset(watcher.Stop)
+
+			time.Sleep(50 * time.Millisecond)
+			select {
+			case sig := <-sigChan:
+				So(sig, ShouldEqual, DiskQuotaExceeded)
+			case <-time.After(time.Second):
+				So("no signal received", ShouldBeNil)
+			}
+		})
+
+		Convey("processes working in it should be found", func() {
+			if _, err := os.Stat("/proc"); err != nil {
+				SkipSo("there is no /proc")
+				return
+			}
+			cmd := exec.Command("sleep", "30")
+			cmd.Dir = filepath.Join(dir, "sub")
+			So(cmd.Start(), ShouldBeNil)
+			Reset(func() { cmd.Process.Kill(); cmd.Wait() })
+			So(processesUsingDir(dir), ShouldContain, cmd.Process.Pid)
+
+			// the processes the agent starts share its process group
+			agentGroup, err := processGroup(os.Getpid())
+			So(err, ShouldBeNil)
+			group, err := processGroup(cmd.Process.Pid)
+			So(err, ShouldBeNil)
+			So(group, ShouldEqual, agentGroup)
+		})
+	})
+}
+
+func TestTaskDirectory(t *testing.T) {
+	Convey("With a task run by an agent that manages its directory", t, func() {
+		dir, err := ioutil.TempDir("", "workspace")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+		So(os.MkdirAll(filepath.Join(dir, taskDirPrefix+"old_0"), 0755), ShouldBeNil)
+
+		project := &model.Project{}
+		So(model.LoadProjectInto([]byte(workspaceProject), "workspace", project), ShouldBeNil)
+		communicator := &LocalCommunicator{
+			Task: &model.Task{
+				Id:           "workspace_linux_write",
+				DisplayName:  "write",
+				BuildVariant: "linux",
+				Project:      "workspace",
+				Requester:    evergreen.RepotrackerVersionRequester,
+			},
+			Distro:     &distro.Distro{Id: "local", WorkDir: dir, MinFreeDisk: 1},
+			Project:    project,
+			ProjectRef: &model.ProjectRef{Identifier: "workspace"},
+			Dir:        filepath.Join(dir, "output"),
+		}
+		agt, err := newAgent(communicator, make(chan Signal, 1), "")
+		So(err, ShouldBeNil)
+		_, err = agt.RunTask()
+		So(err, ShouldBeNil)
+		So(communicator.EndDetail.Status, ShouldEqual, evergreen.TaskSucceeded)
+
+		Convey("the task should run in a directory of its own", func() {
+			taskLog, err := ioutil.ReadFile(filepath.Join(communicator.Dir, LocalTaskLogFile))
+			So(err, ShouldBeNil)
+			So(string(taskLog), ShouldContainSubstring, "workdir is "+
+				taskDirectory(dir, communicator.Task))
+		})
+
+		Convey("the task's directory and earlier ones should be removed", func() {
+			fileInfos, err := ioutil.ReadDir(dir)
+			So(err, ShouldBeNil)
+			So(len(fileInfos), ShouldEqual, 1)
+			So(fileInfos[0].Name(), ShouldEqual, "output")
+		})
+	})
+
+	Convey("With a distro that needs more free disk than there is", t, func() {
+		dir, err := ioutil.TempDir("", "workspace")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		project := &model.Project{}
+		So(model.LoadProjectInto([]byte(workspaceProject), "workspace", project), ShouldBeNil)
+		communicator := &LocalCommunicator{
+			Task: &model.Task{
+				Id:           "workspace_linux_write",
+				DisplayName:  "write",
+				BuildVariant: "linux",
+				Project:      "workspace",
+				Requester:    evergreen.RepotrackerVersionRequester,
+			},
+			Distro:     &distro.Distro{Id: "local", WorkDir: dir, MinFreeDisk: 1 << 30},
+			Project:    project,
+			ProjectRef: &model.ProjectRef{Identifier: "workspace"},
+			Dir:        filepath.Join(dir, "output"),
+		}
+		agt, err := newAgent(communicator, make(chan Signal, 1), "")
+		So(err, ShouldBeNil)
+
+		Convey("the task should fail as a system failure", func() {
+			_, err = agt.RunTask()
+			So(err, ShouldBeNil)
+			So(communicator.EndDetail.Status, ShouldEqual, evergreen.TaskFailed)
+			So(communicator.EndDetail.Type, ShouldEqual, model.SystemCommandType)
+			So(communicator.EndDetail.Description, ShouldEqual, DiskCheckCommand.DisplayName)
+		})
+	})
+}
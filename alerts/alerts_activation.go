@@ -115,9 +115,21 @@ func RunHostProvisionFailTriggers(h *host.Host) error {
 	return storeTriggerBookkeeping(ctx, []Trigger{trigger})
 }
 
+// RunSpawnWarningTriggers queues the warnings that a spawn host is about
+// to expire.
 func RunSpawnWarningTriggers(host *host.Host) error {
+	return runHostTriggers(host, SpawnWarningTriggers)
+}
+
+// RunSpawnIdleTriggers queues the warning that a spawn host is about to be
+// terminated for going unused.
+func RunSpawnIdleTriggers(host *host.Host) error {
+	return runHostTriggers(host, SpawnIdleTriggers)
+}
+
+func runHostTriggers(host *host.Host, triggers []Trigger) error {
 	ctx := triggerContext{host: host}
-	for _, trigger := range SpawnWarningTriggers {
+	for _, trigger := range triggers {
 		shouldExec, err := trigger.ShouldExecute(ctx)
 		if err != nil {
 			return err
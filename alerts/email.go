@@ -123,6 +123,8 @@ func getTemplate(alertCtx AlertContext) string {
 	case alertrecord.SpawnHostTwoHourWarning:
 		fallthrough
 	case alertrecord.SpawnHostTwelveHourWarning:
+		fallthrough
+	case alertrecord.SpawnHostIdleWarning:
 		return "email/host_spawn.html"
 	default:
 		return "email/task_fail.html"
@@ -175,6 +177,10 @@ func getSubject(alertCtx AlertContext) string {
 	case alertrecord.SpawnHostTwelveHourWarning:
 		return fmt.Sprintf("Your %s host (%s) will expire in twelve hours.",
 			alertCtx.Host.Distro, alertCtx.Host.Id)
+	case alertrecord.SpawnHostIdleWarning:
+		return fmt.Sprintf("Your %s host (%s) has not been used recently, and will be terminated soon."+
+			" Terminated hosts can't be restarted, so copy off anything you need from it.",
+			alertCtx.Host.Distro.Id, alertCtx.Host.Id)
 		// TODO(EVG-224) alertrecord.SpawnHostExpired:
 	}
 
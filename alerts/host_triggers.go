@@ -3,9 +3,16 @@ package alerts
 import (
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/model/alertrecord"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/model/user"
 	"time"
 )
 
+const (
+	// how long before an idle spawn host is terminated its user is warned
+	SpawnIdleWarningLead = 2 * time.Hour
+)
+
 // Host Triggers
 
 type SpawnTwoHourWarning struct{}
@@ -58,6 +65,66 @@ func (sthw SpawnTwelveHourWarning) ShouldExecute(ctx triggerContext) (bool, erro
 	return rec == nil, nil
 }
 
+type SpawnIdleWarning struct{}
+
+func (siw SpawnIdleWarning) Id() string { return alertrecord.SpawnHostIdleWarning }
+
+func (siw SpawnIdleWarning) Display() string {
+	return "Spawn host is due to be terminated for being idle"
+}
+
+func (siw SpawnIdleWarning) CreateAlertRecord(ctx triggerContext) *alertrecord.AlertRecord {
+	rec := newAlertRecord(ctx, alertrecord.SpawnHostIdleWarning)
+	return rec
+}
+
+func (siw SpawnIdleWarning) ShouldExecute(ctx triggerContext) (bool, error) {
+	if ctx.host == nil || ctx.host.Status != evergreen.HostRunning {
+		return false, nil
+	}
+	timeout, err := user.FindSpawnHostIdleTimeout(ctx.host.StartedBy)
+	if err != nil {
+		return false, err
+	}
+	if ctx.host.UserIdleTime() < SpawnIdleWarningTime(timeout) {
+		return false, nil
+	}
+	sent, err := LastSpawnIdleWarning(ctx.host)
+	if err != nil {
+		return false, err
+	}
+	return sent.IsZero(), nil
+}
+
+// SpawnIdleWarningTime returns how long a spawn host with the given idle
+// timeout may go unused before its user is warned. Short timeouts get a
+// proportionally shorter warning.
+func SpawnIdleWarningTime(timeout time.Duration) time.Duration {
+	lead := SpawnIdleWarningLead
+	if lead > timeout/2 {
+		lead = timeout / 2
+	}
+	return timeout - lead
+}
+
+// LastSpawnIdleWarning returns when the host's user was warned that it is
+// idle, or the zero time if they haven't been since they last used it.
+func LastSpawnIdleWarning(h *host.Host) (time.Time, error) {
+	rec, err := alertrecord.FindOne(alertrecord.ByLatestHostAlertRecordType(h.Id, alertrecord.SpawnHostIdleWarning))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if rec == nil {
+		return time.Time{}, nil
+	}
+	// record ids are created along with the record
+	sent := rec.Id.Time()
+	if !sent.After(h.LastUserActivity) {
+		return time.Time{}, nil
+	}
+	return sent, nil
+}
+
 type SlowProvisionWarning struct{}
 
 func (sthw SlowProvisionWarning) Id() string { return alertrecord.SlowProvisionWarning }
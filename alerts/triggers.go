@@ -56,6 +56,8 @@ var (
 	}
 
 	SpawnWarningTriggers = []Trigger{SpawnTwoHourWarning{}, SpawnTwelveHourWarning{}}
+
+	SpawnIdleTriggers = []Trigger{SpawnIdleWarning{}}
 )
 
 // newAlertRecord creates an instance of an alert record for the given alert type, populating it
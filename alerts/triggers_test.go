@@ -6,6 +6,7 @@ import (
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/alertrecord"
 	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/model/user"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
 	"time"
@@ -178,3 +179,58 @@ func TestSpawnExpireWarningTrigger(t *testing.T) {
 		So(shouldExec, ShouldBeFalse)
 	})
 }
+
+func TestSpawnIdleWarningTrigger(t *testing.T) {
+	Convey("With a spawnhost that has been idle for a day", t, func() {
+		db.Clear(host.Collection)
+		db.Clear(alertrecord.Collection)
+		db.Clear(user.Collection)
+		testHost := host.Host{
+			Id:           "testhost",
+			StartedBy:    "test_user",
+			Status:       "running",
+			CreationTime: time.Now().Add(-48 * time.Hour),
+		}
+		testHost.LastUserActivity = time.Now().Add(-23 * time.Hour)
+
+		trigger := SpawnIdleWarning{}
+		ctx := triggerContext{host: &testHost}
+		shouldExec, err := trigger.ShouldExecute(ctx)
+		So(err, ShouldBeNil)
+		So(shouldExec, ShouldBeTrue)
+
+		// run bookkeeping
+		err = storeTriggerBookkeeping(ctx, []Trigger{trigger})
+		So(err, ShouldBeNil)
+
+		// should exec should now return false
+		shouldExec, err = trigger.ShouldExecute(ctx)
+		So(err, ShouldBeNil)
+		So(shouldExec, ShouldBeFalse)
+
+		Convey("a user with a longer idle timeout should not be warned", func() {
+			db.Clear(alertrecord.Collection)
+			u := &user.DBUser{Id: "test_user", Settings: user.UserSettings{SpawnHostIdleHours: 48}}
+			So(u.Insert(), ShouldBeNil)
+			shouldExec, err = trigger.ShouldExecute(ctx)
+			So(err, ShouldBeNil)
+			So(shouldExec, ShouldBeFalse)
+		})
+
+		Convey("the warning should be forgotten once the user uses the host", func() {
+			sent, err := LastSpawnIdleWarning(&testHost)
+			So(err, ShouldBeNil)
+			So(sent.IsZero(), ShouldBeFalse)
+
+			testHost.LastUserActivity = time.Now().Add(time.Second)
+			sent, err = LastSpawnIdleWarning(&testHost)
+			So(err, ShouldBeNil)
+			So(sent.IsZero(), ShouldBeTrue)
+		})
+	})
+
+	Convey("Users should be warned ahead of their idle timeouts", t, func() {
+		So(SpawnIdleWarningTime(24*time.Hour), ShouldEqual, 22*time.Hour)
+		So(SpawnIdleWarningTime(2*time.Hour), ShouldEqual, time.Hour)
+	})
+}
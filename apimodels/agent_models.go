@@ -1,5 +1,22 @@
 package apimodels
 
+import "time"
+
+// The blocks of a task's commands that a command can be run in.
+const (
+	CommandBlockPre     = "pre"
+	CommandBlockTask    = "task"
+	CommandBlockPost    = "post"
+	CommandBlockTimeout = "timeout"
+
+	CommandBlockTimeoutDiagnostics = "timeout_diagnostics"
+)
+
+// CommandSkipped is the status of a command that wasn't run, either because
+// it doesn't run on the task's variant or because an earlier command in its
+// block failed.
+const CommandSkipped = "skipped"
+
 // TaskStartRequest holds information sent by the agent to the
 // API server at the beginning of each task run.
 type TaskStartRequest struct {
@@ -19,6 +36,9 @@ type TaskEndDetail struct {
 	Type        string `bson:"type,omitempty" json:"type,omitempty"`
 	Description string `bson:"desc,omitempty" json:"desc,omitempty"`
 	TimedOut    bool   `bson:"timed_out,omitempty" json:"timed_out,omitempty"`
+	// true if the project's pre block failed, whether the task stopped there
+	// or went on to run
+	SetupFailed bool `bson:"setup_failed,omitempty" json:"setup_failed,omitempty"`
 }
 
 type TaskEndDetails struct {
@@ -26,6 +46,44 @@ type TaskEndDetails struct {
 	TimedOut     bool   `bson:"timed_out,omitempty" json:"timed_out,omitempty"`
 }
 
+// CommandRecord is sent by the agent to the API server for each command it
+// runs, or skips, while running a task.
+type CommandRecord struct {
+	// the plugin command, e.g. shell.exec
+	Name        string `bson:"name" json:"name"`
+	DisplayName string `bson:"display_name,omitempty" json:"display_name,omitempty"`
+	// the function the command was run as part of, if any
+	Function string `bson:"function,omitempty" json:"function,omitempty"`
+	// which of the task's blocks of commands the command was in
+	Block     string    `bson:"block" json:"block"`
+	StartTime time.Time `bson:"start_time" json:"start_time"`
+	EndTime   time.Time `bson:"end_time" json:"end_time"`
+	// whether the command succeeded, failed or was skipped, and if it
+	// failed, why
+	Status  string `bson:"status,omitempty" json:"status,omitempty"`
+	Error   string `bson:"error,omitempty" json:"error,omitempty"`
+	Skipped bool   `bson:"skipped,omitempty" json:"skipped,omitempty"`
+}
+
+// ResourceSample is a measurement of the resources in use on an agent's host
+// at one point while it runs a task. Rates are averaged over the time since
+// the previous sample.
+type ResourceSample struct {
+	Time time.Time `bson:"time" json:"time"`
+	// how busy the host's CPUs were, from 0 to 100
+	CPUPercent float64 `bson:"cpu_percent" json:"cpu_percent"`
+	// in bytes
+	MemoryUsed  int64 `bson:"memory_used" json:"memory_used"`
+	MemoryTotal int64 `bson:"memory_total" json:"memory_total"`
+	// the bytes used by the task's directory, as last measured
+	DiskUsed int64 `bson:"disk_used" json:"disk_used"`
+	// in bytes per second
+	DiskReadRate    int64 `bson:"disk_read_rate" json:"disk_read_rate"`
+	DiskWriteRate   int64 `bson:"disk_write_rate" json:"disk_write_rate"`
+	NetworkRecvRate int64 `bson:"network_recv_rate" json:"network_recv_rate"`
+	NetworkSendRate int64 `bson:"network_send_rate" json:"network_send_rate"`
+}
+
 // TaskEndResponse contains data sent by the API server to the agent - in
 // response to a request with TaskEndDetail.
 type TaskEndResponse struct {
@@ -35,5 +93,19 @@ type TaskEndResponse struct {
 	RunNext    bool   `json:"run_next,omitempty"`
 }
 
-// ExpansionVars is a map of expansion variables for a project.
-type ExpansionVars map[string]string
+// NextTaskResponse is sent by the API server to a pull-dispatch agent in
+// response to its request for work.
+type NextTaskResponse struct {
+	TaskId     string `json:"task_id,omitempty"`
+	TaskSecret string `json:"task_secret,omitempty"`
+	Message    string `json:"message,omitempty"`
+	ShouldExit bool   `json:"should_exit,omitempty"`
+}
+
+// ExpansionVars holds the variables of a project, to be used as expansions
+// by its tasks, along with the names of those that are private and must be
+// kept out of task logs.
+type ExpansionVars struct {
+	Vars        map[string]string `json:"vars"`
+	PrivateVars map[string]bool   `json:"private_vars"`
+}
@@ -0,0 +1,62 @@
+package apiserver
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"net/http"
+	"strconv"
+)
+
+// privateVarsProtocolVersion is the first agent protocol version in which
+// project variables are sent along with which of them are private.
+const privateVarsProtocolVersion = 2
+
+// agentProtocolVersion returns the protocol version in the given header.
+// Agents from before the protocol was versioned send none, and are taken to
+// speak version 0.
+func agentProtocolVersion(header string) (int, error) {
+	if header == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(header)
+}
+
+// agentProtocolCompatible returns whether the API server can work with an
+// agent that sent the given protocol version header.
+func agentProtocolCompatible(header string) bool {
+	version, err := agentProtocolVersion(header)
+	if err != nil {
+		return false
+	}
+	return version >= evergreen.MinAgentProtocolVersion && version <= evergreen.AgentProtocolVersion
+}
+
+// rejectIncompatibleAgent turns away an agent that speaks a protocol the API
+// server doesn't, with a status the agent exits on, and marks the agent's
+// host to be given a new one. It returns whether the agent was turned away.
+func rejectIncompatibleAgent(w http.ResponseWriter, r *http.Request, hostId string) bool {
+	header := r.Header.Get(evergreen.AgentProtocolHeader)
+	if agentProtocolCompatible(header) {
+		return false
+	}
+
+	evergreen.Logger.Logf(slogger.WARN, "Turning away agent on host '%v', which speaks protocol"+
+		" version '%v'", hostId, header)
+	if hostId != "" {
+		h, err := host.FindOne(host.ById(hostId))
+		if err == nil && h != nil {
+			err = h.SetNeedsNewAgent(true)
+		}
+		if err != nil {
+			evergreen.Logger.Logf(slogger.ERROR, "Error marking host %v as needing a new agent: %v",
+				hostId, err)
+		}
+	}
+
+	http.Error(w, fmt.Sprintf("agent protocol version '%v' is not supported: the API server"+
+		" supports versions %v to %v", header, evergreen.MinAgentProtocolVersion,
+		evergreen.AgentProtocolVersion), http.StatusUpgradeRequired)
+	return true
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/evergreen-ci/evergreen/db"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/event"
 	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/evergreen-ci/evergreen/model/user"
@@ -28,6 +29,7 @@ import (
 	"github.com/evergreen-ci/render"
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -44,6 +46,7 @@ type (
 	//  special types used as key types in the request context map to prevent key collisions.
 	userKey           int
 	taskKey           int
+	hostKey           int
 	projectContextKey int
 )
 
@@ -52,6 +55,7 @@ const (
 	// These are private custom types to avoid key collisions.
 	apiUserKey    userKey           = 0
 	apiTaskKey    taskKey           = 0
+	apiHostKey    hostKey           = 0
 	apiProjCtxKey projectContextKey = 0
 )
 
@@ -154,6 +158,16 @@ func MustHaveTask(r *http.Request) *model.Task {
 	return t
 }
 
+// MustHaveHost gets the host from an HTTP Request.
+// Panics if the host is not in request context.
+func MustHaveHost(r *http.Request) *host.Host {
+	h := GetHost(r)
+	if h == nil {
+		panic("no host attached to request")
+	}
+	return h
+}
+
 // GetListener creates a network listener on the given address.
 func GetListener(addr string) (net.Listener, error) {
 	return net.Listen("tcp", addr)
@@ -201,6 +215,10 @@ func (as *APIServer) checkTask(checkSecret bool, next http.HandlerFunc) http.Han
 			}
 		}
 
+		if rejectIncompatibleAgent(w, r, task.HostId) {
+			return
+		}
+
 		context.Set(r, apiTaskKey, task)
 		// also set the task in the context visible to plugins
 		plugin.SetTask(r, task)
@@ -208,6 +226,41 @@ func (as *APIServer) checkTask(checkSecret bool, next http.HandlerFunc) http.Han
 	}
 }
 
+// checkHost authenticates the agent of a pull-dispatch host using the
+// host id and secret sent in the request headers.
+func (as *APIServer) checkHost(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hostId := r.Header.Get(evergreen.HostHeader)
+		if hostId == "" {
+			as.LoggedError(w, r, http.StatusBadRequest, fmt.Errorf("missing host id"))
+			return
+		}
+		h, err := host.FindOne(host.ByIdOrTag(hostId))
+		if err != nil {
+			as.LoggedError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if h == nil {
+			as.LoggedError(w, r, http.StatusNotFound, fmt.Errorf("host not found"))
+			return
+		}
+
+		secret := r.Header.Get(evergreen.HostSecretHeader)
+		if h.Secret == "" || secret != h.Secret {
+			evergreen.Logger.Logf(slogger.ERROR, "Wrong secret sent for host %v", hostId)
+			http.Error(w, "wrong secret!", http.StatusConflict)
+			return
+		}
+
+		if rejectIncompatibleAgent(w, r, h.Id) {
+			return
+		}
+
+		context.Set(r, apiHostKey, h)
+		next(w, r)
+	}
+}
+
 func (as *APIServer) GetVersion(w http.ResponseWriter, r *http.Request) {
 	task := MustHaveTask(r)
 
@@ -390,7 +443,8 @@ func markHostRunningTaskFinished(h *host.Host, task *model.Task, newTaskId strin
 // occur for a number of reasons including:
 // a. The version of the agent running on the remote machine is stale
 // b. The host the agent is running on has been decommissioned
-// c. There is no currently queued dispatchable and activated task
+// c. The host or its distro is draining, or the host is retiring
+// d. There is no currently queued dispatchable and activated task
 // In any of these aforementioned cases, the agent in question should terminate
 // immediately and cease running any tasks on its host.
 func (as *APIServer) taskFinished(w http.ResponseWriter, task *model.Task, finishTime time.Time) {
@@ -444,8 +498,32 @@ func (as *APIServer) taskFinished(w http.ResponseWriter, task *model.Task, finis
 		return
 	}
 
-	// c. fetch the task's distro queue to dispatch the next pending task
-	nextTask, err := getNextDistroTask(task, host)
+	// c. a draining or retiring host gets no next task, just like through
+	// NextTask
+	draining, err := isDraining(host)
+	if err != nil {
+		markHostRunningTaskFinished(host, task, "")
+		evergreen.Logger.Logf(slogger.ERROR, "failed to check if host %v is draining: %v",
+			host.Id, err)
+		taskEndResponse.Message = err.Error()
+		as.WriteJSON(w, http.StatusInternalServerError, taskEndResponse)
+		return
+	}
+	if draining {
+		markHostRunningTaskFinished(host, task, "")
+		taskEndResponse.Message = fmt.Sprintf("Host %v is draining", host.Id)
+		as.WriteJSON(w, http.StatusOK, taskEndResponse)
+		return
+	}
+	if host.Retiring {
+		markHostRunningTaskFinished(host, task, "")
+		taskEndResponse.Message = fmt.Sprintf("Host %v is retiring", host.Id)
+		as.WriteJSON(w, http.StatusOK, taskEndResponse)
+		return
+	}
+
+	// d. fetch the task's distro queue to dispatch the next pending task
+	nextTask, err := getNextDistroTask(task.DistroId, host)
 	if err != nil {
 		markHostRunningTaskFinished(host, task, "")
 		evergreen.Logger.Logf(slogger.ERROR, err.Error())
@@ -468,19 +546,36 @@ func (as *APIServer) taskFinished(w http.ResponseWriter, task *model.Task, finis
 	as.WriteJSON(w, http.StatusOK, taskEndResponse)
 }
 
+// isDraining returns whether the host, or the distro it belongs to, is
+// draining. The host's copy of its distro may be out of date, so the distro
+// is looked up again.
+func isDraining(h *host.Host) (bool, error) {
+	if h.Draining {
+		return true, nil
+	}
+	d, err := distro.FindOne(distro.ById(h.Distro.Id))
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return d.Draining, nil
+}
+
 // getNextDistroTask fetches the next task to run for the given distro and marks
 // the task as dispatched in the given host's document
-func getNextDistroTask(currentTask *model.Task, host *host.Host) (
+func getNextDistroTask(distroId string, host *host.Host) (
 	nextTask *model.Task, err error) {
-	taskQueue, err := model.FindTaskQueueForDistro(currentTask.DistroId)
+	taskQueue, err := model.FindTaskQueueForDistro(distroId)
 	if err != nil {
-		return nil, fmt.Errorf("Error locating distro queue (%v) for task "+
-			"'%v': %v", currentTask.DistroId, currentTask.Id, err)
+		return nil, fmt.Errorf("Error locating distro queue (%v) for host "+
+			"'%v': %v", distroId, host.Id, err)
 	}
 
 	if taskQueue == nil {
-		return nil, fmt.Errorf("Nil task queue found for task '%v's distro "+
-			"queue - '%v'", currentTask.Id, currentTask.DistroId)
+		return nil, fmt.Errorf("Nil task queue found for host '%v's distro "+
+			"queue - '%v'", host.Id, distroId)
 	}
 
 	// dispatch the next task for this host
@@ -495,6 +590,108 @@ func getNextDistroTask(currentTask *model.Task, host *host.Host) (
 	return nextTask, nil
 }
 
+// NextTask hands the next task in its distro's queue to the agent of a
+// pull-dispatch host. If the host already has a task assigned, that task is
+// sent again so that a restarted agent can pick up where it left off.
+func (as *APIServer) NextTask(w http.ResponseWriter, r *http.Request) {
+	h := MustHaveHost(r)
+	response := &apimodels.NextTaskResponse{}
+
+	if h.Status == evergreen.HostDecommissioned || h.Status == evergreen.HostQuarantined ||
+		h.Status == evergreen.HostTerminated {
+		response.ShouldExit = true
+		response.Message = fmt.Sprintf("Host %v is in state '%v'. Agent will terminate",
+			h.Id, h.Status)
+		as.WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
+	// hosts that are still being set up, or are on their way out, get no
+	// tasks until they're running
+	if h.Status != evergreen.HostRunning {
+		response.Message = fmt.Sprintf("Host %v is in state '%v', not running", h.Id, h.Status)
+		as.WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
+	if !h.Distro.UsesPullDispatch() {
+		response.ShouldExit = true
+		response.Message = fmt.Sprintf("Distro %v does not use pull dispatch. Agent will terminate",
+			h.Distro.Id)
+		as.WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
+	if !getGlobalLock(r.RemoteAddr, h.Id) {
+		as.LoggedError(w, r, http.StatusInternalServerError, ErrLockTimeout)
+		return
+	}
+	defer releaseGlobalLock(r.RemoteAddr, h.Id)
+
+	if h.RunningTask != "" {
+		runningTask, err := model.FindTask(h.RunningTask)
+		if err != nil {
+			as.LoggedError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if runningTask != nil && runningTask.Status == evergreen.TaskDispatched {
+			response.TaskId = runningTask.Id
+			response.TaskSecret = runningTask.Secret
+			response.Message = "Proceed with assigned task"
+			as.WriteJSON(w, http.StatusOK, response)
+			return
+		}
+		if err = h.ClearRunningTask(); err != nil {
+			as.LoggedError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	draining, err := isDraining(h)
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if draining {
+		response.Message = fmt.Sprintf("Host %v is draining", h.Id)
+		as.WriteJSON(w, http.StatusOK, response)
+		return
+	}
+	if h.Retiring {
+		response.Message = fmt.Sprintf("Host %v is retiring", h.Id)
+		as.WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
+	nextTask, err := getNextDistroTask(h.Distro.Id, h)
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if nextTask == nil {
+		response.Message = "No next task on queue"
+		as.WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
+	taskRunnerInstance := taskrunner.NewTaskRunner(&as.Settings)
+	agentRevision, err := taskRunnerInstance.HostGateway.GetAgentRevision()
+	if err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "failed to get agent revision: %v", err)
+	}
+	if err = h.SetRunningTask(nextTask.Id, agentRevision, time.Now()); err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Dispatched task %v to pull-dispatch host %v",
+		nextTask.Id, h.Id)
+	response.TaskId = nextTask.Id
+	response.TaskSecret = nextTask.Secret
+	response.Message = "Proceed with next task"
+	as.WriteJSON(w, http.StatusOK, response)
+}
+
 // AttachTestLog is the API Server hook for getting
 // the test logs and storing them in the test_logs collection.
 func (as *APIServer) AttachTestLog(w http.ResponseWriter, r *http.Request) {
@@ -547,11 +744,24 @@ func (as *APIServer) FetchProjectVars(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if projectVars == nil {
-		as.WriteJSON(w, http.StatusOK, apimodels.ExpansionVars{})
+		projectVars = &model.ProjectVars{}
+	}
+
+	// older agents only understand a plain map of variables
+	version, _ := agentProtocolVersion(r.Header.Get(evergreen.AgentProtocolHeader))
+	if version < privateVarsProtocolVersion {
+		vars := projectVars.Vars
+		if vars == nil {
+			vars = map[string]string{}
+		}
+		as.WriteJSON(w, http.StatusOK, vars)
 		return
 	}
 
-	as.WriteJSON(w, http.StatusOK, projectVars.Vars)
+	as.WriteJSON(w, http.StatusOK, apimodels.ExpansionVars{
+		Vars:        projectVars.Vars,
+		PrivateVars: projectVars.PrivateVars,
+	})
 }
 
 // AttachFiles updates file mappings for a task or build
@@ -604,6 +814,43 @@ func (as *APIServer) AppendTaskLog(w http.ResponseWriter, r *http.Request) {
 	as.WriteJSON(w, http.StatusOK, "Logs added")
 }
 
+// AppendCommandRecord stores the agent's record of one of the task's commands.
+func (as *APIServer) AppendCommandRecord(w http.ResponseWriter, r *http.Request) {
+	task := MustHaveTask(r)
+	record := apimodels.CommandRecord{}
+
+	if err := util.ReadJSONInto(r.Body, &record); err != nil {
+		http.Error(w, "unable to read command record from request", http.StatusBadRequest)
+		return
+	}
+
+	if err := task.AddCommandRecord(record); err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	as.WriteJSON(w, http.StatusOK, "Command recorded")
+}
+
+// AppendResourceStats stores the resource samples the agent took while
+// running the task.
+func (as *APIServer) AppendResourceStats(w http.ResponseWriter, r *http.Request) {
+	task := MustHaveTask(r)
+	samples := []apimodels.ResourceSample{}
+
+	if err := util.ReadJSONInto(r.Body, &samples); err != nil {
+		http.Error(w, "unable to read resource samples from request", http.StatusBadRequest)
+		return
+	}
+
+	if err := model.AppendResourceSamples(task.Id, task.Execution, samples); err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	as.WriteJSON(w, http.StatusOK, "Resource stats added")
+}
+
 // GetPatch loads the task's patch data from the database and sends
 // it to the requester.
 func (as *APIServer) GetPatch(w http.ResponseWriter, r *http.Request) {
@@ -678,6 +925,14 @@ func GetUser(r *http.Request) *user.DBUser {
 	return nil
 }
 
+// GetHost loads the host attached to a request.
+func GetHost(r *http.Request) *host.Host {
+	if rv := context.Get(r, apiHostKey); rv != nil {
+		return rv.(*host.Host)
+	}
+	return nil
+}
+
 // GetTask loads the task attached to a request.
 func GetTask(r *http.Request) *model.Task {
 	if rv := context.Get(r, apiTaskKey); rv != nil {
@@ -723,7 +978,7 @@ func getHostFromRequest(r *http.Request) (*host.Host, error) {
 		return nil, fmt.Errorf("no host tag supplied")
 	}
 	// find the host
-	host, err := host.FindOne(host.ById(tag))
+	host, err := host.FindOne(host.ByIdOrTag(tag))
 	if host == nil {
 		return nil, fmt.Errorf("no host with tag: %v", tag)
 	}
@@ -741,6 +996,18 @@ func (as *APIServer) hostReady(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// hosts provisioned by userdata report on their own setup, so make
+	// sure the report really comes from the host
+	userDataHost := hostObj.Distro.UsesUserDataProvisioning()
+	if userDataHost {
+		secret := r.Header.Get(evergreen.HostSecretHeader)
+		if hostObj.Secret == "" || secret != hostObj.Secret {
+			evergreen.Logger.Logf(slogger.ERROR, "Wrong secret sent for host %v", hostObj.Id)
+			http.Error(w, "wrong secret!", http.StatusConflict)
+			return
+		}
+	}
+
 	// if the host failed
 	setupSuccess := mux.Vars(r)["status"]
 	if setupSuccess == evergreen.HostStatusFailed {
@@ -764,7 +1031,13 @@ func (as *APIServer) hostReady(w http.ResponseWriter, r *http.Request) {
 
 		event.LogProvisionFailed(hostObj.Id, string(setupLog))
 
-		err = hostObj.SetUnprovisioned()
+		// userdata hosts are never marked as initializing, since
+		// hostinit does not run their setup
+		if userDataHost {
+			err = hostObj.SetStatus(evergreen.HostProvisionFailed)
+		} else {
+			err = hostObj.SetUnprovisioned()
+		}
 		if err != nil {
 			as.LoggedError(w, r, http.StatusInternalServerError, err)
 			return
@@ -774,6 +1047,15 @@ func (as *APIServer) hostReady(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// a report that arrives after the userdata timeout gave up on the host
+	// mustn't bring it back
+	if userDataHost && hostObj.Status != evergreen.HostUninitialized {
+		evergreen.Logger.Logf(slogger.WARN, "Ignoring setup report from host %v "+
+			"with status %v", hostObj.Id, hostObj.Status)
+		http.Error(w, "host is no longer being set up", http.StatusConflict)
+		return
+	}
+
 	cloudManager, err := providers.GetCloudManager(hostObj.Provider, &as.Settings)
 	if err != nil {
 		as.LoggedError(w, r, http.StatusInternalServerError, err)
@@ -793,11 +1075,32 @@ func (as *APIServer) hostReady(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// mark host as provisioned
-	if err := hostObj.MarkAsProvisioned(); err != nil {
+	// hostinit never saw userdata hosts come up, so do its bookkeeping here
+	if userDataHost {
+		if hostObj.Host == "" {
+			if err := hostObj.SetDNSName(dns); err != nil {
+				as.LoggedError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		if err := cloudManager.OnUp(hostObj); err != nil {
+			evergreen.Logger.Logf(slogger.WARN, "OnUp callback failed for host '%v': '%v'", hostObj.Id, err)
+		}
+	}
+
+	// mark host as provisioned, unless its setup was given up on (e.g. timed
+	// out) while it ran
+	marked, err := hostObj.MarkAsProvisionedIfCurrent()
+	if err != nil {
 		as.LoggedError(w, r, http.StatusInternalServerError, err)
 		return
 	}
+	if !marked {
+		evergreen.Logger.Logf(slogger.WARN, "Not marking host %v as provisioned: "+
+			"its setup is no longer current", hostObj.Id)
+		http.Error(w, "host setup is no longer current", http.StatusConflict)
+		return
+	}
 
 	evergreen.Logger.Logf(slogger.INFO, "Successfully marked host “%v” with dns “%v” as provisioned", hostObj.Id, dns)
 }
@@ -898,6 +1201,18 @@ func requireUser(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// requireSuperUser wraps a handler so that it can only be used by super
+// users.
+func (as *APIServer) requireSuperUser(next http.HandlerFunc) http.HandlerFunc {
+	return requireUser(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.IsSuperUser(as.Settings.SuperUsers, GetUser(r).Id) {
+			http.Error(w, "not authorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
 // Returns information about available updates for client binaries.
 // Replies 404 if this data is not configured.
 func (as *APIServer) getUpdate(w http.ResponseWriter, r *http.Request) {
@@ -943,6 +1258,10 @@ func (as *APIServer) Handler() (http.Handler, error) {
 	spawn.HandleFunc("/{instance_id:[\\w_\\-\\@]+}/", requireUser(as.modifyHost)).Methods("POST")
 	spawn.HandleFunc("/ready/{instance_id:[\\w_\\-\\@]+}/{status}", requireUser(as.spawnHostReady)).Methods("POST")
 
+	// Drain mode for hosts and distros
+	apiRootOld.HandleFunc("/hosts/{host_id}/drain", as.requireSuperUser(as.drainHost)).Methods("POST")
+	apiRootOld.HandleFunc("/distros/{distro_id}/drain", as.requireSuperUser(as.drainDistro)).Methods("POST")
+
 	runtimes := apiRootOld.PathPrefix("/runtimes/").Subrouter()
 	runtimes.HandleFunc("/", as.listRuntimes).Methods("GET")
 	runtimes.HandleFunc("/timeout/{seconds:\\d*}", as.lateRuntimes).Methods("GET")
@@ -951,6 +1270,10 @@ func (as *APIServer) Handler() (http.Handler, error) {
 	host := r.PathPrefix("/host/{tag:[\\w_\\-\\@]+}/").Subrouter()
 	host.HandleFunc("/ready/{status}", as.hostReady).Methods("POST")
 
+	// Pull-dispatch agent routes
+	agentRouter := r.PathPrefix("/agent").Subrouter()
+	agentRouter.HandleFunc("/next_task", as.checkHost(as.NextTask)).Methods("GET")
+
 	// Spawnhost routes - creating new hosts, listing existing hosts, listing distros
 	spawns := apiRootOld.PathPrefix("/spawns/").Subrouter()
 	spawns.HandleFunc("/", requireUser(as.requestHost)).Methods("PUT")
@@ -962,6 +1285,8 @@ func (as *APIServer) Handler() (http.Handler, error) {
 	taskRouter.HandleFunc("/end2", as.checkTask(true, as.EndTask)).Methods("POST")
 	taskRouter.HandleFunc("/end", as.checkTask(true, as.EndTask)).Methods("POST")
 	taskRouter.HandleFunc("/log", as.checkTask(true, as.AppendTaskLog)).Methods("POST")
+	taskRouter.HandleFunc("/command", as.checkTask(true, as.AppendCommandRecord)).Methods("POST")
+	taskRouter.HandleFunc("/resource_stats", as.checkTask(true, as.AppendResourceStats)).Methods("POST")
 	taskRouter.HandleFunc("/heartbeat", as.checkTask(true, as.Heartbeat)).Methods("POST")
 	taskRouter.HandleFunc("/results", as.checkTask(true, as.AttachResults)).Methods("POST")
 	taskRouter.HandleFunc("/test_logs", as.checkTask(true, as.AttachTestLog)).Methods("POST")
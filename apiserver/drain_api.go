@@ -0,0 +1,99 @@
+package apiserver
+
+import (
+	"fmt"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/event"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/util"
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
+	"net/http"
+)
+
+// drainRequest is the body of a request to put a host or distro into or
+// take it out of drain mode.
+type drainRequest struct {
+	Draining bool `json:"draining"`
+}
+
+// drainHost puts a host into or takes it out of drain mode. No new tasks
+// are assigned to a draining host.
+func (as *APIServer) drainHost(w http.ResponseWriter, r *http.Request) {
+	user := MustHaveUser(r)
+	hostId := mux.Vars(r)["host_id"]
+
+	req := drainRequest{}
+	if err := util.ReadJSONInto(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h, err := host.FindOne(host.ById(hostId))
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if h == nil {
+		http.Error(w, fmt.Sprintf("host '%v' not found", hostId), http.StatusNotFound)
+		return
+	}
+
+	if err = h.SetDraining(req.Draining, user.Username()); err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError,
+			fmt.Errorf("error updating host %v: %v", h.Id, err))
+		return
+	}
+	as.WriteJSON(w, http.StatusOK, h)
+}
+
+// drainDistro puts a distro into or takes it out of drain mode. No new
+// tasks are assigned to the hosts of a draining distro, and no new hosts
+// are spawned for it.
+func (as *APIServer) drainDistro(w http.ResponseWriter, r *http.Request) {
+	user := MustHaveUser(r)
+	distroId := mux.Vars(r)["distro_id"]
+
+	req := drainRequest{}
+	if err := util.ReadJSONInto(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := distro.FindOne(distro.ById(distroId))
+	if err == mgo.ErrNotFound {
+		http.Error(w, fmt.Sprintf("distro '%v' not found", distroId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	// distros created before revisions were kept need their current state
+	// recorded before it is changed
+	latest, err := distro.FindLatestRevision(d.Id)
+	if err == nil && latest == nil {
+		_, err = distro.SaveRevision(*d, "", 0)
+	}
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError,
+			fmt.Errorf("error saving revision of distro %v: %v", d.Id, err))
+		return
+	}
+
+	d.Draining = req.Draining
+	if err = d.Update(); err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError,
+			fmt.Errorf("error updating distro %v: %v", d.Id, err))
+		return
+	}
+	event.LogDistroModified(d.Id, user.Username(), d)
+	if _, err = distro.SaveRevision(*d, user.Username(), 0); err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError,
+			fmt.Errorf("error saving revision of distro %v: %v", d.Id, err))
+		return
+	}
+
+	as.WriteJSON(w, http.StatusOK, d)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/evergreen-ci/evergreen/apiserver"
 	"github.com/evergreen-ci/evergreen/db"
 	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/plugin/external"
 	"github.com/evergreen-ci/evergreen/util"
 	"gopkg.in/tylerb/graceful.v1"
 	"net"
@@ -39,6 +40,11 @@ func main() {
 
 	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(settings))
 
+	// make the commands of external plugins known to project validation
+	if err := external.Publish(settings.ExternalPluginsDir); err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Error publishing external plugins: %v", err)
+	}
+
 	tlsConfig, err := util.MakeTlsConfig(settings.Expansions["api_httpscert"], settings.Api.HttpsKey)
 	if err != nil {
 		evergreen.Logger.Logf(slogger.ERROR, "Failed to make TLS config: %v", err)
@@ -15,6 +15,8 @@ import (
 	"github.com/gorilla/mux"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type spawnRequest struct {
@@ -30,6 +32,10 @@ type spawnResponse struct {
 	HostInfo host.Host   `json:"host_info,omitempty"`
 	Distros  []string    `json:"distros,omitempty"`
 
+	// the status of the request that spawned HostInfo, as one of the
+	// evergreen.SpawnRequest* constants
+	Status string `json:"status,omitempty"`
+
 	// empty if the request succeeded
 	ErrorMessage string `json:"error_message,omitempty"`
 }
@@ -53,6 +59,7 @@ func (as *APIServer) requestHost(w http.ResponseWriter, r *http.Request) {
 		Distro    string `json:"distro"`
 		PublicKey string `json:"public_key"`
 		UserData  string `json:"userdata"`
+		TaskId    string `json:"task_id"`
 	}{}
 	err := util.ReadJSONInto(r.Body, &hostRequest)
 	if err != nil {
@@ -60,7 +67,7 @@ func (as *APIServer) requestHost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if hostRequest.Distro == "" {
+	if hostRequest.Distro == "" && hostRequest.TaskId == "" {
 		http.Error(w, "distro may not be blank", http.StatusBadRequest)
 		return
 	}
@@ -74,6 +81,7 @@ func (as *APIServer) requestHost(w http.ResponseWriter, r *http.Request) {
 		UserName:  user.Id,
 		PublicKey: hostRequest.PublicKey,
 		UserData:  hostRequest.UserData,
+		TaskId:    hostRequest.TaskId,
 	}
 
 	spawner := spawn.New(&as.Settings)
@@ -87,28 +95,22 @@ func (as *APIServer) requestHost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Start a background goroutine that handles host creation/setup.
-	go func() {
-		host, err := spawner.CreateHost(opts)
-		if err != nil {
-			evergreen.Logger.Logf(slogger.ERROR, err.Error())
-			mailErr := notify.TrySendNotificationToUser(opts.UserName, "Spawning failed", err.Error(),
-				notify.ConstructMailer(as.Settings.Notify))
-			if mailErr != nil {
-				evergreen.Logger.Logf(slogger.ERROR, "Failed to send notification: %v", mailErr)
-			}
-			if host != nil { // a host was inserted - we need to clean it up
-				dErr := host.SetDecommissioned()
-				if err != nil {
-					evergreen.Logger.Logf(slogger.ERROR, "Failed to set host %v decommissioned: %v", host.Id, dErr)
-				}
+	// the host is created before replying, so that the client can follow
+	// the very host it asked for; setting it up still happens in the
+	// background
+	host, err := spawner.CreateHost(opts)
+	if err != nil {
+		if host != nil { // a host was inserted - we need to clean it up
+			dErr := host.SetDecommissioned()
+			if dErr != nil {
+				evergreen.Logger.Logf(slogger.ERROR, "Failed to set host %v decommissioned: %v", host.Id, dErr)
 			}
-			return
 		}
+		as.LoggedError(w, r, http.StatusInternalServerError, fmt.Errorf("Spawning failed: %v", err))
+		return
+	}
 
-	}()
-
-	as.WriteJSON(w, http.StatusOK, "")
+	as.WriteJSON(w, http.StatusOK, spawnResponse{HostInfo: *host})
 }
 
 func (as *APIServer) spawnHostReady(w http.ResponseWriter, r *http.Request) {
@@ -190,7 +192,7 @@ func (as *APIServer) hostInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	as.WriteJSON(w, http.StatusOK, spawnResponse{HostInfo: *host})
+	as.WriteJSON(w, http.StatusOK, spawnResponse{HostInfo: *host, Status: spawn.RequestStatus(host)})
 }
 
 // returns info on all of the hosts spawned by a user
@@ -224,7 +226,7 @@ func (as *APIServer) modifyHost(w http.ResponseWriter, r *http.Request) {
 
 	user := GetUser(r)
 	if user == nil || user.Id != host.StartedBy {
-		message := fmt.Sprintf("Only %v is authorized to terminate this host", host.StartedBy)
+		message := fmt.Sprintf("Only %v is authorized to modify this host", host.StartedBy)
 		http.Error(w, message, http.StatusUnauthorized)
 		return
 	}
@@ -237,6 +239,7 @@ func (as *APIServer) modifyHost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		host.TerminationReason = evergreen.TerminationReasonUser
 		cloudHost, err := providers.GetCloudHost(host, &as.Settings)
 		if err != nil {
 			as.LoggedError(w, r, http.StatusInternalServerError, err)
@@ -247,6 +250,23 @@ func (as *APIServer) modifyHost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		as.WriteJSON(w, http.StatusOK, spawnResponse{HostInfo: *host})
+	case "extend":
+		addHours, err := strconv.Atoi(r.FormValue("add_hours"))
+		if err != nil {
+			http.Error(w, "bad hours param", http.StatusBadRequest)
+			return
+		}
+		spawner := spawn.New(&as.Settings)
+		err = spawner.ExtendExpiration(host, time.Duration(addHours)*time.Hour)
+		if err != nil {
+			errCode := http.StatusBadRequest
+			if _, ok := err.(spawn.BadOptionsErr); !ok {
+				errCode = http.StatusInternalServerError
+			}
+			as.LoggedError(w, r, errCode, err)
+			return
+		}
+		as.WriteJSON(w, http.StatusOK, spawnResponse{HostInfo: *host})
 	default:
 		http.Error(w, fmt.Sprintf("Unrecognized action %v", hostAction), http.StatusBadRequest)
 	}
@@ -3,9 +3,16 @@ package auth
 import (
 	"fmt"
 	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/util"
 	"net/http"
 )
 
+// IsSuperUser returns true if the user with the given id has super user
+// privileges. When no super users are configured, every user has them.
+func IsSuperUser(superUsers []string, userId string) bool {
+	return len(superUsers) == 0 || util.SliceContains(superUsers, userId)
+}
+
 //LoadUserManager is used to check the configuration for authentication and create a UserManager depending on what type of authentication (Crowd or Naive) is used.
 func LoadUserManager(authConfig evergreen.AuthConfig) (UserManager, error) {
 	var manager UserManager
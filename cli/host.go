@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/util"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// how often to check on a host being waited for
+	spawnPollInterval = 10 * time.Second
+)
+
+// This is the template used to render a spawn host's details in a human-readable output format.
+var hostDisplayTemplate = template.Must(template.New("host").Parse(`
+         ID : {{.Host.Id}}
+     Distro : {{.Host.Distro.Id}}
+     Status : {{.Host.Status}}
+   DNS Name : {{if .Host.Host}}{{.Host.Host}}{{else}}<not yet assigned>{{end}}
+       User : {{.Host.User}}
+    Expires : {{.Host.ExpirationTime.Format "Mon Jan 2 15:04 MST"}} (in {{.ExpiresIn}})
+`))
+
+// HostCommand groups the commands for managing spawn hosts.
+type HostCommand struct{}
+
+// HostCreateCommand is used to spawn a new host.
+type HostCreateCommand struct {
+	GlobalOpts  Options `no-flag:"true"`
+	Distro      string  `short:"d" long:"distro" description:"distro to spawn the host from"`
+	Key         string  `short:"k" long:"key" description:"path to the public key to log in with (defaults to ~/.ssh/id_rsa.pub)"`
+	UserData    string  `long:"userdata" description:"path to a file of user data, for distros that take it"`
+	TaskId      string  `short:"t" long:"task" description:"id of a task to load onto the host for debugging; uses the task's distro if none is given"`
+	Wait        bool    `short:"w" long:"wait" description:"wait until the host is ready to use"`
+	WaitTimeout int     `long:"wait-timeout" default:"60" description:"minutes to wait for the host to be ready before giving up"`
+}
+
+// HostListCommand is used to list the user's spawn hosts.
+type HostListCommand struct {
+	GlobalOpts Options `no-flag:"true"`
+	Distros    bool    `long:"distros" description:"list the distros hosts can be spawned from instead"`
+}
+
+// HostExtendCommand is used to push back a spawn host's expiration.
+type HostExtendCommand struct {
+	GlobalOpts Options `no-flag:"true"`
+	HostId     string  `short:"i" description:"id of the host to extend" required:"true"`
+	Hours      int     `long:"hours" description:"number of hours to extend the host's expiration by" required:"true"`
+}
+
+// HostTerminateCommand is used to terminate a spawn host.
+type HostTerminateCommand struct {
+	GlobalOpts  Options `no-flag:"true"`
+	HostId      string  `short:"i" description:"id of the host to terminate" required:"true"`
+	SkipConfirm bool    `short:"y" long:"yes" description:"skip confirmation text"`
+}
+
+// HostSSHCommand is used to log in to a spawn host. Any arguments are
+// passed on to ssh.
+type HostSSHCommand struct {
+	GlobalOpts Options `no-flag:"true"`
+	HostId     string  `short:"i" description:"id of the host to log in to" required:"true"`
+	Key        string  `short:"k" long:"key" description:"path to the private key to log in with"`
+}
+
+func (hcc *HostCreateCommand) Execute(args []string) error {
+	if hcc.Distro == "" && hcc.TaskId == "" {
+		return fmt.Errorf("Need to specify a distro or a task.")
+	}
+	ac, _, err := getAPIClient(hcc.GlobalOpts)
+	if err != nil {
+		return err
+	}
+	notifyUserUpdate(ac)
+
+	keyPath := hcc.Key
+	if keyPath == "" {
+		u, err := user.Current()
+		if err != nil {
+			return err
+		}
+		keyPath = filepath.Join(u.HomeDir, ".ssh", "id_rsa.pub")
+	}
+	publicKey, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("Error reading public key: %v", err)
+	}
+
+	request := spawnRequest{
+		Distro:    hcc.Distro,
+		PublicKey: strings.TrimSpace(string(publicKey)),
+		TaskId:    hcc.TaskId,
+	}
+	if hcc.UserData != "" {
+		userData, err := ioutil.ReadFile(hcc.UserData)
+		if err != nil {
+			return fmt.Errorf("Error reading user data: %v", err)
+		}
+		request.UserData = string(userData)
+	}
+
+	start := time.Now()
+	h, err := ac.SpawnHost(request)
+	if err != nil {
+		return err
+	}
+	if !hcc.Wait {
+		fmt.Printf("Host %v created. Run 'evergreen host list' to check on it.\n", h.Id)
+		return nil
+	}
+
+	fmt.Printf("Host %v created. Waiting for it to be ready...\n", h.Id)
+	h, err = waitForSpawnHost(ac, h.Id, start.Add(time.Duration(hcc.WaitTimeout)*time.Minute))
+	if err != nil {
+		return err
+	}
+	return printHost(h)
+}
+
+// waitForSpawnHost waits until the spawn request that created the host has
+// finished, and returns the host if it is ready to use. It gives up at the
+// deadline.
+func waitForSpawnHost(ac *APIClient, hostId string, deadline time.Time) (*host.Host, error) {
+	for {
+		h, status, err := ac.GetSpawnHost(hostId)
+		if err != nil {
+			return nil, err
+		}
+		switch status {
+		case evergreen.SpawnRequestReady:
+			return h, nil
+		case evergreen.SpawnRequestInit:
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("Host %v was not ready in time (status: %v). Run "+
+					"'evergreen host list' to keep checking on it.", hostId, h.Status)
+			}
+			time.Sleep(spawnPollInterval)
+		default:
+			return nil, fmt.Errorf("Host %v is %v (status: %v).", hostId, status, h.Status)
+		}
+	}
+}
+
+func (hlc *HostListCommand) Execute(args []string) error {
+	ac, _, err := getAPIClient(hlc.GlobalOpts)
+	if err != nil {
+		return err
+	}
+	notifyUserUpdate(ac)
+
+	if hlc.Distros {
+		distros, err := ac.ListSpawnableDistros()
+		if err != nil {
+			return err
+		}
+		for _, d := range distros {
+			fmt.Println(d)
+		}
+		return nil
+	}
+
+	hosts, err := ac.ListSpawnHosts()
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		fmt.Println("You have no spawn hosts.")
+	}
+	for i := range hosts {
+		if err = printHost(&hosts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (hec *HostExtendCommand) Execute(args []string) error {
+	ac, _, err := getAPIClient(hec.GlobalOpts)
+	if err != nil {
+		return err
+	}
+	notifyUserUpdate(ac)
+
+	h, err := ac.ExtendSpawnHost(hec.HostId, hec.Hours)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Host %v will now expire at %v.\n", h.Id,
+		h.ExpirationTime.Format("Mon Jan 2 15:04 MST"))
+	return nil
+}
+
+func (htc *HostTerminateCommand) Execute(args []string) error {
+	ac, _, err := getAPIClient(htc.GlobalOpts)
+	if err != nil {
+		return err
+	}
+	notifyUserUpdate(ac)
+
+	if !htc.SkipConfirm && !confirm(fmt.Sprintf("Terminate host %v? (y/n):", htc.HostId), false) {
+		return nil
+	}
+	if err = ac.TerminateSpawnHost(htc.HostId); err != nil {
+		return err
+	}
+	fmt.Println("Host terminated.")
+	return nil
+}
+
+func (hsc *HostSSHCommand) Execute(args []string) error {
+	ac, _, err := getAPIClient(hsc.GlobalOpts)
+	if err != nil {
+		return err
+	}
+
+	h, status, err := ac.GetSpawnHost(hsc.HostId)
+	if err != nil {
+		return err
+	}
+	if status != evergreen.SpawnRequestReady {
+		return fmt.Errorf("Host %v is not ready to log in to (status: %v).", h.Id, h.Status)
+	}
+
+	sshArgs, err := sshCommandArgs(h, hsc.Key, args)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("ssh", sshArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// sshCommandArgs returns the arguments to ssh for logging in to the host
+// with the given private key, if any, followed by any extra arguments.
+func sshCommandArgs(h *host.Host, keyPath string, extra []string) ([]string, error) {
+	info, err := util.ParseSSHInfo(h.Host)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"-p", info.Port}
+	if keyPath != "" {
+		args = append(args, "-i", keyPath)
+	}
+	args = append(args, fmt.Sprintf("%v@%v", h.User, info.Hostname))
+	return append(args, extra...), nil
+}
+
+// printHost writes a human-readable summary of the host to the terminal.
+func printHost(h *host.Host) error {
+	var out bytes.Buffer
+	expiresIn := h.ExpirationTime.Sub(time.Now()) / time.Minute * time.Minute
+	err := hostDisplayTemplate.Execute(&out, struct {
+		Host      *host.Host
+		ExpiresIn time.Duration
+	}{h, expiresIn})
+	if err != nil {
+		return err
+	}
+	fmt.Println(out.String())
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/evergreen-ci/evergreen/model/patch"
 	"github.com/evergreen-ci/evergreen/util"
 	"github.com/evergreen-ci/evergreen/validator"
@@ -294,3 +295,154 @@ func (ac *APIClient) CheckUpdates() (*evergreen.ClientConfig, error) {
 	}
 	return &reply, nil
 }
+
+// authParams returns url values holding the user's authentication token.
+func (ac *APIClient) authParams() (url.Values, error) {
+	authToken, err := generateTokenParam(ac.User, ac.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	data := url.Values{}
+	data.Set("id_token", authToken)
+	return data, nil
+}
+
+// spawnReply is the response of the API server's spawn host endpoints.
+type spawnReply struct {
+	Hosts    []host.Host `json:"hosts"`
+	HostInfo host.Host   `json:"host_info"`
+	Distros  []string    `json:"distros"`
+	Status   string      `json:"status"`
+}
+
+// spawnRequest is the set of options a spawn host is requested with.
+type spawnRequest struct {
+	Distro    string `json:"distro"`
+	PublicKey string `json:"public_key"`
+	UserData  string `json:"userdata"`
+	TaskId    string `json:"task_id"`
+}
+
+// SpawnHost asks the API server to spawn a host for the user, and returns
+// the host it created. The host is set up in the background, so it isn't
+// ready to use yet.
+func (ac *APIClient) SpawnHost(request spawnRequest) (*host.Host, error) {
+	data, err := ac.authParams()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ac.put(fmt.Sprintf("spawns/?%v", data.Encode()), bytes.NewBuffer(body), false)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewAPIError(resp)
+	}
+	reply := spawnReply{}
+	if err := util.ReadJSONInto(resp.Body, &reply); err != nil {
+		return nil, err
+	}
+	return &reply.HostInfo, nil
+}
+
+// ListSpawnHosts returns the user's unterminated spawn hosts.
+func (ac *APIClient) ListSpawnHosts() ([]host.Host, error) {
+	data, err := ac.authParams()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ac.get(fmt.Sprintf("spawns/%v/?%v", url.QueryEscape(ac.User), data.Encode()), nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewAPIError(resp)
+	}
+	reply := spawnReply{}
+	if err := util.ReadJSONInto(resp.Body, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Hosts, nil
+}
+
+// ListSpawnableDistros returns the distros the user may spawn hosts of.
+func (ac *APIClient) ListSpawnableDistros() ([]string, error) {
+	data, err := ac.authParams()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ac.get(fmt.Sprintf("spawns/distros/list/?%v", data.Encode()), nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewAPIError(resp)
+	}
+	reply := spawnReply{}
+	if err := util.ReadJSONInto(resp.Body, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Distros, nil
+}
+
+// GetSpawnHost returns the spawn host with the given id, along with the
+// status of the request that spawned it.
+func (ac *APIClient) GetSpawnHost(hostId string) (*host.Host, string, error) {
+	data, err := ac.authParams()
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := ac.get(fmt.Sprintf("spawn/%v/?%v", url.QueryEscape(hostId), data.Encode()), nil, true)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", NewAPIError(resp)
+	}
+	reply := spawnReply{}
+	if err := util.ReadJSONInto(resp.Body, &reply); err != nil {
+		return nil, "", err
+	}
+	return &reply.HostInfo, reply.Status, nil
+}
+
+// modifySpawnHost makes a request to the API server to perform the action
+// on the spawn host, and returns the host as it is afterwards.
+func (ac *APIClient) modifySpawnHost(hostId, action string, params url.Values) (*host.Host, error) {
+	data, err := ac.authParams()
+	if err != nil {
+		return nil, err
+	}
+	data.Set("action", action)
+	for key := range params {
+		data.Set(key, params.Get(key))
+	}
+	resp, err := ac.post(fmt.Sprintf("spawn/%v/", url.QueryEscape(hostId)), bytes.NewBufferString(data.Encode()), true)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewAPIError(resp)
+	}
+	reply := spawnReply{}
+	if err := util.ReadJSONInto(resp.Body, &reply); err != nil {
+		return nil, err
+	}
+	return &reply.HostInfo, nil
+}
+
+// ExtendSpawnHost pushes back the spawn host's expiration by the given
+// number of hours.
+func (ac *APIClient) ExtendSpawnHost(hostId string, hours int) (*host.Host, error) {
+	return ac.modifySpawnHost(hostId, "extend", url.Values{"add_hours": {fmt.Sprintf("%v", hours)}})
+}
+
+// TerminateSpawnHost terminates the spawn host.
+func (ac *APIClient) TerminateSpawnHost(hostId string) error {
+	_, err := ac.modifySpawnHost(hostId, "terminate", nil)
+	return err
+}
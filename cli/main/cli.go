@@ -20,6 +20,13 @@ func main() {
 	parser.AddCommand("finalize-patch", "finalize an existing patch", "", &cli.FinalizePatchCommand{GlobalOpts: opts})
 	parser.AddCommand("list-projects", "list all projects", "", &cli.ListProjectsCommand{GlobalOpts: opts})
 	parser.AddCommand("validate", "validate a config file", "", &cli.ValidateCommand{GlobalOpts: opts})
+	parser.AddCommand("run-task", "run a project's task on this machine", "", &cli.RunTaskCommand{})
+	host, _ := parser.AddCommand("host", "manage spawn hosts", "", &cli.HostCommand{})
+	host.AddCommand("create", "spawn a new host", "", &cli.HostCreateCommand{GlobalOpts: opts})
+	host.AddCommand("list", "show your spawn hosts", "", &cli.HostListCommand{GlobalOpts: opts})
+	host.AddCommand("extend", "extend the expiration of a spawn host", "", &cli.HostExtendCommand{GlobalOpts: opts})
+	host.AddCommand("terminate", "terminate a spawn host", "", &cli.HostTerminateCommand{GlobalOpts: opts})
+	host.AddCommand("ssh", "log in to a spawn host", "", &cli.HostSSHCommand{GlobalOpts: opts})
 	_, err := parser.Parse()
 	if err != nil {
 		os.Exit(1)
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/agent"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the directory, under the one the task runs in, that a local task's
+// output is written to by default
+const defaultLocalOutputDir = "evergreen_output"
+
+// RunTaskCommand runs one of a project's tasks on the local machine, with
+// no API server involved.
+type RunTaskCommand struct {
+	ProjectFile string   `short:"p" long:"project" description:"path to the project's config file" required:"true"`
+	Variant     string   `short:"v" long:"variant" description:"build variant to run the task as" required:"true"`
+	Task        string   `short:"t" long:"task" description:"name of the task to run" required:"true"`
+	WorkDir     string   `short:"d" long:"dir" description:"directory to run the task in (defaults to the current directory)"`
+	Output      string   `short:"o" long:"output" description:"directory to write the task's logs, test results and files to (defaults to evergreen_output in the task's directory)"`
+	Revision    string   `short:"r" long:"revision" description:"revision to give the task"`
+	Expansions  []string `short:"e" long:"expansion" description:"an expansion to run the task with, as key=value; may be given more than once"`
+}
+
+func (rtc *RunTaskCommand) Execute(args []string) error {
+	project, err := loadLocalProject(rtc.ProjectFile)
+	if err != nil {
+		return err
+	}
+	variant := project.FindBuildVariant(rtc.Variant)
+	if variant == nil {
+		return fmt.Errorf("Build variant '%v' is not in the project.", rtc.Variant)
+	}
+	if !variantHasTask(variant, rtc.Task) || project.FindProjectTask(rtc.Task) == nil {
+		return fmt.Errorf("Task '%v' is not in build variant '%v'.", rtc.Task, rtc.Variant)
+	}
+
+	expansions, err := parseExpansions(rtc.Expansions)
+	if err != nil {
+		return err
+	}
+
+	workDir := rtc.WorkDir
+	if workDir == "" {
+		if workDir, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+	if workDir, err = filepath.Abs(workDir); err != nil {
+		return err
+	}
+	output := rtc.Output
+	if output == "" {
+		output = filepath.Join(workDir, defaultLocalOutputDir)
+	}
+	if err = os.MkdirAll(output, 0755); err != nil {
+		return fmt.Errorf("Error creating output directory: %v", err)
+	}
+
+	communicator := &agent.LocalCommunicator{
+		Task: &model.Task{
+			Id:           fmt.Sprintf("local_%v_%v_%v", project.Identifier, variant.Name, rtc.Task),
+			DisplayName:  rtc.Task,
+			BuildVariant: variant.Name,
+			Project:      project.Identifier,
+			Revision:     rtc.Revision,
+			Requester:    evergreen.RepotrackerVersionRequester,
+		},
+		Distro: &distro.Distro{
+			Id:      "local",
+			WorkDir: workDir,
+		},
+		Project: project,
+		ProjectRef: &model.ProjectRef{
+			Identifier: project.Identifier,
+			Owner:      project.Owner,
+			Repo:       project.Repo,
+			Branch:     project.Branch,
+			RepoKind:   project.RepoKind,
+			RemotePath: project.RemotePath,
+		},
+		Expansions: expansions,
+		Dir:        output,
+	}
+
+	agt, err := agent.NewLocal(communicator, "")
+	if err != nil {
+		return err
+	}
+	if _, err = agt.RunTask(); err != nil {
+		return err
+	}
+
+	status := evergreen.TaskFailed
+	if communicator.EndDetail != nil {
+		status = communicator.EndDetail.Status
+	}
+	fmt.Printf("Task %v finished with status '%v'; its output is in %v\n",
+		rtc.Task, status, output)
+	if status != evergreen.TaskSucceeded {
+		return fmt.Errorf("Task did not succeed.")
+	}
+	return nil
+}
+
+// loadLocalProject reads in a project's config file, identifying the
+// project by the file's name.
+func loadLocalProject(path string) (*model.Project, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	project := &model.Project{}
+	identifier := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if err = model.LoadProjectInto(data, identifier, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// variantHasTask returns whether the task is one of the variant's.
+func variantHasTask(variant *model.BuildVariant, name string) bool {
+	for _, task := range variant.Tasks {
+		if task.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExpansions turns a list of key=value pairs into a map.
+func parseExpansions(pairs []string) (map[string]string, error) {
+	expansions := map[string]string{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Expansion '%v' must be in the form key=value.", pair)
+		}
+		expansions[parts[0]] = parts[1]
+	}
+	return expansions, nil
+}
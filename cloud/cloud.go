@@ -106,6 +106,46 @@ type CloudManager interface {
 	TimeTilNextPayment(host *host.Host) time.Duration
 }
 
+// CloudInstance describes an instance as reported by its provider.
+type CloudInstance struct {
+	// Id is the id the instance's host document would have
+	Id string
+
+	// Tag is the name Evergreen tagged the instance with when it was
+	// created, or empty if the instance carries no Evergreen tags
+	Tag string
+
+	Status     CloudStatus
+	LaunchTime time.Time
+}
+
+// InstanceLister is implemented by CloudManagers that can list the
+// instances they are running. Implementing it is optional; providers that
+// don't are skipped when reconciling cloud inventory with the database.
+type InstanceLister interface {
+	// ListInstances returns every live instance the provider has
+	// for the given distro.
+	ListInstances(*distro.Distro) ([]CloudInstance, error)
+}
+
+// Fallbacker is implemented by CloudManagers that can replace a host whose
+// instance they have not been able to start with one from another provider.
+// Implementing it is optional.
+type Fallbacker interface {
+	// FallBack replaces the host if its distro's fallback policy calls for
+	// it, and returns the replacement. Returns nil if the host was left alone.
+	FallBack(*host.Host) (*host.Host, error)
+}
+
+// ImagePruner is implemented by CloudManagers whose hosts keep a cache of
+// images that needs to be cleaned up over time. Implementing it is optional.
+type ImagePruner interface {
+	// PruneImages removes unused images that Evergreen put on the hosts
+	// backing the given distros, all of which use the provider, and returns
+	// their ids. Images put there by anything else must be left alone.
+	PruneImages([]distro.Distro) ([]string, error)
+}
+
 //CloudHost is a provider-agnostic host object that delegates methods
 //like status checks, ssh options, DNS name checks, termination, etc. to the
 //underlying provider's implementation.
@@ -15,6 +15,7 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"gopkg.in/mgo.v2/bson"
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -27,6 +28,24 @@ const (
 
 	ProviderName   = "docker"
 	TimeoutSeconds = 5
+
+	// prefix of the names of containers created by Evergreen
+	containerNamePrefix = "docker-"
+
+	// image pull policies
+	PullPolicyIfNotPresent = "if_not_present"
+	PullPolicyAlways       = "always"
+	PullPolicyNever        = "never"
+
+	// the period, in microseconds, over which a container's CPU quota applies
+	CPUPeriod = 100000
+
+	// the smallest memory limit Docker accepts, in megabytes
+	MinMemoryMB = 4
+
+	// how old an unused image must be before it is pruned, so that images
+	// that were just pulled aren't removed before a container uses them
+	ImagePruneGracePeriod = time.Hour
 )
 
 type DockerManager struct {
@@ -49,6 +68,23 @@ type Settings struct {
 	ClientPort int        `mapstructure:"client_port" json:"client_port" bson:"client_port"`
 	PortRange  *portRange `mapstructure:"port_range" json:"port_range" bson:"port_range"`
 	Auth       *auth      `mapstructure:"auth" json:"auth" bson:"auth"`
+
+	// PullPolicy is when the image is pulled onto the Docker host before a
+	// container is started: "if_not_present" (the default), "always", or
+	// "never"
+	PullPolicy string `mapstructure:"pull_policy" json:"pull_policy,omitempty" bson:"pull_policy,omitempty"`
+
+	// CPUShares is the container's relative CPU weight; Docker's default
+	// is 1024. CPUQuota is the CPU time, in microseconds, the container may
+	// use every 100ms, e.g. 50000 for half a CPU. MemoryMB is the
+	// container's memory limit, which includes swap. Zero means no limit.
+	CPUShares int64 `mapstructure:"cpu_shares" json:"cpu_shares,omitempty" bson:"cpu_shares,omitempty"`
+	CPUQuota  int64 `mapstructure:"cpu_quota" json:"cpu_quota,omitempty" bson:"cpu_quota,omitempty"`
+	MemoryMB  int64 `mapstructure:"memory_mb" json:"memory_mb,omitempty" bson:"memory_mb,omitempty"`
+
+	// Mounts are extra bind mounts for the container, in Docker's
+	// "/host/path:/container/path[:ro]" form
+	Mounts []string `mapstructure:"mounts" json:"mounts,omitempty" bson:"mounts,omitempty"`
 }
 
 var (
@@ -58,6 +94,11 @@ var (
 	ClientPort = bsonutil.MustHaveTag(Settings{}, "ClientPort")
 	PortRange  = bsonutil.MustHaveTag(Settings{}, "PortRange")
 	Auth       = bsonutil.MustHaveTag(Settings{}, "Auth")
+	PullPolicy = bsonutil.MustHaveTag(Settings{}, "PullPolicy")
+	CPUShares  = bsonutil.MustHaveTag(Settings{}, "CPUShares")
+	CPUQuota   = bsonutil.MustHaveTag(Settings{}, "CPUQuota")
+	MemoryMB   = bsonutil.MustHaveTag(Settings{}, "MemoryMB")
+	Mounts     = bsonutil.MustHaveTag(Settings{}, "Mounts")
 
 	// bson fields for the portRange struct
 	MinPort = bsonutil.MustHaveTag(portRange{}, "MinPort")
@@ -150,6 +191,82 @@ func populateHostConfig(hostConfig *docker.HostConfig, d *distro.Distro) error {
 	return nil
 }
 
+// populateResourceLimits sets the container's resource limits and mounts
+// from the distro's settings.
+func populateResourceLimits(hostConfig *docker.HostConfig, settings *Settings) {
+	hostConfig.CPUShares = settings.CPUShares
+	if settings.CPUQuota > 0 {
+		hostConfig.CPUQuota = settings.CPUQuota
+		hostConfig.CPUPeriod = CPUPeriod
+	}
+	if settings.MemoryMB > 0 {
+		hostConfig.Memory = settings.MemoryMB * 1024 * 1024
+		// setting swap to the same limit keeps the container from using
+		// swap to get around its memory limit
+		hostConfig.MemorySwap = hostConfig.Memory
+	}
+	hostConfig.Binds = settings.Mounts
+}
+
+// hostEndpoint names the Docker host of the given settings.
+func hostEndpoint(settings *Settings) string {
+	return fmt.Sprintf("%v:%v", settings.HostIp, settings.ClientPort)
+}
+
+// ensureImage makes sure the distro's image is on the Docker host,
+// pulling it as the distro's pull policy calls for. The image is recorded
+// as one Evergreen manages, so that it may be pruned once no distro uses it.
+func ensureImage(client *docker.Client, settings *Settings) error {
+	policy := settings.PullPolicy
+	if policy == "" {
+		policy = PullPolicyIfNotPresent
+	}
+
+	if err := model.RecordManagedImage(hostEndpoint(settings), settings.ImageId); err != nil {
+		return fmt.Errorf("error recording image '%v' on Docker host '%v': %v",
+			settings.ImageId, settings.HostIp, err)
+	}
+
+	if policy != PullPolicyAlways {
+		_, err := client.InspectImage(settings.ImageId)
+		if err == nil {
+			return nil
+		}
+		if err != docker.ErrNoSuchImage {
+			return fmt.Errorf("Docker inspect image API call failed for image '%v': %v",
+				settings.ImageId, err)
+		}
+		if policy == PullPolicyNever {
+			return fmt.Errorf("Image '%v' is not present on Docker host '%v' and its"+
+				" pull policy is '%v'", settings.ImageId, settings.HostIp, policy)
+		}
+	}
+
+	repository, tag := docker.ParseRepositoryTag(settings.ImageId)
+	if tag == "" {
+		tag = "latest"
+	}
+	evergreen.Logger.Logf(slogger.INFO, "Pulling image '%v:%v' onto Docker host '%v'",
+		repository, tag, settings.HostIp)
+	err := client.PullImage(docker.PullImageOptions{Repository: repository, Tag: tag},
+		docker.AuthConfiguration{})
+	if err != nil {
+		return fmt.Errorf("Docker pull image API call failed for image '%v': %v",
+			settings.ImageId, err)
+	}
+	return nil
+}
+
+// imageRefs returns the names an image may be referred to by, so that an
+// untagged name matches its "latest" tag.
+func imageRefs(name string) []string {
+	_, tag := docker.ParseRepositoryTag(name)
+	if tag == "" {
+		return []string{name, name + ":latest"}
+	}
+	return []string{name}
+}
+
 func retrieveOpenPortBinding(containerPtr *docker.Container) (string, error) {
 	exposedPorts := containerPtr.Config.ExposedPorts
 	ports := containerPtr.NetworkSettings.Ports
@@ -189,6 +306,38 @@ func (settings *Settings) Validate() error {
 		}
 	}
 
+	switch settings.PullPolicy {
+	case "", PullPolicyIfNotPresent, PullPolicyAlways, PullPolicyNever:
+	default:
+		return fmt.Errorf("Pull policy must be one of '%v', '%v', or '%v'",
+			PullPolicyIfNotPresent, PullPolicyAlways, PullPolicyNever)
+	}
+
+	if settings.CPUShares < 0 {
+		return fmt.Errorf("CPU shares must not be negative")
+	}
+
+	// Docker rejects quotas under a millisecond
+	if settings.CPUQuota < 0 || (settings.CPUQuota > 0 && settings.CPUQuota < 1000) {
+		return fmt.Errorf("CPU quota must be zero or at least 1000 microseconds")
+	}
+
+	if settings.MemoryMB < 0 || (settings.MemoryMB > 0 && settings.MemoryMB < MinMemoryMB) {
+		return fmt.Errorf("Memory limit must be zero or at least %vMB", MinMemoryMB)
+	}
+
+	for _, mount := range settings.Mounts {
+		parts := strings.Split(mount, ":")
+		if len(parts) < 2 || len(parts) > 3 ||
+			!strings.HasPrefix(parts[0], "/") || !strings.HasPrefix(parts[1], "/") {
+			return fmt.Errorf("Mount '%v' must be of the form"+
+				" '/host/path:/container/path[:ro]'", mount)
+		}
+		if len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw" {
+			return fmt.Errorf("Mount '%v' must end with ':ro' or ':rw', if anything", mount)
+		}
+	}
+
 	if settings.Auth == nil {
 		return fmt.Errorf("Authentication materials must not be blank")
 	} else if settings.Auth.Cert == "" {
@@ -227,9 +376,16 @@ func (dockerMgr *DockerManager) SpawnInstance(d *distro.Distro, owner string, us
 		evergreen.Logger.Logf(slogger.ERROR, "Unable to populate docker host config for host '%s': %v", settings.HostIp, err)
 		return nil, err
 	}
+	populateResourceLimits(hostConfig, settings)
+
+	// Make sure the image is there to start the container from
+	if err = ensureImage(dockerClient, settings); err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Unable to get image for host '%s': %v", settings.HostIp, err)
+		return nil, err
+	}
 
 	// Build container
-	containerName := "docker-" + bson.NewObjectId().Hex()
+	containerName := containerNamePrefix + bson.NewObjectId().Hex()
 	newContainer, err := dockerClient.CreateContainer(
 		docker.CreateContainerOptions{
 			Name: containerName,
@@ -385,6 +541,164 @@ func (dockerMgr *DockerManager) TerminateInstance(host *host.Host) error {
 	return host.Terminate()
 }
 
+// ListInstances returns every container on the distro's Docker host. Only
+// containers created by Evergreen are given a tag.
+func (dockerMgr *DockerManager) ListInstances(d *distro.Distro) ([]cloud.CloudInstance, error) {
+	dockerClient, _, err := generateClient(d)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := dockerClient.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, evergreen.Logger.Errorf(slogger.ERROR, "Docker list containers API call failed: %v", err)
+	}
+
+	instances := []cloud.CloudInstance{}
+	for _, c := range containers {
+		tag := ""
+		for _, name := range c.Names {
+			name = strings.TrimPrefix(name, "/")
+			if strings.HasPrefix(name, containerNamePrefix) {
+				tag = name
+			}
+		}
+		status := cloud.StatusStopped
+		if strings.HasPrefix(c.Status, "Up") {
+			status = cloud.StatusRunning
+		}
+		instances = append(instances, cloud.CloudInstance{
+			Id:         c.ID,
+			Tag:        tag,
+			Status:     status,
+			LaunchTime: time.Unix(c.Created, 0),
+		})
+	}
+	return instances, nil
+}
+
+// PruneImages removes images that no container is using from the Docker
+// hosts of the given distros. Only images that distros have run their
+// containers from before are pruned; of those, images that any of the
+// distros run their containers from now are kept, as are images too new to
+// have been used yet.
+// A distro whose Docker host can't be reached doesn't stop the others from
+// being pruned.
+func (dockerMgr *DockerManager) PruneImages(distros []distro.Distro) ([]string, error) {
+	// distros may share a Docker host, so prune each host once while keeping
+	// the images of all its distros
+	clients := map[string]*docker.Client{}
+	keep := map[string]map[string]bool{}
+	for _, d := range distros {
+		client, settings, err := generateClient(&d)
+		if err != nil {
+			evergreen.Logger.Logf(slogger.ERROR, "Not pruning images for distro %v: %v", d.Id, err)
+		}
+		// still keep the images of a distro with no client, in case it
+		// shares its Docker host with one that has
+		endpoint := hostEndpoint(settings)
+		if keep[endpoint] == nil {
+			keep[endpoint] = map[string]bool{}
+		}
+		if err == nil && clients[endpoint] == nil {
+			clients[endpoint] = client
+		}
+		for _, ref := range imageRefs(settings.ImageId) {
+			keep[endpoint][ref] = true
+		}
+	}
+
+	pruned := []string{}
+	failed := []string{}
+	for endpoint, client := range clients {
+		names, err := model.FindManagedImageNames(endpoint)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("'%v': %v", endpoint, err))
+			continue
+		}
+		managed := map[string]bool{}
+		for _, name := range names {
+			for _, ref := range imageRefs(name) {
+				managed[ref] = true
+			}
+		}
+		ids, err := pruneHostImages(client, managed, keep[endpoint])
+		for _, name := range names {
+			removed := true
+			for _, ref := range imageRefs(name) {
+				removed = removed && !managed[ref]
+			}
+			if !removed {
+				continue
+			}
+			// the image is gone, so it no longer needs managing
+			if err := model.RemoveManagedImage(endpoint, name); err != nil {
+				evergreen.Logger.Logf(slogger.ERROR, "Error forgetting image '%v' on"+
+					" Docker host '%v': %v", name, endpoint, err)
+			}
+		}
+		pruned = append(pruned, ids...)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("'%v': %v", endpoint, err))
+		}
+	}
+	if len(failed) > 0 {
+		return pruned, fmt.Errorf("error pruning images on Docker hosts %v", strings.Join(failed, ", "))
+	}
+	return pruned, nil
+}
+
+// pruneHostImages removes the managed images on one Docker host that aren't
+// kept and that no container, running or stopped, was created from. The
+// names of removed images are taken out of managed.
+func pruneHostImages(client *docker.Client, managed, keep map[string]bool) ([]string, error) {
+	containers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("Docker list containers API call failed: %v", err)
+	}
+	for _, c := range containers {
+		for _, ref := range imageRefs(c.Image) {
+			keep[ref] = true
+		}
+	}
+
+	images, err := client.ListImages(docker.ListImagesOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Docker list images API call failed: %v", err)
+	}
+
+	pruned := []string{}
+	for _, image := range images {
+		if time.Since(time.Unix(image.Created, 0)) < ImagePruneGracePeriod {
+			continue
+		}
+		used, isManaged := keep[image.ID], managed[image.ID]
+		for _, tag := range image.RepoTags {
+			used = used || keep[tag]
+			isManaged = isManaged || managed[tag]
+		}
+		if used || !isManaged {
+			continue
+		}
+
+		// Docker refuses to remove images that containers still use, so
+		// a failure here only means the image was in use after all
+		if err := client.RemoveImage(image.ID); err != nil {
+			evergreen.Logger.Logf(slogger.WARN, "Unable to remove image '%v' (%v): %v",
+				image.ID, strings.Join(image.RepoTags, ", "), err)
+			continue
+		}
+		evergreen.Logger.Logf(slogger.INFO, "Removed unused image '%v' (%v)",
+			image.ID, strings.Join(image.RepoTags, ", "))
+		pruned = append(pruned, image.ID)
+		delete(managed, image.ID)
+		for _, tag := range image.RepoTags {
+			delete(managed, tag)
+		}
+	}
+	return pruned, nil
+}
+
 //Configure populates a DockerManager by reading relevant settings from the
 //config object.
 func (dockerMgr *DockerManager) Configure(settings *evergreen.Settings) error {
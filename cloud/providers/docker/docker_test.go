@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestValidateLimits(t *testing.T) {
+	Convey("When validating a docker distro's container settings", t, func() {
+		settings := &Settings{
+			HostIp:     "localhost",
+			ImageId:    "ubuntu",
+			ClientPort: 2376,
+			Auth:       &auth{Cert: "cert", Key: "key", Ca: "ca"},
+		}
+
+		Convey("settings with no limits should be valid", func() {
+			So(settings.Validate(), ShouldBeNil)
+		})
+
+		Convey("an unknown pull policy should be invalid", func() {
+			settings.PullPolicy = "sometimes"
+			So(settings.Validate(), ShouldNotBeNil)
+			settings.PullPolicy = PullPolicyNever
+			So(settings.Validate(), ShouldBeNil)
+		})
+
+		Convey("limits Docker would reject should be invalid", func() {
+			settings.CPUQuota = 500
+			So(settings.Validate(), ShouldNotBeNil)
+			settings.CPUQuota = 50000
+			settings.MemoryMB = 2
+			So(settings.Validate(), ShouldNotBeNil)
+			settings.MemoryMB = 512
+			settings.CPUShares = -1
+			So(settings.Validate(), ShouldNotBeNil)
+			settings.CPUShares = 512
+			So(settings.Validate(), ShouldBeNil)
+		})
+
+		Convey("mounts should need absolute paths on both sides", func() {
+			settings.Mounts = []string{"/data:/data:ro", "/cache:/cache"}
+			So(settings.Validate(), ShouldBeNil)
+			settings.Mounts = []string{"data:/data"}
+			So(settings.Validate(), ShouldNotBeNil)
+			settings.Mounts = []string{"/data"}
+			So(settings.Validate(), ShouldNotBeNil)
+			settings.Mounts = []string{"/data:/data:rx"}
+			So(settings.Validate(), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestPopulateResourceLimits(t *testing.T) {
+	Convey("When populating a container's resource limits", t, func() {
+		hostConfig := &docker.HostConfig{}
+
+		Convey("zero limits should leave the container unlimited", func() {
+			populateResourceLimits(hostConfig, &Settings{})
+			So(hostConfig.CPUShares, ShouldEqual, 0)
+			So(hostConfig.CPUQuota, ShouldEqual, 0)
+			So(hostConfig.Memory, ShouldEqual, 0)
+			So(hostConfig.MemorySwap, ShouldEqual, 0)
+		})
+
+		Convey("set limits should be enforced without swap", func() {
+			populateResourceLimits(hostConfig, &Settings{
+				CPUShares: 512,
+				CPUQuota:  50000,
+				MemoryMB:  256,
+				Mounts:    []string{"/data:/data:ro"},
+			})
+			So(hostConfig.CPUShares, ShouldEqual, 512)
+			So(hostConfig.CPUQuota, ShouldEqual, 50000)
+			So(hostConfig.CPUPeriod, ShouldEqual, CPUPeriod)
+			So(hostConfig.Memory, ShouldEqual, 256*1024*1024)
+			So(hostConfig.MemorySwap, ShouldEqual, hostConfig.Memory)
+			So(hostConfig.Binds, ShouldResemble, []string{"/data:/data:ro"})
+		})
+	})
+}
+
+func TestImageRefs(t *testing.T) {
+	Convey("An untagged image name should also match its latest tag", t, func() {
+		So(imageRefs("ubuntu"), ShouldResemble, []string{"ubuntu", "ubuntu:latest"})
+		So(imageRefs("ubuntu:14.04"), ShouldResemble, []string{"ubuntu:14.04"})
+		So(imageRefs("registry:5000/ubuntu"), ShouldResemble,
+			[]string{"registry:5000/ubuntu", "registry:5000/ubuntu:latest"})
+	})
+}
+
+func TestPruneImagesSkipsBadDistros(t *testing.T) {
+	Convey("A distro whose Docker client can't be made should be skipped", t, func() {
+		distros := []distro.Distro{
+			{Id: "bad", ProviderSettings: &map[string]interface{}{"host_ip": "10.0.0.1"}},
+		}
+		pruned, err := (&DockerManager{}).PruneImages(distros)
+		So(err, ShouldBeNil)
+		So(pruned, ShouldBeEmpty)
+	})
+}
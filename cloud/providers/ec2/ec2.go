@@ -9,6 +9,7 @@ import (
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/util"
 	"github.com/goamz/goamz/aws"
 	"github.com/goamz/goamz/ec2"
 	"github.com/mitchellh/mapstructure"
@@ -18,6 +19,7 @@ import (
 // EC2Manager implements the CloudManager interface for Amazon EC2
 type EC2Manager struct {
 	awsCredentials *aws.Auth
+	settings       *evergreen.Settings
 }
 
 //Valid values for EC2 instance states:
@@ -75,6 +77,7 @@ func (cloudManager *EC2Manager) Configure(settings *evergreen.Settings) error {
 		AccessKey: settings.Providers.AWS.Id,
 		SecretKey: settings.Providers.AWS.Secret,
 	}
+	cloudManager.settings = settings
 	return nil
 }
 
@@ -149,6 +152,26 @@ func (cloudManager *EC2Manager) SpawnInstance(d *distro.Distro, owner string, us
 		UserHost:         userHost,
 	}
 
+	// hosts provisioned by userdata report back to the API server
+	// themselves, so they need a secret and the script to do it with
+	var userData []byte
+	if d.UsesUserDataProvisioning() {
+		intentHost.Secret = util.RandomString()
+		script, err := cloud.MakeUserDataScript(intentHost, cloudManager.settings)
+		if err != nil {
+			return nil, fmt.Errorf("Error building userdata for distro %v: %v", d.Id, err)
+		}
+		userData = []byte(script)
+	}
+
+	// spawn the host with the current version of its distro's key, if the
+	// provider knows it, so it needn't be rotated
+	keyName := ec2Settings.KeyName
+	if version, keyPair := cloudManager.settings.SpawnKeyPair(d.SSHKey); keyPair != "" {
+		keyName = keyPair
+		intentHost.KeyVersion = version
+	}
+
 	// record this 'intent host'
 	if err := intentHost.Insert(); err != nil {
 		return nil, evergreen.Logger.Errorf(slogger.ERROR, "Could not insert intent "+
@@ -163,10 +186,11 @@ func (cloudManager *EC2Manager) SpawnInstance(d *distro.Distro, owner string, us
 		MinCount:       1,
 		MaxCount:       1,
 		ImageId:        ec2Settings.AMI,
-		KeyName:        ec2Settings.KeyName,
+		KeyName:        keyName,
 		InstanceType:   ec2Settings.InstanceType,
 		SecurityGroups: ec2.SecurityGroupNames(ec2Settings.SecurityGroup),
 		BlockDevices:   blockDevices,
+		UserData:       userData,
 	}
 
 	// start the instance - starting an instance does not mean you can connect
@@ -270,6 +294,12 @@ func (cloudManager *EC2Manager) TerminateInstance(host *host.Host) error {
 	return host.Terminate()
 }
 
+// ListInstances returns the on-demand instances tagged as belonging to the
+// given distro.
+func (cloudManager *EC2Manager) ListInstances(d *distro.Distro) ([]cloud.CloudInstance, error) {
+	return listDistroInstances(getUSEast(*cloudManager.awsCredentials), d.Id, false)
+}
+
 // determine how long until a payment is due for the host
 func (cloudManager *EC2Manager) TimeTilNextPayment(host *host.Host) time.Duration {
 	return timeTilNextEC2Payment(host)
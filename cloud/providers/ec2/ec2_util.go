@@ -123,6 +123,50 @@ func getInstanceInfo(ec2Handle *ec2.EC2, instanceId string) (*ec2.Instance, erro
 	return &instances[0], nil
 }
 
+// listDistroInstances returns the live instances tagged as belonging to the
+// given distro. Spot instances are identified by the id of the spot request
+// they fulfilled, since that is what their host documents use.
+func listDistroInstances(ec2Handle *ec2.EC2, distroId string, spot bool) ([]cloud.CloudInstance, error) {
+	filter := ec2.NewFilter()
+	filter.Add("tag:distro", distroId)
+	filter.Add("instance-state-name", EC2StatusPending, EC2StatusRunning, "stopping", EC2StatusStopped)
+	resp, err := ec2Handle.DescribeInstances(nil, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []cloud.CloudInstance{}
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			if (instance.InstanceLifecycle == "spot") != spot {
+				continue
+			}
+			id := instance.InstanceId
+			if spot {
+				id = instance.SpotInstanceRequestId
+			}
+			launchTime, err := time.Parse(time.RFC3339, instance.LaunchTime)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing launch time of instance %v: %v",
+					instance.InstanceId, err)
+			}
+			name := ""
+			for _, tag := range instance.Tags {
+				if tag.Key == "Name" {
+					name = tag.Value
+				}
+			}
+			instances = append(instances, cloud.CloudInstance{
+				Id:         id,
+				Tag:        name,
+				Status:     ec2StatusToEvergreenStatus(instance.State.Name),
+				LaunchTime: launchTime,
+			})
+		}
+	}
+	return instances, nil
+}
+
 //ec2StatusToEvergreenStatus returns a "universal" status code based on EC2's
 //provider-specific status codes.
 func ec2StatusToEvergreenStatus(ec2Status string) cloud.CloudStatus {
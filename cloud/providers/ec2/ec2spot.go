@@ -8,7 +8,9 @@ import (
 	"github.com/evergreen-ci/evergreen/hostutil"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/event"
 	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/util"
 	"github.com/goamz/goamz/aws"
 	"github.com/goamz/goamz/ec2"
 	"github.com/mitchellh/mapstructure"
@@ -28,6 +30,7 @@ const (
 // EC2SpotManager implements the CloudManager interface for Amazon EC2 Spot
 type EC2SpotManager struct {
 	awsCredentials *aws.Auth
+	settings       *evergreen.Settings
 }
 
 type EC2SpotSettings struct {
@@ -37,6 +40,39 @@ type EC2SpotSettings struct {
 	KeyName       string       `mapstructure:"key_name" json:"key_name,omitempty" bson:"key_name,omitempty"`
 	MountPoints   []MountPoint `mapstructure:"mount_points" json:"mount_points,omitempty" bson:"mount_points,omitempty"`
 	BidPrice      float64      `mapstructure:"bid_price" json:"bid_price,omitempty" bson:"bid_price,omitempty"`
+
+	// Fallback is whether an on-demand instance is started in place of a
+	// spot request that fails, can't be fulfilled because of its price or a
+	// lack of capacity, or stays unfulfilled for FallbackMinutes. Zero
+	// FallbackMinutes never falls back just for the time a request has been
+	// unfulfilled.
+	Fallback        bool `mapstructure:"fallback" json:"fallback,omitempty" bson:"fallback,omitempty"`
+	FallbackMinutes int  `mapstructure:"fallback_minutes" json:"fallback_minutes,omitempty" bson:"fallback_minutes,omitempty"`
+}
+
+// spotFallbackCodes are the status codes of spot requests that won't be
+// fulfilled any time soon, because of their price or a lack of capacity.
+var spotFallbackCodes = map[string]bool{
+	"price-too-low":           true,
+	"capacity-not-available":  true,
+	"capacity-oversubscribed": true,
+}
+
+// spotRequestErrorCodes are the codes of the errors EC2 fails spot requests
+// with because of their price or a lack of capacity. Any other error, such
+// as one in the credentials or the distro's settings, would fail an
+// on-demand request too.
+var spotRequestErrorCodes = map[string]bool{
+	"InsufficientInstanceCapacity": true,
+	"MaxSpotInstanceCountExceeded": true,
+	"SpotMaxPriceTooLow":           true,
+}
+
+// spotRequestErrorFallsBack returns whether a failed spot request should be
+// replaced by an on-demand one.
+func spotRequestErrorFallsBack(err error) bool {
+	ec2Err, ok := err.(*ec2.Error)
+	return ok && spotRequestErrorCodes[ec2Err.Code]
 }
 
 func (self *EC2SpotSettings) Validate() error {
@@ -44,6 +80,10 @@ func (self *EC2SpotSettings) Validate() error {
 		return fmt.Errorf("Bid price must be greater than zero")
 	}
 
+	if self.FallbackMinutes < 0 {
+		return fmt.Errorf("Fallback minutes must not be negative")
+	}
+
 	if self.AMI == "" {
 		return fmt.Errorf("AMI must not be blank")
 	}
@@ -78,6 +118,7 @@ func (cloudManager *EC2SpotManager) Configure(settings *evergreen.Settings) erro
 		AccessKey: settings.Providers.AWS.Id,
 		SecretKey: settings.Providers.AWS.Secret,
 	}
+	cloudManager.settings = settings
 	return nil
 }
 
@@ -85,6 +126,129 @@ func (_ *EC2SpotManager) GetSettings() cloud.ProviderSettings {
 	return &EC2SpotSettings{}
 }
 
+// FallBack replaces a spot host with an on-demand one if the distro allows
+// it and its spot request has failed, can't be fulfilled, or has been pending
+// for longer than the distro allows. The spot request is cancelled and the
+// spot host marked terminated.
+func (cloudManager *EC2SpotManager) FallBack(h *host.Host) (*host.Host, error) {
+	spotSettings := &EC2SpotSettings{}
+	if err := mapstructure.Decode(h.Distro.ProviderSettings, spotSettings); err != nil {
+		return nil, fmt.Errorf("Error decoding params for distro %v: %v", h.Distro.Id, err)
+	}
+	if !spotSettings.Fallback {
+		return nil, nil
+	}
+
+	spotDetails, err := cloudManager.describeSpotRequest(h.Id)
+	if err != nil {
+		return nil, evergreen.Logger.Errorf(slogger.ERROR,
+			"failed to get spot request info for %v: %v", h.Id, err)
+	}
+	statusCode := ""
+	if spotDetails.State == SpotStatusOpen {
+		status, err := describeSpotStatus(*cloudManager.awsCredentials, aws.USEast, h.Id)
+		if err != nil {
+			evergreen.Logger.Logf(slogger.WARN, "Failed to get the status of spot request %v: %v",
+				h.Id, err)
+		} else {
+			statusCode = status.Code
+		}
+	}
+	reason := spotFallbackReason(spotSettings, spotDetails, statusCode, time.Now().Sub(h.CreationTime))
+	if reason == "" {
+		return nil, nil
+	}
+
+	// cancel the spot request first, so we don't end up paying for both
+	evergreen.Logger.Logf(slogger.INFO, "Cancelling spot request %v: %v", h.Id, reason)
+	ec2Handle := getUSEast(*cloudManager.awsCredentials)
+	if _, err = ec2Handle.CancelSpotRequests([]string{h.Id}); err != nil {
+		return nil, evergreen.Logger.Errorf(slogger.ERROR,
+			"Failed to cancel spot request for host %v: %v", h.Id, err)
+	}
+
+	// the request may have been fulfilled before it was cancelled, in
+	// which case the spot host can carry on
+	spotDetails, err = cloudManager.describeSpotRequest(h.Id)
+	if err != nil {
+		return nil, evergreen.Logger.Errorf(slogger.ERROR,
+			"failed to get spot request info for %v: %v", h.Id, err)
+	}
+	if spotDetails.InstanceId != "" {
+		evergreen.Logger.Logf(slogger.INFO, "Spot request %v was fulfilled by %v before"+
+			" it was cancelled; not falling back", h.Id, spotDetails.InstanceId)
+		return nil, nil
+	}
+
+	replacement, err := cloudManager.spawnOnDemand(&h.Distro, h.StartedBy, h.UserHost)
+	if err != nil {
+		return nil, evergreen.Logger.Errorf(slogger.ERROR,
+			"Failed to spawn on-demand replacement for spot host %v: %v", h.Id, err)
+	}
+
+	event.LogHostSpotFallback(h.Id, replacement.Id, reason)
+	h.TerminationReason = evergreen.TerminationReasonSpotFallback
+	if err := h.Terminate(); err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Failed to mark spot host %v terminated: %v", h.Id, err)
+	}
+	countSpotFallback(h.Distro.Id)
+
+	evergreen.Logger.Logf(slogger.INFO, "Replaced spot host %v with on-demand host %v",
+		h.Id, replacement.Id)
+	return replacement, nil
+}
+
+// spotFallbackReason returns why an unfulfilled spot request with the given
+// status code, which has been pending for the given time, should be replaced
+// by an on-demand instance, or "" if it shouldn't be.
+func spotFallbackReason(spotSettings *EC2SpotSettings, spotDetails *ec2.SpotRequestResult,
+	statusCode string, pending time.Duration) string {
+	if !spotSettings.Fallback || spotDetails.InstanceId != "" {
+		return ""
+	}
+	switch spotDetails.State {
+	case SpotStatusFailed:
+		return "spot request failed"
+	case SpotStatusOpen, SpotStatusActive:
+		if spotFallbackCodes[statusCode] {
+			return fmt.Sprintf("spot request can't be fulfilled: %v", statusCode)
+		}
+		if spotSettings.FallbackMinutes == 0 ||
+			pending < time.Duration(spotSettings.FallbackMinutes)*time.Minute {
+			return ""
+		}
+		return fmt.Sprintf("spot request pending for %v", pending)
+	}
+	return ""
+}
+
+// spawnOnDemand starts an on-demand instance for a spot distro.
+func (cloudManager *EC2SpotManager) spawnOnDemand(d *distro.Distro, owner string,
+	userHost bool) (*host.Host, error) {
+	onDemandDistro := *d
+	onDemandDistro.Provider = OnDemandProviderName
+	onDemandManager := &EC2Manager{}
+	if err := onDemandManager.Configure(cloudManager.settings); err != nil {
+		return nil, err
+	}
+	return onDemandManager.SpawnInstance(&onDemandDistro, owner, userHost)
+}
+
+// countSpotFallback records a fallback for the distro, logging any failure,
+// since the fallback itself has already happened.
+func countSpotFallback(distroId string) {
+	if err := model.IncSpotFallbackCount(distroId); err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Failed to count spot fallback for distro %v: %v",
+			distroId, err)
+	}
+}
+
+// ListInstances returns the spot instances tagged as belonging to the given
+// distro. Spot requests that have not been fulfilled yet are not included.
+func (cloudManager *EC2SpotManager) ListInstances(d *distro.Distro) ([]cloud.CloudInstance, error) {
+	return listDistroInstances(getUSEast(*cloudManager.awsCredentials), d.Id, true)
+}
+
 // determine how long until a payment is due for the host
 func (cloudManager *EC2SpotManager) TimeTilNextPayment(host *host.Host) time.Duration {
 	return timeTilNextEC2Payment(host)
@@ -240,6 +404,26 @@ func (cloudManager *EC2SpotManager) SpawnInstance(d *distro.Distro, owner string
 		UserHost:         userHost,
 	}
 
+	// hosts provisioned by userdata report back to the API server
+	// themselves, so they need a secret and the script to do it with
+	var userData []byte
+	if d.UsesUserDataProvisioning() {
+		intentHost.Secret = util.RandomString()
+		script, err := cloud.MakeUserDataScript(intentHost, cloudManager.settings)
+		if err != nil {
+			return nil, fmt.Errorf("Error building userdata for distro %v: %v", d.Id, err)
+		}
+		userData = []byte(script)
+	}
+
+	// spawn the host with the current version of its distro's key, if the
+	// provider knows it, so it needn't be rotated
+	keyName := ec2Settings.KeyName
+	if version, keyPair := cloudManager.settings.SpawnKeyPair(d.SSHKey); keyPair != "" {
+		keyName = keyPair
+		intentHost.KeyVersion = version
+	}
+
 	// record this 'intent host'
 	if err := intentHost.Insert(); err != nil {
 		return nil, evergreen.Logger.Errorf(slogger.ERROR, "Could not insert intent "+
@@ -254,10 +438,11 @@ func (cloudManager *EC2SpotManager) SpawnInstance(d *distro.Distro, owner string
 		SpotPrice:      fmt.Sprintf("%v", ec2Settings.BidPrice),
 		InstanceCount:  1,
 		ImageId:        ec2Settings.AMI,
-		KeyName:        ec2Settings.KeyName,
+		KeyName:        keyName,
 		InstanceType:   ec2Settings.InstanceType,
 		SecurityGroups: ec2.SecurityGroupNames(ec2Settings.SecurityGroup),
 		BlockDevices:   blockDevices,
+		UserData:       userData,
 	}
 
 	spotResp, err := ec2Handle.RequestSpotInstances(spotRequest)
@@ -266,8 +451,21 @@ func (cloudManager *EC2SpotManager) SpawnInstance(d *distro.Distro, owner string
 		if err := intentHost.Remove(); err != nil {
 			evergreen.Logger.Logf(slogger.ERROR, "Failed to remove intent host %v: %v", intentHost.Id, err)
 		}
-		return nil, evergreen.Logger.Errorf(slogger.ERROR, "Failed starting spot instance "+
-			" for distro '%v' on intent host %v: %v", d.Id, intentHost.Id, err)
+		if !ec2Settings.Fallback || !spotRequestErrorFallsBack(err) {
+			return nil, evergreen.Logger.Errorf(slogger.ERROR, "Failed starting spot instance "+
+				" for distro '%v' on intent host %v: %v", d.Id, intentHost.Id, err)
+		}
+
+		evergreen.Logger.Logf(slogger.WARN, "Failed requesting spot instance for distro %v;"+
+			" starting an on-demand instance instead: %v", d.Id, err)
+		onDemandHost, err := cloudManager.spawnOnDemand(d, owner, userHost)
+		if err != nil {
+			return nil, evergreen.Logger.Errorf(slogger.ERROR, "Failed to spawn on-demand "+
+				"instance in place of spot instance for distro %v: %v", d.Id, err)
+		}
+		event.LogHostSpotFallback(intentHost.Id, onDemandHost.Id, "spot request failed")
+		countSpotFallback(d.Id)
+		return onDemandHost, nil
 	}
 
 	spotReqRes := spotResp.SpotRequestResults[0]
@@ -0,0 +1,148 @@
+package ec2
+
+import (
+	"fmt"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/ec2"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSpotFallbackReason(t *testing.T) {
+	Convey("With a spot distro that falls back after ten minutes", t, func() {
+		settings := &EC2SpotSettings{Fallback: true, FallbackMinutes: 10}
+
+		Convey("fulfilled requests should never fall back", func() {
+			spotDetails := &ec2.SpotRequestResult{State: SpotStatusActive, InstanceId: "i-1"}
+			So(spotFallbackReason(settings, spotDetails, "", time.Hour), ShouldEqual, "")
+		})
+
+		Convey("failed requests should fall back at once", func() {
+			spotDetails := &ec2.SpotRequestResult{State: SpotStatusFailed}
+			So(spotFallbackReason(settings, spotDetails, "", time.Minute), ShouldNotEqual, "")
+		})
+
+		Convey("requests that can't be fulfilled at their price or for lack"+
+			" of capacity should fall back at once", func() {
+			for _, code := range []string{"price-too-low", "capacity-not-available",
+				"capacity-oversubscribed"} {
+				spotDetails := &ec2.SpotRequestResult{State: SpotStatusOpen}
+				So(spotFallbackReason(settings, spotDetails, code, time.Minute),
+					ShouldContainSubstring, code)
+			}
+		})
+
+		Convey("other pending requests should only fall back once they have"+
+			" waited long enough", func() {
+			spotDetails := &ec2.SpotRequestResult{State: SpotStatusOpen}
+			So(spotFallbackReason(settings, spotDetails, "pending-evaluation", 5*time.Minute),
+				ShouldEqual, "")
+			So(spotFallbackReason(settings, spotDetails, "pending-evaluation", 15*time.Minute),
+				ShouldNotEqual, "")
+		})
+
+		Convey("no fallback minutes should only fall back on price or capacity", func() {
+			settings.FallbackMinutes = 0
+			spotDetails := &ec2.SpotRequestResult{State: SpotStatusOpen}
+			So(spotFallbackReason(settings, spotDetails, "pending-evaluation", time.Hour),
+				ShouldEqual, "")
+			So(spotFallbackReason(settings, spotDetails, "", time.Hour), ShouldEqual, "")
+			So(spotFallbackReason(settings, spotDetails, "price-too-low", time.Second),
+				ShouldNotEqual, "")
+		})
+
+		Convey("closed and cancelled requests should not fall back", func() {
+			for _, state := range []string{SpotStatusClosed, SpotStatusCancelled} {
+				spotDetails := &ec2.SpotRequestResult{State: state}
+				So(spotFallbackReason(settings, spotDetails, "", time.Hour), ShouldEqual, "")
+			}
+		})
+
+		Convey("nothing should fall back unless the distro allows it", func() {
+			settings.Fallback = false
+			spotDetails := &ec2.SpotRequestResult{State: SpotStatusFailed}
+			So(spotFallbackReason(settings, spotDetails, "", time.Hour), ShouldEqual, "")
+		})
+	})
+}
+
+func TestSpotRequestErrorFallsBack(t *testing.T) {
+	Convey("Only spot requests that failed for price or capacity should fall back", t, func() {
+		So(spotRequestErrorFallsBack(&ec2.Error{Code: "InsufficientInstanceCapacity"}), ShouldBeTrue)
+		So(spotRequestErrorFallsBack(&ec2.Error{Code: "SpotMaxPriceTooLow"}), ShouldBeTrue)
+		So(spotRequestErrorFallsBack(&ec2.Error{Code: "AuthFailure"}), ShouldBeFalse)
+		So(spotRequestErrorFallsBack(&ec2.Error{Code: "InvalidParameterValue"}), ShouldBeFalse)
+		So(spotRequestErrorFallsBack(fmt.Errorf("connection refused")), ShouldBeFalse)
+	})
+}
+
+func TestDescribeSpotStatus(t *testing.T) {
+	Convey("With an EC2 endpoint that describes spot requests", t, func() {
+		query := url.Values{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			fmt.Fprint(w, `<DescribeSpotInstanceRequestsResponse>
+  <spotInstanceRequestSet>
+    <item>
+      <spotInstanceRequestId>sir-1</spotInstanceRequestId>
+      <state>open</state>
+      <status>
+        <code>capacity-not-available</code>
+        <message>There is no capacity available</message>
+      </status>
+    </item>
+  </spotInstanceRequestSet>
+</DescribeSpotInstanceRequestsResponse>`)
+		}))
+		Reset(func() { ts.Close() })
+		region := aws.Region{EC2Endpoint: ts.URL}
+		auth := aws.Auth{AccessKey: "key", SecretKey: "secret"}
+
+		Convey("the status of a request should be parsed from a signed request", func() {
+			status, err := describeSpotStatus(auth, region, "sir-1")
+			So(err, ShouldBeNil)
+			So(status.Code, ShouldEqual, "capacity-not-available")
+			So(query.Get("Action"), ShouldEqual, "DescribeSpotInstanceRequests")
+			So(query.Get("SpotInstanceRequestId.1"), ShouldEqual, "sir-1")
+			So(query.Get("Signature"), ShouldNotEqual, "")
+		})
+
+		Convey("a request that isn't described should be an error", func() {
+			_, err := describeSpotStatus(auth, region, "sir-2")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestFallBack(t *testing.T) {
+	Convey("With a spot host whose distro doesn't allow falling back", t, func() {
+		h := &host.Host{
+			Id: "sir-1",
+			Distro: distro.Distro{
+				Id:               "d1",
+				Provider:         SpotProviderName,
+				ProviderSettings: &map[string]interface{}{"fallback_minutes": 10},
+			},
+			CreationTime: time.Now().Add(-time.Hour),
+		}
+
+		Convey("FallBack should leave it alone without asking EC2", func() {
+			// the manager has no credentials, so any call to EC2 would panic
+			replacement, err := (&EC2SpotManager{}).FallBack(h)
+			So(err, ShouldBeNil)
+			So(replacement, ShouldBeNil)
+		})
+
+		Convey("FallBack should fail on settings it can't decode", func() {
+			h.Distro.ProviderSettings = &map[string]interface{}{"fallback": "sometimes"}
+			_, err := (&EC2SpotManager{}).FallBack(h)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,79 @@
+package ec2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/goamz/goamz/aws"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// spotRequestStatus is the status EC2 gives a spot request, which says why
+// it is in its state, e.g. that it can't be fulfilled at its price. The
+// vendored goamz leaves it out of the spot requests it describes, so it is
+// looked up here.
+type spotRequestStatus struct {
+	Code    string `xml:"code"`
+	Message string `xml:"message"`
+}
+
+type describeSpotStatusResp struct {
+	Requests []struct {
+		Id     string            `xml:"spotInstanceRequestId"`
+		Status spotRequestStatus `xml:"status"`
+	} `xml:"spotInstanceRequestSet>item"`
+}
+
+// describeSpotStatus returns the status of the spot request with the given
+// id in the given region.
+func describeSpotStatus(auth aws.Auth, region aws.Region, spotReqId string) (*spotRequestStatus, error) {
+	endpoint, err := url.Parse(region.EC2Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Path == "" {
+		endpoint.Path = "/"
+	}
+	signer, err := aws.NewV2Signer(auth, aws.ServiceInfo{Endpoint: region.EC2Endpoint,
+		Signer: aws.V2Signature})
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"Action":                  "DescribeSpotInstanceRequests",
+		"SpotInstanceRequestId.1": spotReqId,
+		"Version":                 "2014-02-01",
+		"Timestamp":               time.Now().In(time.UTC).Format(time.RFC3339),
+	}
+	signer.Sign("GET", endpoint.Path, params)
+	query := url.Values{}
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	resp, err := http.Get(endpoint.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("describing spot request %v failed (%v): %v", spotReqId,
+			resp.StatusCode, string(body))
+	}
+
+	result := &describeSpotStatusResp{}
+	if err = xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+	for _, request := range result.Requests {
+		if request.Id == spotReqId {
+			return &request.Status, nil
+		}
+	}
+	return nil, fmt.Errorf("spot request %v was not described", spotReqId)
+}
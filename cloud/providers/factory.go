@@ -51,7 +51,7 @@ func GetCloudHost(host *host.Host, settings *evergreen.Settings) (*cloud.CloudHo
 
 	keyPath := ""
 	if host.Distro.SSHKey != "" {
-		keyPath = settings.Keys[host.Distro.SSHKey]
+		keyPath = settings.KeyPath(host.Distro.SSHKey, host.KeyVersion)
 	}
 	return &cloud.CloudHost{host, keyPath, mgr}, nil
 }
@@ -11,6 +11,15 @@ import (
 
 const ProviderName = "mock"
 
+// MockInstances is the inventory the mock provider reports, keyed by
+// distro id. Hosts of a distro with an inventory are reported as terminated
+// unless one of the distro's instances has their id.
+var MockInstances = map[string][]cloud.CloudInstance{}
+
+// MockStatusErrors holds the errors the mock provider fails to get the
+// status of instances with, keyed by host id.
+var MockStatusErrors = map[string]error{}
+
 type MockCloudManager struct{}
 
 func (staticMgr *MockCloudManager) SpawnInstance(distro *distro.Distro, owner string, userHost bool) (*host.Host, error) {
@@ -23,7 +32,24 @@ func (staticMgr *MockCloudManager) SpawnInstance(distro *distro.Distro, owner st
 
 // get the status of an instance
 func (staticMgr *MockCloudManager) GetInstanceStatus(host *host.Host) (cloud.CloudStatus, error) {
-	return cloud.StatusRunning, nil
+	if err, ok := MockStatusErrors[host.Id]; ok {
+		return cloud.StatusUnknown, err
+	}
+	instances, ok := MockInstances[host.Distro.Id]
+	if !ok {
+		return cloud.StatusRunning, nil
+	}
+	for _, instance := range instances {
+		if instance.Id == host.Id {
+			return instance.Status, nil
+		}
+	}
+	return cloud.StatusTerminated, nil
+}
+
+// ListInstances returns the mock inventory for the distro.
+func (staticMgr *MockCloudManager) ListInstances(d *distro.Distro) ([]cloud.CloudInstance, error) {
+	return MockInstances[d.Id], nil
 }
 
 // get instance DNS
@@ -0,0 +1,90 @@
+package cloud
+
+import (
+	"fmt"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"strings"
+)
+
+// Expansions available to the setup script of hosts that talk back to
+// the API server on their own, i.e. pull-dispatch or userdata-provisioned
+// distros.
+const (
+	HostIdExpansion     = "host_id"
+	HostSecretExpansion = "host_secret"
+	APIServerExpansion  = "api_server"
+	// the distro's plugin directory, to start the agent with
+	PluginDirExpansion = "plugin_dir"
+)
+
+// Locations used by the userdata bootstrap script on the host.
+const (
+	userDataSetupFile = "/tmp/evergreen_setup.sh"
+	userDataLogFile   = "/tmp/evergreen_setup.log"
+	userDataHeredoc   = "EVERGREEN_SETUP_EOF"
+)
+
+// ExpandSetupScript returns the host's distro setup script with the global
+// expansions, and where applicable the host's credentials, filled in.
+// Callers are responsible for giving the host a secret beforehand.
+func ExpandSetupScript(h *host.Host, settings *evergreen.Settings) (string, error) {
+	exp := command.NewExpansions(settings.Expansions)
+	if h.Distro.UsesPullDispatch() || h.Distro.UsesUserDataProvisioning() {
+		exp.Put(HostIdExpansion, h.Id)
+		exp.Put(HostSecretExpansion, h.Secret)
+		exp.Put(APIServerExpansion, settings.ApiUrl)
+		exp.Put(PluginDirExpansion, h.Distro.PluginDir)
+	}
+
+	setupScript, err := exp.ExpandString(h.Distro.Setup)
+	if err != nil {
+		return "", fmt.Errorf("expansions error: %v", err)
+	}
+	return setupScript, nil
+}
+
+// MakeUserDataScript builds the script passed to the cloud provider as
+// userdata for the given host. It runs the distro setup script and
+// reports the outcome, along with the setup output, to the API server's
+// host ready callback.
+func MakeUserDataScript(h *host.Host, settings *evergreen.Settings) (string, error) {
+	if h.Secret == "" {
+		return "", fmt.Errorf("host %v has no secret to report its setup with", h.Id)
+	}
+
+	setup, err := ExpandSetupScript(h, settings)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(setup, userDataHeredoc) {
+		return "", fmt.Errorf("setup script may not contain '%v'", userDataHeredoc)
+	}
+
+	runSetup := fmt.Sprintf("sh %v", userDataSetupFile)
+	if !h.Distro.SetupAsSudo && h.Distro.User != "" {
+		runSetup = fmt.Sprintf("su - '%v' -c 'sh %v'", h.Distro.User, userDataSetupFile)
+	}
+
+	// the host is identified by the id it had when the script was made;
+	// the API server also accepts it once the provider assigns a new id
+	readyURL := fmt.Sprintf("%v/api/2/host/%v/ready", settings.ApiUrl, h.Id)
+
+	script := []string{
+		"#!/bin/bash",
+		fmt.Sprintf("cat > %v <<'%v'", userDataSetupFile, userDataHeredoc),
+		setup,
+		userDataHeredoc,
+		fmt.Sprintf("chmod 755 %v", userDataSetupFile),
+		fmt.Sprintf("if %v > %v 2>&1; then", runSetup, userDataLogFile),
+		fmt.Sprintf("  status=%v", evergreen.HostStatusSuccess),
+		"else",
+		fmt.Sprintf("  status=%v", evergreen.HostStatusFailed),
+		"fi",
+		fmt.Sprintf("curl -s --retry 10 -X POST -H '%v: %v' -H '%v: %v' --data-binary @%v %v/$status",
+			evergreen.HostHeader, h.Id, evergreen.HostSecretHeader, h.Secret,
+			userDataLogFile, readyURL),
+	}
+	return strings.Join(script, "\n") + "\n", nil
+}
@@ -0,0 +1,50 @@
+package cloud
+
+import (
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestMakeUserDataScript(t *testing.T) {
+	Convey("With a host of a userdata-provisioned distro", t, func() {
+		settings := &evergreen.Settings{
+			ApiUrl:     "http://evergreen.example.com",
+			Expansions: map[string]string{"greeting": "hello"},
+		}
+		h := &host.Host{
+			Id:     "h1",
+			Secret: "s3cr3t",
+			Distro: distro.Distro{
+				Setup:         "echo ${greeting} ${host_id} ${host_secret}",
+				User:          "admin",
+				ProvisionMode: distro.ProvisionModeUserData,
+			},
+		}
+
+		Convey("the script should run the expanded setup and report to the API server", func() {
+			script, err := MakeUserDataScript(h, settings)
+			So(err, ShouldBeNil)
+			So(script, ShouldContainSubstring, "echo hello h1 s3cr3t")
+			So(script, ShouldContainSubstring, "su - 'admin'")
+			So(script, ShouldContainSubstring,
+				"http://evergreen.example.com/api/2/host/h1/ready/$status")
+			So(script, ShouldContainSubstring, evergreen.HostSecretHeader+": s3cr3t")
+		})
+
+		Convey("setup should run as root if the distro sets up as sudo", func() {
+			h.Distro.SetupAsSudo = true
+			script, err := MakeUserDataScript(h, settings)
+			So(err, ShouldBeNil)
+			So(script, ShouldNotContainSubstring, "su - ")
+		})
+
+		Convey("a host without a secret should be rejected", func() {
+			h.Secret = ""
+			_, err := MakeUserDataScript(h, settings)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -111,8 +111,14 @@ type RunnerConfig struct {
 
 // HostInitConfig holds logging settings for the hostinit process.
 type HostInitConfig struct {
-	LogFile           string
-	SSHTimeoutSeconds int64
+	LogFile                 string
+	SSHTimeoutSeconds       int64
+	UserDataTimeoutSeconds  int64
+	ProvisionTimeoutSeconds int64
+	// the most hosts set up at once, across all distros, and for each
+	// distro that doesn't set its own limit
+	MaxConcurrentSetups    int
+	DistroConcurrentSetups int
 }
 
 // NotifyConfig hold logging and email settings for the notify package.
@@ -185,33 +191,47 @@ type AlertsConfig struct {
 	SMTP    *SMTPConfig `yaml:"smtp"`
 }
 
+// HostKey registers a new version of one of the named keypairs in Keys.
+// The path in Keys is version 0 of the key; registering a higher version
+// makes it the key that new hosts are spawned with and that running hosts
+// are rotated onto. KeyPair is the name the cloud provider knows the
+// version's public key by; providers that can't be told it spawn hosts with
+// the keypair in the distro's settings, as version 0.
+type HostKey struct {
+	Version int    `yaml:"version"`
+	Path    string `yaml:"path"`
+	KeyPair string `yaml:"key_pair"`
+}
+
 // Settings contains all configuration settings for running Evergreen.
 type Settings struct {
-	DbUrl               string            `yaml:"dburl"`
-	Db                  string            `yaml:"db"`
-	WriteConcern        WriteConcern      `yaml:"write_concern"`
-	ConfigDir           string            `yaml:"configdir"`
-	ApiUrl              string            `yaml:"api_url"`
-	AgentExecutablesDir string            `yaml:"agentexecutablesdir"`
-	SuperUsers          []string          `yaml:"superusers"`
-	Jira                JiraConfig        `yaml:"jira"`
-	Providers           CloudProviders    `yaml:"providers"`
-	Keys                map[string]string `yaml:"keys"`
-	Credentials         map[string]string `yaml:"credentials"`
-	AuthConfig          AuthConfig        `yaml:"auth"`
-	RepoTracker         RepoTrackerConfig `yaml:"repotracker"`
-	Monitor             MonitorConfig     `yaml:"monitor"`
-	Api                 APIConfig         `yaml:"api"`
-	Alerts              AlertsConfig      `yaml:"alerts"`
-	Ui                  UIConfig          `yaml:"ui"`
-	HostInit            HostInitConfig    `yaml:"hostinit"`
-	Notify              NotifyConfig      `yaml:"notify"`
-	Runner              RunnerConfig      `yaml:"runner"`
-	Scheduler           SchedulerConfig   `yaml:"scheduler"`
-	TaskRunner          TaskRunnerConfig  `yaml:"taskrunner"`
-	Expansions          map[string]string `yaml:"expansions"`
-	Plugins             PluginConfig      `yaml:"plugins"`
-	IsProd              bool              `yaml:"isprod"`
+	DbUrl               string               `yaml:"dburl"`
+	Db                  string               `yaml:"db"`
+	WriteConcern        WriteConcern         `yaml:"write_concern"`
+	ConfigDir           string               `yaml:"configdir"`
+	ApiUrl              string               `yaml:"api_url"`
+	AgentExecutablesDir string               `yaml:"agentexecutablesdir"`
+	SuperUsers          []string             `yaml:"superusers"`
+	Jira                JiraConfig           `yaml:"jira"`
+	Providers           CloudProviders       `yaml:"providers"`
+	Keys                map[string]string    `yaml:"keys"`
+	KeyVersions         map[string][]HostKey `yaml:"key_versions"`
+	Credentials         map[string]string    `yaml:"credentials"`
+	AuthConfig          AuthConfig           `yaml:"auth"`
+	RepoTracker         RepoTrackerConfig    `yaml:"repotracker"`
+	Monitor             MonitorConfig        `yaml:"monitor"`
+	Api                 APIConfig            `yaml:"api"`
+	Alerts              AlertsConfig         `yaml:"alerts"`
+	Ui                  UIConfig             `yaml:"ui"`
+	HostInit            HostInitConfig       `yaml:"hostinit"`
+	Notify              NotifyConfig         `yaml:"notify"`
+	Runner              RunnerConfig         `yaml:"runner"`
+	Scheduler           SchedulerConfig      `yaml:"scheduler"`
+	TaskRunner          TaskRunnerConfig     `yaml:"taskrunner"`
+	Expansions          map[string]string    `yaml:"expansions"`
+	Plugins             PluginConfig         `yaml:"plugins"`
+	ExternalPluginsDir  string               `yaml:"external_plugins_dir"`
+	IsProd              bool                 `yaml:"isprod"`
 }
 
 // NewSettings builds an in-memory representation of the given settings file.
@@ -240,6 +260,45 @@ func (settings *Settings) Validate(validators []ConfigValidator) error {
 	return nil
 }
 
+// CurrentKeyVersion returns the newest registered version of the named key.
+func (settings *Settings) CurrentKeyVersion(name string) int {
+	current := 0
+	for _, key := range settings.KeyVersions[name] {
+		if key.Version > current {
+			current = key.Version
+		}
+	}
+	return current
+}
+
+// SpawnKeyPair returns the current version of the named key and the name
+// the cloud provider knows it by, for spawning new hosts with. It returns
+// an empty name if the current version has none, in which case hosts are
+// spawned with the keypair in their distro's settings.
+func (settings *Settings) SpawnKeyPair(name string) (int, string) {
+	current := settings.CurrentKeyVersion(name)
+	for _, key := range settings.KeyVersions[name] {
+		if key.Version == current && key.KeyPair != "" {
+			return current, key.KeyPair
+		}
+	}
+	return 0, ""
+}
+
+// KeyPath returns the local path to the private key file for the given
+// version of the named key, or an empty string if it isn't registered.
+func (settings *Settings) KeyPath(name string, version int) string {
+	if version == 0 {
+		return settings.Keys[name]
+	}
+	for _, key := range settings.KeyVersions[name] {
+		if key.Version == version {
+			return key.Path
+		}
+	}
+	return ""
+}
+
 // GetSettingsOrExit loads the evergreen settings file,
 // or exits with a non-0 code if any errors occur.
 func GetSettingsOrExit() *Settings {
@@ -344,6 +403,8 @@ var ConfigValidationRules = []ConfigValidator{
 		return nil
 	},
 
+	validateKeyVersions,
+
 	func(settings *Settings) error {
 		if settings.AuthConfig.Crowd == nil && settings.AuthConfig.Naive == nil && settings.AuthConfig.Github == nil {
 			return fmt.Errorf("You must specify one form of authentication")
@@ -365,3 +426,26 @@ var ConfigValidationRules = []ConfigValidator{
 		return nil
 	},
 }
+
+// validateKeyVersions checks that registered key versions are well formed.
+func validateKeyVersions(settings *Settings) error {
+	for name, keys := range settings.KeyVersions {
+		if _, ok := settings.Keys[name]; !ok {
+			return fmt.Errorf("Key '%v' has versions but is not in keys", name)
+		}
+		versions := map[int]bool{}
+		for _, key := range keys {
+			if key.Version <= 0 {
+				return fmt.Errorf("Versions of key '%v' must be positive", name)
+			}
+			if versions[key.Version] {
+				return fmt.Errorf("Duplicate version %v of key '%v'", key.Version, name)
+			}
+			if key.Path == "" {
+				return fmt.Errorf("Version %v of key '%v' must have a path", key.Version, name)
+			}
+			versions[key.Version] = true
+		}
+	}
+	return nil
+}
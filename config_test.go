@@ -21,3 +21,55 @@ func TestBadInit(t *testing.T) {
 		So(err, ShouldNotBeNil)
 	})
 }
+
+func TestKeyVersions(t *testing.T) {
+	Convey("With a settings file that registers versions of a key", t, func() {
+		settings, err := NewSettings("testdata/mci_settings.yml")
+		So(err, ShouldBeNil)
+
+		Convey("the current version should be the highest one", func() {
+			So(settings.CurrentKeyVersion("mci"), ShouldEqual, 2)
+			So(settings.CurrentKeyVersion("other"), ShouldEqual, 0)
+		})
+
+		Convey("version 0 should be the key's original path", func() {
+			So(settings.KeyPath("mci", 0), ShouldEqual, "/data/home/etc/mci.pem")
+			So(settings.KeyPath("mci", 1), ShouldEqual, "/data/home/etc/mci_1.pem")
+			So(settings.KeyPath("mci", 3), ShouldEqual, "")
+		})
+
+		Convey("new hosts should be spawned with the current version's keypair", func() {
+			version, keyPair := settings.SpawnKeyPair("mci")
+			So(version, ShouldEqual, 2)
+			So(keyPair, ShouldEqual, "mci_2")
+
+			// without a keypair, the distro's own is used
+			settings.KeyVersions["mci"][1].KeyPair = ""
+			version, keyPair = settings.SpawnKeyPair("mci")
+			So(version, ShouldEqual, 0)
+			So(keyPair, ShouldEqual, "")
+		})
+
+		Convey("valid versions should pass validation", func() {
+			So(validateKeyVersions(settings), ShouldBeNil)
+		})
+
+		Convey("duplicate versions should be rejected", func() {
+			settings.KeyVersions["mci"] = append(settings.KeyVersions["mci"],
+				HostKey{Version: 2, Path: "/data/home/etc/other.pem"})
+			So(validateKeyVersions(settings), ShouldNotBeNil)
+		})
+
+		Convey("versions must be positive and have a path", func() {
+			settings.KeyVersions["mci"] = []HostKey{{Version: 0, Path: "/a.pem"}}
+			So(validateKeyVersions(settings), ShouldNotBeNil)
+			settings.KeyVersions["mci"] = []HostKey{{Version: 1}}
+			So(validateKeyVersions(settings), ShouldNotBeNil)
+		})
+
+		Convey("versions of unknown keys should be rejected", func() {
+			settings.KeyVersions["other"] = []HostKey{{Version: 1, Path: "/a.pem"}}
+			So(validateKeyVersions(settings), ShouldNotBeNil)
+		})
+	})
+}
@@ -27,6 +27,19 @@ const (
 	HostStatusSuccess = "success"
 	HostStatusFailed  = "failed"
 
+	// reasons a host was terminated
+	TerminationReasonIdle             = "idle"
+	TerminationReasonExcess           = "excess"
+	TerminationReasonDecommissioned   = "decommissioned"
+	TerminationReasonProvisionTimeout = "provisioning timed out"
+	TerminationReasonProvisionFailed  = "provisioning failed"
+	TerminationReasonExpired          = "expired"
+	TerminationReasonRetired          = "retired"
+	TerminationReasonExternal         = "terminated externally"
+	TerminationReasonSpotFallback     = "spot fallback"
+	TerminationReasonUser             = "terminated by user"
+	TerminationReasonSpawnIdle        = "spawn host idle"
+
 	SpawnRequestInit       = "initializing"
 	SpawnRequestReady      = "ready"
 	SpawnRequestUnusable   = "unusable"
@@ -90,6 +103,24 @@ const (
 const (
 	AuthTokenCookie  = "mci-token"
 	TaskSecretHeader = "Task-Secret"
+	HostHeader       = "Host-Id"
+	HostSecretHeader = "Host-Secret"
+
+	// AgentProtocolHeader carries the protocol version an agent speaks on
+	// every request it makes to the API server.
+	AgentProtocolHeader = "Agent-Protocol-Version"
+)
+
+// The protocol spoken between agents and the API server. AgentProtocolVersion
+// must be bumped whenever the API server or the agent changes in a way the
+// other side has to know about, and MinAgentProtocolVersion raised to it when
+// older agents can no longer work with the API server. The API server rejects
+// agents outside of this range, which then exit so that the host can be given
+// a new agent. Agents from before the protocol was versioned speak version 0,
+// and are still supported so that deploying doesn't strand running tasks.
+const (
+	AgentProtocolVersion    = 2
+	MinAgentProtocolVersion = 0
 )
 
 var (
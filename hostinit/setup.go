@@ -8,12 +8,13 @@ import (
 	"github.com/evergreen-ci/evergreen/alerts"
 	"github.com/evergreen-ci/evergreen/cloud"
 	"github.com/evergreen-ci/evergreen/cloud/providers"
-	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/event"
 	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/evergreen-ci/evergreen/notify"
 	"github.com/evergreen-ci/evergreen/remote"
 	"github.com/evergreen-ci/evergreen/util"
+	"golang.org/x/crypto/ssh"
 	"gopkg.in/mgo.v2"
 	"sync"
 	"time"
@@ -29,19 +30,60 @@ var (
 	SSHTimeoutSeconds = int64(300) // 5 minutes
 )
 
+// Longest duration a userdata-provisioned host may take to report the
+// result of its setup, counted from when it was created.
+var (
+	UserDataTimeoutSeconds = int64(1800) // 30 minutes
+)
+
+// Longest duration allowed for an attempt at setting up a host, from
+// checking that it's ready to running its setup script. An attempt that
+// hasn't finished after twice this long is taken to have been abandoned by a
+// hostinit that stopped partway, and is resumed.
+var (
+	ProvisionTimeoutSeconds = int64(900) // 15 minutes
+)
+
+// Most hosts set up at once, across all distros, and for each distro that
+// doesn't set its own limit.
+var (
+	MaxConcurrentSetups    = 64
+	DistroConcurrentSetups = 16
+)
+
+// The setups in progress for each distro. They are kept across hostinit
+// runs, since a setup can outlast the run that started it.
+var distroSetupSlots = newSetupSlots()
+
+const (
+	// the most attempts made at setting up a host before giving up on it
+	MaxProvisionAttempts = 3
+
+	// the file left in the home directory of a host's user once its setup
+	// script succeeds, so a resumed setup knows not to run it again
+	SetupCompleteMarker = ".evergreen-setup-complete"
+
+	// how long to wait when checking a host for the setup complete marker
+	setupCompleteCheckTimeout = 30 * time.Second
+)
+
 // HostInit is responsible for running setup scripts on Evergreen hosts.
 type HostInit struct {
 	Settings *evergreen.Settings
 }
 
-// setupReadyHosts runs the distro setup script of all hosts that are up and reachable.
+// setupJob is a host to be set up by one of the hostinit workers. Resumed
+// jobs are for hosts whose last setup attempt was abandoned.
+type setupJob struct {
+	host   host.Host
+	resume bool
+}
+
+// setupReadyHosts runs the distro setup script of all hosts that are up and
+// reachable, using a bounded pool of workers. Hosts whose setup attempt was
+// abandoned are resumed.
 func (init *HostInit) setupReadyHosts() error {
-	// set SSH timeout duration
-	if timeoutSecs := init.Settings.HostInit.SSHTimeoutSeconds; timeoutSecs <= 0 {
-		evergreen.Logger.Logf(slogger.WARN, "SSH timeout set to %vs (<= 0s) using %vs instead", timeoutSecs, SSHTimeoutSeconds)
-	} else {
-		SSHTimeoutSeconds = timeoutSecs
-	}
+	init.loadSettings()
 
 	// find all hosts in the uninitialized state
 	uninitializedHosts, err := host.Find(host.IsUninitialized)
@@ -49,59 +91,266 @@ func (init *HostInit) setupReadyHosts() error {
 		return fmt.Errorf("error fetching uninitialized hosts: %v", err)
 	}
 
-	evergreen.Logger.Logf(slogger.DEBUG, "There are %v uninitialized hosts",
-		len(uninitializedHosts))
+	// find all hosts whose setup was abandoned partway
+	provisionTimeout := time.Duration(ProvisionTimeoutSeconds) * time.Second
+	abandonedHosts, err := host.Find(host.ByAbandonedInitializingSince(
+		time.Now().Add(-2 * provisionTimeout)))
+	if err != nil {
+		return fmt.Errorf("error fetching abandoned initializing hosts: %v", err)
+	}
 
-	// used for making sure we don't exit before a setup script is done
-	wg := &sync.WaitGroup{}
+	evergreen.Logger.Logf(slogger.DEBUG, "There are %v uninitialized hosts and %v"+
+		" hosts with abandoned setups", len(uninitializedHosts), len(abandonedHosts))
 
+	jobs := []setupJob{}
 	for _, h := range uninitializedHosts {
 
+		// replace hosts whose provider can't deliver an instance in time
+		replaced, err := init.fallBack(&h)
+		if err != nil {
+			evergreen.Logger.Logf(slogger.ERROR, "Error checking host %v for fallback: %v",
+				h.Id, err)
+			continue
+		}
+		if replaced {
+			continue
+		}
+
+		// hosts provisioned by userdata run their own setup, so all we
+		// need to do is make sure they report back in time
+		if h.Distro.UsesUserDataProvisioning() {
+			if err := init.checkUserDataTimeout(&h); err != nil {
+				evergreen.Logger.Logf(slogger.ERROR, "Error checking userdata setup of host %v: %v",
+					h.Id, err)
+			}
+			continue
+		}
+
+		jobs = append(jobs, setupJob{host: h})
+	}
+	for _, h := range abandonedHosts {
+		jobs = append(jobs, setupJob{host: h, resume: true})
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	// feed the jobs to the workers
+	jobChan := make(chan setupJob, len(jobs))
+	for _, job := range jobs {
+		jobChan <- job
+	}
+	close(jobChan)
+
+	workers := MaxConcurrentSetups
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	// used for making sure we don't exit before the workers are done
+	wg := &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				init.runSetupJob(job, distroSetupSlots)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// loadSettings applies any hostinit settings that override the defaults.
+func (init *HostInit) loadSettings() {
+	conf := init.Settings.HostInit
+
+	// set SSH timeout duration
+	if timeoutSecs := conf.SSHTimeoutSeconds; timeoutSecs <= 0 {
+		evergreen.Logger.Logf(slogger.WARN, "SSH timeout set to %vs (<= 0s) using %vs instead", timeoutSecs, SSHTimeoutSeconds)
+	} else {
+		SSHTimeoutSeconds = timeoutSecs
+	}
+	if timeoutSecs := conf.UserDataTimeoutSeconds; timeoutSecs > 0 {
+		UserDataTimeoutSeconds = timeoutSecs
+	}
+	if timeoutSecs := conf.ProvisionTimeoutSeconds; timeoutSecs > 0 {
+		ProvisionTimeoutSeconds = timeoutSecs
+	}
+	if conf.MaxConcurrentSetups > 0 {
+		MaxConcurrentSetups = conf.MaxConcurrentSetups
+	}
+	if conf.DistroConcurrentSetups > 0 {
+		DistroConcurrentSetups = conf.DistroConcurrentSetups
+	}
+}
+
+// runSetupJob sets up or resumes setting up the job's host, if its distro
+// isn't already setting up as many hosts as it may. Hosts skipped here are
+// picked up on a later run.
+func (init *HostInit) runSetupJob(job setupJob, slots *setupSlots) {
+	h := job.host
+
+	if !job.resume {
 		// check whether or not the host is ready for its setup script to be run
 		ready, err := init.IsHostReady(&h)
 		if err != nil {
 			evergreen.Logger.Logf(slogger.ERROR, "Error checking host %v for readiness: %v",
 				h.Id, err)
-			continue
+			return
 		}
 
 		// if the host isn't ready (for instance, it might not be up yet), skip it
 		if !ready {
 			evergreen.Logger.Logf(slogger.DEBUG, "Host %v not ready for setup", h.Id)
-			continue
+			return
 		}
+	}
 
+	if !slots.acquire(&h.Distro) {
+		evergreen.Logger.Logf(slogger.DEBUG, "Distro %v is setting up as many hosts as it"+
+			" may; host %v will wait", h.Distro.Id, h.Id)
+		return
+	}
+
+	provision := init.provisionHost
+	if job.resume {
+		evergreen.Logger.Logf(slogger.INFO, "Resuming abandoned setup of host %v", h.Id)
+		provision = init.resumeProvisioning
+	} else {
 		evergreen.Logger.Logf(slogger.INFO, "Running setup script for host %v", h.Id)
+	}
 
-		// kick off the setup, in its own goroutine, so pending setups don't have
-		// to wait for it to finish
-		wg.Add(1)
-		go func(h host.Host) {
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		defer slots.release(&h.Distro)
+		if err := provision(&h, stop); err != nil {
+			evergreen.Logger.Logf(slogger.ERROR, "Error provisioning host %v: %v",
+				h.Id, err)
+			init.notifyProvisionFailure(&h)
+		}
+	}()
+
+	// stop hosts that take too long, so they don't hold up the rest. the
+	// host is left initializing, so a later run resumes it as abandoned
+	timeout := time.Duration(ProvisionTimeoutSeconds) * time.Second
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		evergreen.Logger.Logf(slogger.WARN, "Setup of host %v did not finish within %v;"+
+			" stopping it", h.Id, timeout)
+		close(stop)
+		<-done
+	}
+}
 
-			if err := init.ProvisionHost(&h); err != nil {
-				evergreen.Logger.Logf(slogger.ERROR, "Error provisioning host %v: %v",
-					h.Id, err)
+// notifyProvisionFailure notifies the admins that the host failed to
+// provision.
+func (init *HostInit) notifyProvisionFailure(h *host.Host) {
+	subject := fmt.Sprintf("%v Evergreen provisioning failure on %v",
+		notify.ProvisionFailurePreface, h.Distro.Id)
+	hostLink := fmt.Sprintf("%v/host/%v", init.Settings.Ui.Url, h.Id)
+	message := fmt.Sprintf("Provisioning failed on %v host -- %v: see %v",
+		h.Distro.Id, h.Id, hostLink)
+	if err := notify.NotifyAdmins(subject, message, init.Settings); err != nil {
+		evergreen.Logger.Errorf(slogger.ERROR, "Error sending email: %v", err)
+	}
+}
 
-				// notify the admins of the failure
-				subject := fmt.Sprintf("%v Evergreen provisioning failure on %v",
-					notify.ProvisionFailurePreface, h.Distro.Id)
-				hostLink := fmt.Sprintf("%v/host/%v", init.Settings.Ui.Url, h.Id)
-				message := fmt.Sprintf("Provisioning failed on %v host -- %v: see %v",
-					h.Distro.Id, h.Id, hostLink)
-				if err := notify.NotifyAdmins(subject, message, init.Settings); err != nil {
-					evergreen.Logger.Errorf(slogger.ERROR, "Error sending email: %v", err)
-				}
-			}
+// setupSlots tracks how many hosts of each distro are being set up.
+type setupSlots struct {
+	sync.Mutex
+	inUse map[string]int
+}
 
-			wg.Done()
+func newSetupSlots() *setupSlots {
+	return &setupSlots{inUse: map[string]int{}}
+}
 
-		}(h)
+// acquire takes one of the distro's slots, returning false if they are all
+// in use.
+func (s *setupSlots) acquire(d *distro.Distro) bool {
+	s.Lock()
+	defer s.Unlock()
 
+	limit := d.SetupConcurrency
+	if limit <= 0 {
+		limit = DistroConcurrentSetups
 	}
+	if s.inUse[d.Id] >= limit {
+		return false
+	}
+	s.inUse[d.Id]++
+	return true
+}
 
-	// let all setup routines finish
-	wg.Wait()
+// release gives back one of the distro's slots.
+func (s *setupSlots) release(d *distro.Distro) {
+	s.Lock()
+	defer s.Unlock()
+	s.inUse[d.Id]--
+}
+
+// fallBack asks the host's cloud provider to replace the host, if the
+// provider supports it and the distro's policy calls for it. Returns true if
+// the host was replaced. Spawn hosts are left alone, since the spawn process
+// is watching them itself.
+func (init *HostInit) fallBack(h *host.Host) (bool, error) {
+	if h.UserHost {
+		return false, nil
+	}
+
+	cloudMgr, err := providers.GetCloudManager(h.Provider, init.Settings)
+	if err != nil {
+		return false,
+			fmt.Errorf("failed to get cloud manager for provider %v: %v", h.Provider, err)
+	}
+	fallbacker, ok := cloudMgr.(cloud.Fallbacker)
+	if !ok {
+		return false, nil
+	}
+
+	replacement, err := fallbacker.FallBack(h)
+	if err != nil {
+		return false, err
+	}
+	if replacement == nil {
+		return false, nil
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Host %v was replaced by host %v", h.Id, replacement.Id)
+	return true, nil
+}
+
+// checkUserDataTimeout marks a userdata-provisioned host as having failed
+// provisioning if it has not reported the result of its setup within
+// UserDataTimeoutSeconds of being created.
+func (init *HostInit) checkUserDataTimeout(h *host.Host) error {
+	timeout := time.Duration(UserDataTimeoutSeconds) * time.Second
+	if time.Now().Sub(h.CreationTime) < timeout {
+		return nil
+	}
+
+	evergreen.Logger.Logf(slogger.WARN, "Host %v did not report its setup result within %v",
+		h.Id, timeout)
 
+	alerts.RunHostProvisionFailTriggers(h)
+	event.LogProvisionFailed(h.Id, fmt.Sprintf("host did not report its setup result within %v", timeout))
+	if err := h.SetStatus(evergreen.HostProvisionFailed); err != nil {
+		return fmt.Errorf("error marking host as failed provisioning: %v", err)
+	}
+
+	subject := fmt.Sprintf("%v Evergreen provisioning failure on %v",
+		notify.ProvisionFailurePreface, h.Distro.Id)
+	hostLink := fmt.Sprintf("%v/host/%v", init.Settings.Ui.Url, h.Id)
+	message := fmt.Sprintf("Provisioning timed out on %v host -- %v: see %v",
+		h.Distro.Id, h.Id, hostLink)
+	if err := notify.NotifyAdmins(subject, message, init.Settings); err != nil {
+		evergreen.Logger.Errorf(slogger.ERROR, "Error sending email: %v", err)
+	}
 	return nil
 }
 
@@ -166,15 +415,7 @@ func (init *HostInit) IsHostReady(host *host.Host) (bool, error) {
 // setupHost runs the specified setup script for an individual host. Returns
 // the output from running the script remotely, as well as any error that
 // occurs. If the script exits with a non-zero exit code, the error will be non-nil.
-func (init *HostInit) setupHost(targetHost *host.Host) ([]byte, error) {
-
-	// fetch the appropriate cloud provider for the host
-	cloudMgr, err := providers.GetCloudManager(targetHost.Provider, init.Settings)
-	if err != nil {
-		return nil,
-			fmt.Errorf("failed to get cloud manager for host %v with provider %v: %v",
-				targetHost.Id, targetHost.Provider, err)
-	}
+func (init *HostInit) setupHost(targetHost *host.Host, stop <-chan bool) ([]byte, error) {
 
 	// mark the host as initializing
 	if err := targetHost.SetInitializing(); err != nil {
@@ -185,6 +426,23 @@ func (init *HostInit) setupHost(targetHost *host.Host) ([]byte, error) {
 		}
 	}
 
+	return init.runSetup(targetHost, stop)
+}
+
+// runSetup runs the setup script of a host that has been marked as
+// initializing, as the host's current setup attempt. The script is killed
+// if stop is closed.
+func (init *HostInit) runSetup(targetHost *host.Host, stop <-chan bool) ([]byte, error) {
+	attempt := targetHost.ProvisionAttempts
+
+	// fetch the appropriate cloud provider for the host
+	cloudMgr, err := providers.GetCloudManager(targetHost.Provider, init.Settings)
+	if err != nil {
+		return nil,
+			fmt.Errorf("failed to get cloud manager for host %v with provider %v: %v",
+				targetHost.Id, targetHost.Provider, err)
+	}
+
 	// run the function scheduled for when the host is up
 	err = cloudMgr.OnUp(targetHost)
 	if err != nil {
@@ -192,28 +450,20 @@ func (init *HostInit) setupHost(targetHost *host.Host) ([]byte, error) {
 		evergreen.Logger.Logf(slogger.WARN, "OnUp callback failed for host '%v': '%v'", targetHost.Id, err)
 	}
 
-	// get the local path to the SSH keyfile, if not specified
-	keyfile := init.Settings.Keys[targetHost.Distro.SSHKey]
-
 	// run the remote setup script as sudo, if appropriate
 	sudoStr := ""
 	if targetHost.Distro.SetupAsSudo {
 		sudoStr = "sudo "
 	}
 
-	// parse the hostname into the user, host and port
-	hostInfo, err := util.ParseSSHInfo(targetHost.Host)
+	hostPort, user, keyfile, err := init.sshTarget(targetHost)
 	if err != nil {
 		return nil, err
 	}
-	user := targetHost.Distro.User
-	if hostInfo.User != "" {
-		user = hostInfo.User
-	}
 
 	// initialize a gateway for creating the script on the remote machine
 	gateway := &remote.SFTPGateway{
-		Host:    hostInfo.Hostname + ":" + hostInfo.Port,
+		Host:    hostPort,
 		User:    user,
 		Keyfile: keyfile,
 	}
@@ -241,13 +491,24 @@ func (init *HostInit) setupHost(targetHost *host.Host) ([]byte, error) {
 		return nil, fmt.Errorf("error writing remote setup script: %v", err)
 	}
 
-	// set up remote running of the script
+	// if this attempt took so long that it was resumed by another, leave
+	// the setup to that one
+	current, err := targetHost.HasProvisionAttempt(attempt)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if !current {
+		return nil, ErrHostAlreadyInitializing
+	}
+
+	// set up remote running of the script, marking the host once it succeeds
 	script := &remote.SSHCommand{
-		Command: sudoStr + "sh " + remoteFileName,
-		Host:    hostInfo.Hostname + ":" + hostInfo.Port,
+		Command: sudoStr + "sh " + remoteFileName + " && touch " + SetupCompleteMarker,
+		Host:    hostPort,
 		User:    user,
 		Keyfile: keyfile,
 		Timeout: time.Duration(SSHTimeoutSeconds) * time.Second,
+		Stop:    stop,
 	}
 
 	// run the setup script
@@ -255,22 +516,115 @@ func (init *HostInit) setupHost(targetHost *host.Host) ([]byte, error) {
 
 }
 
+// sshTarget returns the address, user and local key file to use to connect
+// to the host over SSH.
+func (init *HostInit) sshTarget(h *host.Host) (string, string, string, error) {
+	// parse the hostname into the user, host and port
+	hostInfo, err := util.ParseSSHInfo(h.Host)
+	if err != nil {
+		return "", "", "", err
+	}
+	user := h.Distro.User
+	if hostInfo.User != "" {
+		user = hostInfo.User
+	}
+
+	// get the local path to the SSH keyfile, if not specified
+	keyfile := init.Settings.KeyPath(h.Distro.SSHKey, h.KeyVersion)
+
+	return hostInfo.Hostname + ":" + hostInfo.Port, user, keyfile, nil
+}
+
+// setupCompleted returns whether the host's setup script has already
+// succeeded, by checking for the marker it leaves behind.
+func (init *HostInit) setupCompleted(h *host.Host) (bool, error) {
+	hostPort, user, keyfile, err := init.sshTarget(h)
+	if err != nil {
+		return false, err
+	}
+	check := &remote.SSHCommand{
+		Command: "test -f " + SetupCompleteMarker,
+		Host:    hostPort,
+		User:    user,
+		Keyfile: keyfile,
+		Timeout: setupCompleteCheckTimeout,
+	}
+	_, err = check.Run()
+	if err == nil {
+		return true, nil
+	}
+	if _, ok := err.(*ssh.ExitError); ok {
+		return false, nil
+	}
+	return false, err
+}
+
 // Build the setup script that will need to be run on the specified host.
 func (init *HostInit) buildSetupScript(h *host.Host) (string, error) {
-	// replace expansions in the script
-	exp := command.NewExpansions(init.Settings.Expansions)
-	setupScript, err := exp.ExpandString(h.Distro.Setup)
-	if err != nil {
-		return "", fmt.Errorf("expansions error: %v", err)
+	// hosts of pull-dispatch distros must start a long-lived agent as part
+	// of their setup, so make sure they have a secret to authenticate with
+	if h.Distro.UsesPullDispatch() && h.Secret == "" {
+		if err := h.SetSecret(util.RandomString()); err != nil {
+			return "", fmt.Errorf("error setting secret: %v", err)
+		}
 	}
-	return setupScript, err
+	return cloud.ExpandSetupScript(h, init.Settings)
 }
 
 // Provision the host, and update the database accordingly.
 func (init *HostInit) ProvisionHost(h *host.Host) error {
+	return init.provisionHost(h, nil)
+}
+
+// provisionHost provisions the host, stopping its setup script if stop is
+// closed.
+func (init *HostInit) provisionHost(h *host.Host, stop <-chan bool) error {
 
 	// run the setup script
-	output, err := init.setupHost(h)
+	output, err := init.setupHost(h, stop)
+
+	return init.finishProvisioning(h, output, err)
+}
+
+// resumeProvisioning starts a new attempt at setting up a host whose last
+// attempt was abandoned. The setup script is only run again if it didn't
+// succeed the last time, and hosts are given up on after
+// MaxProvisionAttempts.
+func (init *HostInit) resumeProvisioning(h *host.Host, stop <-chan bool) error {
+	if h.ProvisionAttempts >= MaxProvisionAttempts {
+		evergreen.Logger.Logf(slogger.WARN, "Giving up on setting up host %v after %v attempts",
+			h.Id, h.ProvisionAttempts)
+		return init.finishProvisioning(h, nil, fmt.Errorf("setup was abandoned %v times",
+			h.ProvisionAttempts))
+	}
+
+	if err := h.ResumeInitializing(); err != nil {
+		if err == mgo.ErrNotFound {
+			evergreen.Logger.Logf(slogger.DEBUG,
+				"Abandoned setup of host %v was already resumed", h.Id)
+			return nil
+		}
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	completed, err := init.setupCompleted(h)
+	if err != nil {
+		evergreen.Logger.Logf(slogger.WARN, "Error checking whether host %v finished its"+
+			" setup; running it again: %v", h.Id, err)
+	}
+	if completed {
+		evergreen.Logger.Logf(slogger.INFO, "Host %v finished its setup before it was"+
+			" abandoned", h.Id)
+		return init.finishProvisioning(h, nil, nil)
+	}
+
+	output, err := init.runSetup(h, stop)
+	return init.finishProvisioning(h, output, err)
+}
+
+// finishProvisioning updates the database with the result of an attempt at
+// setting up the host.
+func (init *HostInit) finishProvisioning(h *host.Host, output []byte, err error) error {
 
 	// deal with any errors that occured while running the setup
 	if err != nil {
@@ -284,6 +638,21 @@ func (init *HostInit) ProvisionHost(h *host.Host) error {
 			return nil
 		}
 
+		// the setup took too long and was stopped; it is resumed once it
+		// counts as abandoned
+		if err == remote.ErrCmdStopped {
+			evergreen.Logger.Logf(slogger.WARN, "Setup of host %v was stopped", h.Id)
+			return nil
+		}
+
+		// a newer attempt has taken over, so this one's failure doesn't count
+		current, dbErr := h.HasProvisionAttempt(h.ProvisionAttempts)
+		if dbErr == nil && !current {
+			evergreen.Logger.Logf(slogger.WARN, "Ignoring failed setup of host %v:"+
+				" attempt %v is no longer current", h.Id, h.ProvisionAttempts)
+			return nil
+		}
+
 		// log the provisioning failure
 		setupLog := ""
 		if output != nil {
@@ -301,9 +670,16 @@ func (init *HostInit) ProvisionHost(h *host.Host) error {
 
 	}
 
-	// the setup was successful. update the host accordingly in the database
-	if err := h.MarkAsProvisioned(); err != nil {
-		return fmt.Errorf("error marking host %v as provisioned: %v", err)
+	// the setup was successful. update the host accordingly in the database,
+	// unless it was given up on since
+	marked, err := h.MarkAsProvisionedIfCurrent()
+	if err != nil {
+		return fmt.Errorf("error marking host %v as provisioned: %v", h.Id, err)
+	}
+	if !marked {
+		evergreen.Logger.Logf(slogger.WARN, "Not marking host %v as provisioned:"+
+			" it is no longer %v", h.Id, h.Status)
+		return nil
 	}
 
 	evergreen.Logger.Logf(slogger.INFO, "Host %v successfully provisioned", h.Id)
@@ -0,0 +1,34 @@
+package hostinit
+
+import (
+	"github.com/evergreen-ci/evergreen/model/distro"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestSetupSlots(t *testing.T) {
+	Convey("When limiting how many hosts are set up at once", t, func() {
+		slots := newSetupSlots()
+
+		Convey("a distro's own limit should be used", func() {
+			d := &distro.Distro{Id: "d1", SetupConcurrency: 2}
+			So(slots.acquire(d), ShouldBeTrue)
+			So(slots.acquire(d), ShouldBeTrue)
+			So(slots.acquire(d), ShouldBeFalse)
+
+			// other distros are unaffected
+			So(slots.acquire(&distro.Distro{Id: "d2", SetupConcurrency: 1}), ShouldBeTrue)
+
+			slots.release(d)
+			So(slots.acquire(d), ShouldBeTrue)
+		})
+
+		Convey("distros without a limit should get the default", func() {
+			d := &distro.Distro{Id: "d1"}
+			for i := 0; i < DistroConcurrentSetups; i++ {
+				So(slots.acquire(d), ShouldBeTrue)
+			}
+			So(slots.acquire(d), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package hostutil
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/util"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// activityCommand returns a shell command that prints the number of signs
+// of a user on the host: terminals that were read from or written to in the
+// given window, open login sessions, and the user's processes that are
+// running, other than the ones of the check itself. A user who left a
+// build running or is still logged in isn't idle, even if their terminals
+// have been quiet.
+func activityCommand(window time.Duration) string {
+	minutes := int(math.Ceil(window.Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	return strings.Join([]string{
+		"terminals=0",
+		fmt.Sprintf("if [ -d /dev/pts ]; then terminals=$(find /dev/pts -mindepth 1 -maxdepth 1"+
+			" \\( -amin -%v -o -mmin -%v \\) | wc -l); fi", minutes, minutes),
+		"sessions=0",
+		"if command -v who > /dev/null; then sessions=$(who | wc -l); fi",
+		"session=$(ps -o sid= -p $$ | tr -d ' ')",
+		"processes=$(ps -u \"$(id -u)\" -o sid=,stat= |" +
+			" awk -v sid=\"$session\" '$1 != sid && $2 ~ /^[RD]/' | wc -l)",
+		"echo $((terminals + sessions + processes))",
+	}, "\n")
+}
+
+// parseActivity interprets the output of the activity command.
+func parseActivity(output string) (bool, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return false, fmt.Errorf("activity check printed nothing")
+	}
+	count, err := strconv.Atoi(output)
+	if err != nil {
+		return false, fmt.Errorf("unexpected output from activity check: %v", output)
+	}
+	return count > 0, nil
+}
+
+// CheckUserActivity runs a command over SSH to check whether a user has used
+// the host in the given window, or is still using it. Returns an error if the
+// host can't be checked, in which case the user's activity is unknown.
+func CheckUserActivity(hostObject *host.Host, sshOptions []string, window time.Duration) (bool, error) {
+	hostInfo, err := util.ParseSSHInfo(hostObject.Host)
+	if err != nil {
+		return false, err
+	}
+
+	if hostInfo.User == "" {
+		hostInfo.User = hostObject.User
+	}
+
+	output := &bytes.Buffer{}
+	remoteCommand := &command.RemoteCommand{
+		CmdString:      activityCommand(window),
+		Stdout:         output,
+		Stderr:         ioutil.Discard,
+		RemoteHostName: hostInfo.Hostname,
+		User:           hostInfo.User,
+		Options:        append([]string{"-p", hostInfo.Port}, sshOptions...),
+		Background:     false,
+	}
+
+	done := make(chan error)
+	if err = remoteCommand.Start(); err != nil {
+		return false, err
+	}
+
+	go func() {
+		done <- remoteCommand.Wait()
+	}()
+
+	select {
+	case <-time.After(HostCheckTimeout):
+		remoteCommand.Stop()
+		return false, fmt.Errorf("activity check timed out after %v", HostCheckTimeout)
+	case err = <-done:
+		if err != nil {
+			return false, fmt.Errorf("error running activity check: %v", err)
+		}
+		return parseActivity(output.String())
+	}
+}
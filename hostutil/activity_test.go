@@ -0,0 +1,40 @@
+package hostutil
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestActivityCheck(t *testing.T) {
+	Convey("When checking a host for user activity", t, func() {
+
+		Convey("the window should be rounded up to whole minutes", func() {
+			So(activityCommand(90*time.Second), ShouldContainSubstring, "-amin -2 -o -mmin -2")
+			So(activityCommand(0), ShouldContainSubstring, "-amin -1 -o -mmin -1")
+		})
+
+		Convey("login sessions and running processes should be checked too", func() {
+			cmd := activityCommand(time.Minute)
+			So(cmd, ShouldContainSubstring, "who | wc -l")
+			So(cmd, ShouldContainSubstring, "ps -u")
+		})
+
+		Convey("any recently used terminal should count as activity", func() {
+			active, err := parseActivity("2\n")
+			So(err, ShouldBeNil)
+			So(active, ShouldBeTrue)
+
+			active, err = parseActivity("0\n")
+			So(err, ShouldBeNil)
+			So(active, ShouldBeFalse)
+		})
+
+		Convey("output that can't be interpreted should be an error", func() {
+			_, err := parseActivity("")
+			So(err, ShouldNotBeNil)
+			_, err = parseActivity("find: not found")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package hostutil
+
+import (
+	"fmt"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/remote"
+	"github.com/evergreen-ci/evergreen/util"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+const (
+	// how long to wait for each command run while rotating a host's key
+	KeyRotationTimeout = time.Minute
+
+	authorizedKeysFile = "~/.ssh/authorized_keys"
+)
+
+// AuthorizedKey returns the authorized_keys entry for the public half of
+// the PEM-encoded private key in the given file.
+func AuthorizedKey(keyfile string) (string, error) {
+	keyBytes, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		return "", fmt.Errorf("error reading private key file `%v`: %v", keyfile, err)
+	}
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	if err != nil {
+		return "", fmt.Errorf("error parsing private key from file `%v`: %v", keyfile, err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))), nil
+}
+
+// authorizeKeyCommand returns a shell command that adds the given entry to
+// the authorized keys, unless it's already there.
+func authorizeKeyCommand(authorizedKey string) string {
+	return fmt.Sprintf("mkdir -p ~/.ssh && chmod 700 ~/.ssh && (grep -qF '%v' %v 2>/dev/null"+
+		" || echo '%v' >> %v) && chmod 600 %v", authorizedKey, authorizedKeysFile,
+		authorizedKey, authorizedKeysFile, authorizedKeysFile)
+}
+
+// revokeKeyCommand returns a shell command that removes every line with the
+// given entry from the authorized keys. The key used to connect must stay
+// authorized, so the file is never left empty.
+func revokeKeyCommand(authorizedKey string) string {
+	return fmt.Sprintf("grep -vF '%v' %v > %v.tmp && chmod 600 %v.tmp && mv %v.tmp %v",
+		authorizedKey, authorizedKeysFile, authorizedKeysFile, authorizedKeysFile,
+		authorizedKeysFile, authorizedKeysFile)
+}
+
+// AuthorizeKey connects to the host with the key in keyfile, and authorizes
+// the key in newKeyfile for the host's distro user.
+func AuthorizeKey(h *host.Host, keyfile, newKeyfile string) error {
+	authorizedKey, err := AuthorizedKey(newKeyfile)
+	if err != nil {
+		return err
+	}
+	if err := runKeyCommand(h, keyfile, authorizeKeyCommand(authorizedKey)); err != nil {
+		return fmt.Errorf("error authorizing new key: %v", err)
+	}
+	// make sure the new key is accepted before anything comes to rely on it
+	if err := runKeyCommand(h, newKeyfile, "true"); err != nil {
+		return fmt.Errorf("error connecting with new key: %v", err)
+	}
+	return nil
+}
+
+// RevokeKey connects to the host with the key in keyfile, and removes the
+// key in oldKeyfile from the keys authorized for the host's distro user.
+func RevokeKey(h *host.Host, keyfile, oldKeyfile string) error {
+	authorizedKey, err := AuthorizedKey(oldKeyfile)
+	if err != nil {
+		return err
+	}
+	if err := runKeyCommand(h, keyfile, revokeKeyCommand(authorizedKey)); err != nil {
+		return fmt.Errorf("error revoking old key: %v", err)
+	}
+	return nil
+}
+
+// runKeyCommand runs the command on the host as its distro user.
+func runKeyCommand(h *host.Host, keyfile, command string) error {
+	hostInfo, err := util.ParseSSHInfo(h.Host)
+	if err != nil {
+		return err
+	}
+	user := h.Distro.User
+	if hostInfo.User != "" {
+		user = hostInfo.User
+	}
+	cmd := &remote.SSHCommand{
+		Command: command,
+		Host:    hostInfo.Hostname + ":" + hostInfo.Port,
+		User:    user,
+		Keyfile: keyfile,
+		Timeout: KeyRotationTimeout,
+	}
+	output, err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%v (output: %v)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package hostutil
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestKeyRotationCommands(t *testing.T) {
+	Convey("When rotating a host's key", t, func() {
+
+		Convey("the authorized key should be derived from the private key", func() {
+			privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+			So(err, ShouldBeNil)
+			keyfile, err := ioutil.TempFile("", "hostkey")
+			So(err, ShouldBeNil)
+			defer os.Remove(keyfile.Name())
+			So(pem.Encode(keyfile, &pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+			}), ShouldBeNil)
+			So(keyfile.Close(), ShouldBeNil)
+
+			authorizedKey, err := AuthorizedKey(keyfile.Name())
+			So(err, ShouldBeNil)
+			So(strings.HasPrefix(authorizedKey, "ssh-rsa "), ShouldBeTrue)
+			So(authorizedKey, ShouldNotContainSubstring, "\n")
+		})
+
+		Convey("a missing key file should be an error", func() {
+			_, err := AuthorizedKey("/this/key/does/not/exist")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("the new key should only be added if it isn't there", func() {
+			cmd := authorizeKeyCommand("ssh-rsa AAAA")
+			So(cmd, ShouldContainSubstring, "grep -qF 'ssh-rsa AAAA' ~/.ssh/authorized_keys")
+			So(cmd, ShouldContainSubstring, "|| echo 'ssh-rsa AAAA' >> ~/.ssh/authorized_keys")
+		})
+
+		Convey("the old key should be filtered out of the file", func() {
+			cmd := revokeKeyCommand("ssh-rsa AAAA")
+			So(cmd, ShouldStartWith, "grep -vF 'ssh-rsa AAAA' ~/.ssh/authorized_keys >")
+			So(cmd, ShouldEndWith, "mv ~/.ssh/authorized_keys.tmp ~/.ssh/authorized_keys")
+		})
+	})
+}
@@ -25,6 +25,7 @@ var (
 var (
 	SpawnHostTwoHourWarning    = "spawn_twohour"
 	SpawnHostTwelveHourWarning = "spawn_twelvehour"
+	SpawnHostIdleWarning       = "spawn_idle"
 	SlowProvisionWarning       = "slow_provision"
 	ProvisionFailed            = "provision_failed"
 )
@@ -105,6 +106,15 @@ func ByHostAlertRecordType(hostId, triggerId string) db.Q {
 	}).Limit(1)
 }
 
+// ByLatestHostAlertRecordType returns the most recent record of the given
+// type for the host.
+func ByLatestHostAlertRecordType(hostId, triggerId string) db.Q {
+	return db.Query(bson.M{
+		TypeKey:   triggerId,
+		HostIdKey: hostId,
+	}).Sort([]string{"-" + IdKey}).Limit(1)
+}
+
 func ByLastRevNotFound(projectId, versionId string) db.Q {
 	return db.Query(bson.M{
 		TypeKey:      LastRevisionNotFound,
@@ -25,6 +25,16 @@ var (
 	SpawnAllowedKey = bsonutil.MustHaveTag(Distro{}, "SpawnAllowed")
 	ExpansionsKey   = bsonutil.MustHaveTag(Distro{}, "Expansions")
 
+	DispatchModeKey     = bsonutil.MustHaveTag(Distro{}, "DispatchMode")
+	ProvisionModeKey    = bsonutil.MustHaveTag(Distro{}, "ProvisionMode")
+	DrainingKey         = bsonutil.MustHaveTag(Distro{}, "Draining")
+	MaxLifetimeKey      = bsonutil.MustHaveTag(Distro{}, "MaxLifetime")
+	SetupConcurrencyKey = bsonutil.MustHaveTag(Distro{}, "SetupConcurrency")
+	LabelsKey           = bsonutil.MustHaveTag(Distro{}, "Labels")
+	MinFreeDiskKey      = bsonutil.MustHaveTag(Distro{}, "MinFreeDisk")
+	DiskQuotaKey        = bsonutil.MustHaveTag(Distro{}, "DiskQuota")
+	PluginDirKey        = bsonutil.MustHaveTag(Distro{}, "PluginDir")
+
 	// bson fields for the UserData struct
 	UserDataFileKey     = bsonutil.MustHaveTag(UserData{}, "File")
 	UserDataValidateKey = bsonutil.MustHaveTag(UserData{}, "Validate")
@@ -73,6 +83,11 @@ func ByProvider(p string) db.Q {
 	return db.Query(bson.D{{ProviderKey, p}})
 }
 
+// ByDraining returns a query that selects distros in drain mode.
+func ByDraining() db.Q {
+	return db.Query(bson.D{{DrainingKey, true}})
+}
+
 // BySpawnAllowed returns a query that contains the SpawnAllowed selector.
 func BySpawnAllowed() db.Q {
 	return db.Query(bson.D{{SpawnAllowedKey, true}})
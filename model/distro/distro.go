@@ -1,5 +1,14 @@
 package distro
 
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
 // UserData validation formats
 const (
 	UserDataFormatFormURLEncoded = "x-www-form-urlencoded"
@@ -7,6 +16,27 @@ const (
 	UserDataFormatYAML           = "yaml"
 )
 
+// Task dispatch modes
+const (
+	// DispatchModePush has the taskrunner start an agent over SSH for
+	// each task it assigns to a host.
+	DispatchModePush = "push"
+	// DispatchModePull has a long-lived agent on each host ask the
+	// API server for its next task.
+	DispatchModePull = "pull"
+)
+
+// Host provisioning modes
+const (
+	// ProvisionModeSSH has hostinit run the setup script over SSH once
+	// the host is reachable.
+	ProvisionModeSSH = "ssh"
+	// ProvisionModeUserData passes the setup script to the cloud provider
+	// as instance userdata; the host reports back to the API server when
+	// its setup finishes.
+	ProvisionModeUserData = "userdata"
+)
+
 type Distro struct {
 	Id               string                  `bson:"_id" json:"_id,omitempty" mapstructure:"_id,omitempty"`
 	Arch             string                  `bson:"arch" json:"arch,omitempty" mapstructure:"arch,omitempty"`
@@ -24,6 +54,104 @@ type Distro struct {
 
 	SpawnAllowed bool        `bson:"spawn_allowed" json:"spawn_allowed,omitempty" mapstructure:"spawn_allowed,omitempty"`
 	Expansions   []Expansion `bson:"expansions,omitempty" json:"expansions,omitempty" mapstructure:"expansions,omitempty"`
+
+	DispatchMode  string `bson:"dispatch_mode,omitempty" json:"dispatch_mode,omitempty" mapstructure:"dispatch_mode,omitempty"`
+	ProvisionMode string `bson:"provision_mode,omitempty" json:"provision_mode,omitempty" mapstructure:"provision_mode,omitempty"`
+
+	// Draining stops new tasks from being assigned to the distro's hosts,
+	// and new hosts from being spawned for it
+	Draining bool `bson:"draining,omitempty" json:"draining,omitempty" mapstructure:"draining,omitempty"`
+
+	// MaxLifetime is how many minutes a dynamic host may live before it is
+	// replaced by a fresh one. Zero means hosts are never recycled.
+	MaxLifetime int `bson:"max_lifetime,omitempty" json:"max_lifetime,omitempty" mapstructure:"max_lifetime,omitempty"`
+
+	// SetupConcurrency is the most of the distro's hosts hostinit sets up
+	// at once. Zero means hostinit's default.
+	SetupConcurrency int `bson:"setup_concurrency,omitempty" json:"setup_concurrency,omitempty" mapstructure:"setup_concurrency,omitempty"`
+
+	// Labels describe the distro's capabilities as "key:value" pairs, e.g.
+	// "os:linux", so that tasks can ask for what they need instead of
+	// naming distros
+	Labels []string `bson:"labels,omitempty" json:"labels,omitempty" mapstructure:"labels,omitempty"`
+
+	// MinFreeDisk is how many megabytes must be free in the working
+	// directory for the agent to start a task. Zero means the agent's default.
+	MinFreeDisk int `bson:"min_free_disk,omitempty" json:"min_free_disk,omitempty" mapstructure:"min_free_disk,omitempty"`
+
+	// DiskQuota is how many megabytes each task may use in its directory
+	// before it is failed. Zero means tasks have no quota.
+	DiskQuota int `bson:"disk_quota,omitempty" json:"disk_quota,omitempty" mapstructure:"disk_quota,omitempty"`
+
+	// PluginDir is the directory on the distro's hosts that the agent loads
+	// external plugins from. The plugins are put there by the distro's
+	// setup script or image, so it must be outside the working directory,
+	// where tasks can't write. Empty means the agent loads none.
+	PluginDir string `bson:"plugin_dir,omitempty" json:"plugin_dir,omitempty" mapstructure:"plugin_dir,omitempty"`
+}
+
+// HasLabels returns true if the distro has every one of the given labels.
+func (d *Distro) HasLabels(labels []string) bool {
+	for _, label := range labels {
+		found := false
+		for _, own := range d.Labels {
+			if own == label {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchLabels returns the ids of the distros that have every one of the
+// given labels.
+func MatchLabels(distros []Distro, labels []string) []string {
+	ids := []string{}
+	for _, d := range distros {
+		if d.HasLabels(labels) {
+			ids = append(ids, d.Id)
+		}
+	}
+	return ids
+}
+
+// ParseLabel splits a label into its key and value.
+func ParseLabel(label string) (string, string, error) {
+	parts := strings.SplitN(label, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("label '%v' is not of the form 'key:value'", label)
+	}
+	return parts[0], parts[1], nil
+}
+
+// InWorkDir returns true if the given path is the distro's working directory
+// or anywhere under it.
+func (d *Distro) InWorkDir(path string) bool {
+	if d.WorkDir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(d.WorkDir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// UsesPullDispatch returns true if hosts of this distro run a long-lived
+// agent that requests its own tasks, rather than having the taskrunner
+// start an agent for each task.
+func (d *Distro) UsesPullDispatch() bool {
+	return d.DispatchMode == DispatchModePull
+}
+
+// UsesUserDataProvisioning returns true if hosts of this distro run their
+// setup script from provider userdata instead of over SSH.
+func (d *Distro) UsesUserDataProvisioning() bool {
+	return d.ProvisionMode == ProvisionModeUserData
 }
 
 type ValidateFormat string
@@ -33,6 +161,23 @@ type UserData struct {
 	Validate ValidateFormat `bson:"validate,omitempty" json:"validate,omitempty"`
 }
 
+// ValidateData checks that the given user-supplied data parses
+// according to the distro's validation format.
+func (u UserData) ValidateData(data string) error {
+	var err error
+	switch u.Validate {
+	case UserDataFormatFormURLEncoded:
+		_, err = url.ParseQuery(data)
+	case UserDataFormatJSON:
+		var out map[string]interface{}
+		err = json.Unmarshal([]byte(data), &out)
+	case UserDataFormatYAML:
+		var out map[string]interface{}
+		err = yaml.Unmarshal([]byte(data), &out)
+	}
+	return err
+}
+
 type Expansion struct {
 	Key   string `bson:"key,omitempty" json:"key,omitempty"`
 	Value string `bson:"value,omitempty" json:"value,omitempty"`
@@ -0,0 +1,47 @@
+package distro
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	Convey("With distros with labels", t, func() {
+		distros := []Distro{
+			{Id: "linux", Labels: []string{"os:linux", "arch:amd64"}},
+			{Id: "arm", Labels: []string{"os:linux", "arch:arm64"}},
+			{Id: "windows", Labels: []string{"os:windows", "arch:amd64"}},
+		}
+
+		Convey("a distro should only match if it has every label", func() {
+			So(distros[0].HasLabels([]string{"os:linux"}), ShouldBeTrue)
+			So(distros[0].HasLabels([]string{"os:linux", "arch:amd64"}), ShouldBeTrue)
+			So(distros[0].HasLabels([]string{"os:linux", "arch:arm64"}), ShouldBeFalse)
+			So(distros[0].HasLabels(nil), ShouldBeTrue)
+		})
+
+		Convey("all the matching distros should be found", func() {
+			So(MatchLabels(distros, []string{"os:linux"}), ShouldResemble,
+				[]string{"linux", "arm"})
+			So(MatchLabels(distros, []string{"arch:amd64"}), ShouldResemble,
+				[]string{"linux", "windows"})
+			So(MatchLabels(distros, []string{"gpu:true"}), ShouldResemble, []string{})
+		})
+
+		Convey("labels should be split into their key and value", func() {
+			key, value, err := ParseLabel("os:linux")
+			So(err, ShouldBeNil)
+			So(key, ShouldEqual, "os")
+			So(value, ShouldEqual, "linux")
+
+			_, value, err = ParseLabel("image:ubuntu:16.04")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, "ubuntu:16.04")
+
+			for _, label := range []string{"linux", ":linux", "os:"} {
+				_, _, err = ParseLabel(label)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
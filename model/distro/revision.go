@@ -0,0 +1,222 @@
+package distro
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/db/bsonutil"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"sort"
+	"time"
+)
+
+const (
+	RevisionsCollection = "distro_revisions"
+	// RevisionCountersCollection holds the number of the newest revision of
+	// each distro, so that concurrent saves each get a number of their own
+	RevisionCountersCollection = "distro_revision_counters"
+)
+
+// revisionCounter is the number of the newest revision of a distro.
+type revisionCounter struct {
+	DistroId string `bson:"_id"`
+	Last     int    `bson:"last"`
+}
+
+// Revision is a full copy of a distro as it was saved by a user.
+type Revision struct {
+	Id        bson.ObjectId `bson:"_id" json:"id"`
+	DistroId  string        `bson:"distro_id" json:"distro_id"`
+	Number    int           `bson:"number" json:"number"`
+	Author    string        `bson:"author" json:"author"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	Distro    Distro        `bson:"distro" json:"distro"`
+
+	// RestoredFrom is the number of the revision this one rolled back to,
+	// if it was created by a rollback
+	RestoredFrom int `bson:"restored_from,omitempty" json:"restored_from,omitempty"`
+}
+
+// FieldChange describes how one field of a distro differs between two
+// revisions. Provider settings are compared one level down, as
+// "settings.<name>".
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+var (
+	RevisionDistroIdKey = bsonutil.MustHaveTag(Revision{}, "DistroId")
+	RevisionNumberKey   = bsonutil.MustHaveTag(Revision{}, "Number")
+
+	revisionCounterLastKey = bsonutil.MustHaveTag(revisionCounter{}, "Last")
+)
+
+// SaveRevision stores the distro as its newest revision, authored by the
+// given user. restoredFrom is the revision being rolled back to, or zero.
+func SaveRevision(d Distro, author string, restoredFrom int) (*Revision, error) {
+	number, err := nextRevisionNumber(d.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	rev := &Revision{
+		Id:           bson.NewObjectId(),
+		DistroId:     d.Id,
+		Number:       number,
+		Author:       author,
+		CreatedAt:    time.Now(),
+		Distro:       d,
+		RestoredFrom: restoredFrom,
+	}
+	if err := db.Insert(RevisionsCollection, rev); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// nextRevisionNumber atomically takes the number of the distro's next
+// revision.
+func nextRevisionNumber(distroId string) (int, error) {
+	change := mgo.Change{
+		Update:    bson.M{"$inc": bson.M{revisionCounterLastKey: 1}},
+		ReturnNew: true,
+	}
+	for {
+		counter := &revisionCounter{}
+		_, err := db.FindAndModify(RevisionCountersCollection, bson.M{"_id": distroId},
+			db.NoSort, change, counter)
+		if err == nil {
+			return counter.Last, nil
+		}
+		if err != mgo.ErrNotFound {
+			return 0, err
+		}
+
+		// distros with revisions from before the counter was kept carry on
+		// numbering from their newest one
+		latest, err := FindLatestRevision(distroId)
+		if err != nil {
+			return 0, err
+		}
+		start := 0
+		if latest != nil {
+			start = latest.Number
+		}
+		err = db.Insert(RevisionCountersCollection, revisionCounter{distroId, start})
+		if err != nil && !mgo.IsDup(err) {
+			return 0, err
+		}
+	}
+}
+
+// FindRevisions returns every revision of the distro, newest first.
+func FindRevisions(distroId string) ([]Revision, error) {
+	revs := []Revision{}
+	err := db.FindAll(
+		RevisionsCollection,
+		bson.M{RevisionDistroIdKey: distroId},
+		db.NoProjection,
+		[]string{"-" + RevisionNumberKey},
+		db.NoSkip,
+		db.NoLimit,
+		&revs,
+	)
+	return revs, err
+}
+
+// FindRevision returns the given revision of the distro, or nil if it
+// does not exist.
+func FindRevision(distroId string, number int) (*Revision, error) {
+	return findOneRevision(bson.M{
+		RevisionDistroIdKey: distroId,
+		RevisionNumberKey:   number,
+	}, db.NoSort)
+}
+
+// FindLatestRevision returns the newest revision of the distro, or nil if
+// it has none.
+func FindLatestRevision(distroId string) (*Revision, error) {
+	return findOneRevision(bson.M{RevisionDistroIdKey: distroId},
+		[]string{"-" + RevisionNumberKey})
+}
+
+func findOneRevision(query interface{}, sort []string) (*Revision, error) {
+	rev := &Revision{}
+	err := db.FindOne(RevisionsCollection, query, db.NoProjection, sort, rev)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// KeepOperationalState copies the fields of the current distro that
+// reflect what is being done with it, rather than how it is configured, so
+// that rolling its configuration back to an old revision leaves them as
+// they are.
+func (d *Distro) KeepOperationalState(current *Distro) {
+	d.Draining = current.Draining
+}
+
+// Diff returns the fields that differ between the two distros, sorted by
+// field name. Fields are named as they are in the distro's JSON form.
+func Diff(old, new Distro) ([]FieldChange, error) {
+	oldFields, err := distroFields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := distroFields(new)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+
+	changes := []FieldChange{}
+	for name := range names {
+		if !reflect.DeepEqual(oldFields[name], newFields[name]) {
+			changes = append(changes, FieldChange{name, oldFields[name], newFields[name]})
+		}
+	}
+	sort.Sort(byField(changes))
+	return changes, nil
+}
+
+// distroFields flattens the distro's JSON form into a map of field names to
+// values, with provider settings broken out into their own fields.
+func distroFields(d Distro) (map[string]interface{}, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling distro %v: %v", d.Id, err)
+	}
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("error unmarshaling distro %v: %v", d.Id, err)
+	}
+
+	if settings, ok := fields["settings"].(map[string]interface{}); ok {
+		delete(fields, "settings")
+		for name, value := range settings {
+			fields["settings."+name] = value
+		}
+	}
+	return fields, nil
+}
+
+type byField []FieldChange
+
+func (b byField) Len() int           { return len(b) }
+func (b byField) Less(i, j int) bool { return b[i].Field < b[j].Field }
+func (b byField) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
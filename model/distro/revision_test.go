@@ -0,0 +1,58 @@
+package distro
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestKeepOperationalState(t *testing.T) {
+	Convey("When rolling a distro back to an old revision", t, func() {
+		old := Distro{Id: "d1", User: "root", Draining: false}
+		current := &Distro{Id: "d1", User: "admin", Draining: true}
+
+		Convey("its configuration should be restored but not whether it is draining", func() {
+			old.KeepOperationalState(current)
+			So(old.User, ShouldEqual, "root")
+			So(old.Draining, ShouldBeTrue)
+		})
+	})
+}
+
+func TestDiff(t *testing.T) {
+	Convey("When diffing two distros", t, func() {
+		old := Distro{
+			Id:       "d1",
+			Arch:     "linux_amd64",
+			User:     "root",
+			Provider: "ec2",
+			ProviderSettings: &map[string]interface{}{
+				"instance_type": "m3.large",
+				"region":        "us-east-1",
+			},
+		}
+
+		Convey("identical distros should have no changes", func() {
+			changes, err := Diff(old, old)
+			So(err, ShouldBeNil)
+			So(changes, ShouldBeEmpty)
+		})
+
+		Convey("changed, added and removed fields should be listed in order", func() {
+			new := old
+			new.User = "admin"
+			new.Setup = "echo hi"
+			new.ProviderSettings = &map[string]interface{}{
+				"instance_type": "m3.xlarge",
+				"region":        "us-east-1",
+			}
+
+			changes, err := Diff(old, new)
+			So(err, ShouldBeNil)
+			So(changes, ShouldResemble, []FieldChange{
+				{"settings.instance_type", "m3.large", "m3.xlarge"},
+				{"setup", nil, "echo hi"},
+				{"user", "root", "admin"},
+			})
+		})
+	})
+}
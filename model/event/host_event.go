@@ -19,6 +19,13 @@ const (
 	EventHostRunningTaskSet     = "HOST_RUNNING_TASK_SET"
 	EventHostRunningTaskCleared = "HOST_RUNNING_TASK_CLEARED"
 	EventHostTaskPidSet         = "HOST_TASK_PID_SET"
+	EventHostSpotFallback       = "HOST_SPOT_FALLBACK"
+	EventHostDrainingSet        = "HOST_DRAINING_SET"
+	EventHostDrainingCleared    = "HOST_DRAINING_CLEARED"
+	EventHostDrained            = "HOST_DRAINED"
+	EventHostReplacementSpawned = "HOST_REPLACEMENT_SPAWNED"
+	EventHostRetiring           = "HOST_RETIRING"
+	EventHostTerminated         = "HOST_TERMINATED"
 )
 
 // implements EventData
@@ -32,6 +39,13 @@ type HostEventData struct {
 	Hostname  string `bson:"hn,omitempty" json:"hostname,omitempty"`
 	TaskId    string `bson:"t_id,omitempty" json:"task_id,omitempty"`
 	TaskPid   string `bson:"t_pid,omitempty" json:"task_pid,omitempty"`
+
+	// set when a host is replaced by another
+	ReplacementId string `bson:"rep_id,omitempty" json:"replacement_id,omitempty"`
+	Reason        string `bson:"rsn,omitempty" json:"reason,omitempty"`
+
+	// the user who made the change, if it was made by hand
+	User string `bson:"usr,omitempty" json:"user,omitempty"`
 }
 
 func (self HostEventData) IsValid() bool {
@@ -87,6 +101,47 @@ func LogHostTaskPidSet(hostId string, taskPid string) {
 		HostEventData{TaskPid: taskPid})
 }
 
+// LogHostSpotFallback records that a spot host's request was cancelled and
+// an on-demand host was spawned in its place.
+func LogHostSpotFallback(hostId string, replacementId string, reason string) {
+	LogHostEvent(hostId, EventHostSpotFallback,
+		HostEventData{ReplacementId: replacementId, Reason: reason})
+}
+
+// LogHostDrainingSet records that the host was put into drain mode.
+func LogHostDrainingSet(hostId string, user string) {
+	LogHostEvent(hostId, EventHostDrainingSet, HostEventData{User: user})
+}
+
+// LogHostDrainingCleared records that the host was taken out of drain mode.
+func LogHostDrainingCleared(hostId string, user string) {
+	LogHostEvent(hostId, EventHostDrainingCleared, HostEventData{User: user})
+}
+
+// LogHostDrained records that a draining host has finished its last task.
+func LogHostDrained(hostId string) {
+	LogHostEvent(hostId, EventHostDrained, HostEventData{})
+}
+
+// LogHostReplacementSpawned records that a host past its distro's max
+// lifetime has had a replacement spawned.
+func LogHostReplacementSpawned(hostId string, replacementId string) {
+	LogHostEvent(hostId, EventHostReplacementSpawned,
+		HostEventData{ReplacementId: replacementId})
+}
+
+// LogHostRetiring records that a host's replacement is up, and that the
+// host will be terminated once its current task finishes.
+func LogHostRetiring(hostId string, replacementId string) {
+	LogHostEvent(hostId, EventHostRetiring,
+		HostEventData{ReplacementId: replacementId})
+}
+
+// LogHostTerminated records that the host was terminated, and why.
+func LogHostTerminated(hostId string, reason string) {
+	LogHostEvent(hostId, EventHostTerminated, HostEventData{Reason: reason})
+}
+
 func LogProvisionFailed(hostId string, setupLog string) {
 	LogHostEvent(hostId, EventHostProvisionFailed, HostEventData{SetupLog: setupLog})
 }
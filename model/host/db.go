@@ -29,21 +29,33 @@ var (
 	DistroKey                = bsonutil.MustHaveTag(Host{}, "Distro")
 	ProviderKey              = bsonutil.MustHaveTag(Host{}, "Provider")
 	ProvisionedKey           = bsonutil.MustHaveTag(Host{}, "Provisioned")
+	ProvisionAttemptsKey     = bsonutil.MustHaveTag(Host{}, "ProvisionAttempts")
+	ProvisionStartTimeKey    = bsonutil.MustHaveTag(Host{}, "ProvisionStartTime")
 	RunningTaskKey           = bsonutil.MustHaveTag(Host{}, "RunningTask")
 	PidKey                   = bsonutil.MustHaveTag(Host{}, "Pid")
 	TaskDispatchTimeKey      = bsonutil.MustHaveTag(Host{}, "TaskDispatchTime")
 	CreateTimeKey            = bsonutil.MustHaveTag(Host{}, "CreationTime")
 	ExpirationTimeKey        = bsonutil.MustHaveTag(Host{}, "ExpirationTime")
 	TerminationTimeKey       = bsonutil.MustHaveTag(Host{}, "TerminationTime")
+	TerminationReasonKey     = bsonutil.MustHaveTag(Host{}, "TerminationReason")
 	LTCTimeKey               = bsonutil.MustHaveTag(Host{}, "LastTaskCompletedTime")
 	LTCKey                   = bsonutil.MustHaveTag(Host{}, "LastTaskCompleted")
 	StatusKey                = bsonutil.MustHaveTag(Host{}, "Status")
 	AgentRevisionKey         = bsonutil.MustHaveTag(Host{}, "AgentRevision")
+	NeedsNewAgentKey         = bsonutil.MustHaveTag(Host{}, "NeedsNewAgent")
 	StartedByKey             = bsonutil.MustHaveTag(Host{}, "StartedBy")
 	InstanceTypeKey          = bsonutil.MustHaveTag(Host{}, "InstanceType")
 	NotificationsKey         = bsonutil.MustHaveTag(Host{}, "Notifications")
 	UserDataKey              = bsonutil.MustHaveTag(Host{}, "UserData")
 	LastReachabilityCheckKey = bsonutil.MustHaveTag(Host{}, "LastReachabilityCheck")
+	SecretKey                = bsonutil.MustHaveTag(Host{}, "Secret")
+	DrainingKey              = bsonutil.MustHaveTag(Host{}, "Draining")
+	DrainedTimeKey           = bsonutil.MustHaveTag(Host{}, "DrainedTime")
+	ReplacementIdKey         = bsonutil.MustHaveTag(Host{}, "ReplacementId")
+	RetiringKey              = bsonutil.MustHaveTag(Host{}, "Retiring")
+	LastUserActivityKey      = bsonutil.MustHaveTag(Host{}, "LastUserActivity")
+	LastActivityCheckKey     = bsonutil.MustHaveTag(Host{}, "LastActivityCheck")
+	KeyVersionKey            = bsonutil.MustHaveTag(Host{}, "KeyVersion")
 )
 
 // === Queries ===
@@ -85,12 +97,38 @@ func ByUserWithUnterminatedStatus(user string) db.Q {
 }
 
 // IsAvailableAndFree is a query that returns all running
-// Evergreen hosts without an assigned task.
+// Evergreen hosts without an assigned task that are not draining
+// or retiring.
 var IsAvailableAndFree = db.Query(
 	bson.M{
 		"$or":        noRunningTask,
 		StatusKey:    evergreen.HostRunning,
 		StartedByKey: evergreen.User,
+		DrainingKey:  bson.M{"$ne": true},
+		RetiringKey:  bson.M{"$ne": true},
+	},
+)
+
+// NeedsNewAgent is a query that returns running hosts of pull-dispatch
+// distros whose agents were turned away by the API server, and so must have
+// new agents started on them.
+var NeedsNewAgent = db.Query(
+	bson.M{
+		StatusKey:        evergreen.HostRunning,
+		StartedByKey:     evergreen.User,
+		NeedsNewAgentKey: true,
+		fmt.Sprintf("%v.%v", DistroKey, distro.DispatchModeKey): distro.DispatchModePull,
+	},
+)
+
+// IsRetiredAndFree is a query that returns all retiring hosts that have
+// finished their last task.
+var IsRetiredAndFree = db.Query(
+	bson.M{
+		"$or":        noRunningTask,
+		RetiringKey:  true,
+		StatusKey:    bson.M{"$ne": evergreen.HostTerminated},
+		StartedByKey: evergreen.User,
 	},
 )
 
@@ -121,6 +159,19 @@ var IsUninitialized = db.Query(
 	bson.M{StatusKey: evergreen.HostUninitialized, StartedByKey: evergreen.User},
 )
 
+// ByAbandonedInitializingSince produces a query that returns all Evergreen
+// hosts still being set up by an attempt started before the given time.
+func ByAbandonedInitializingSince(threshold time.Time) db.Q {
+	return db.Query(bson.M{
+		StatusKey:    evergreen.HostInitializing,
+		StartedByKey: evergreen.User,
+		"$or": []bson.M{
+			bson.M{ProvisionStartTimeKey: bson.M{"$lte": threshold}},
+			bson.M{ProvisionStartTimeKey: bson.M{"$exists": false}},
+		},
+	})
+}
+
 // ByUnproductiveSince produces a query that returns all hosts that
 // are not doign work and were created before the given time.
 func ByUnproductiveSince(threshold time.Time) db.Q {
@@ -178,6 +229,46 @@ func ById(id string) db.Q {
 	return db.Query(bson.D{{IdKey, id}})
 }
 
+// ByUnterminatedProvider produces a query that returns all hosts of the
+// given provider that have not been terminated.
+func ByUnterminatedProvider(provider string) db.Q {
+	return db.Query(bson.M{
+		ProviderKey: provider,
+		StatusKey:   bson.M{"$ne": evergreen.HostTerminated},
+	})
+}
+
+// ByIdOrTag produces a query that returns a host whose id or tag matches
+// the given value. Hosts that identify themselves before their cloud
+// provider has assigned their final id use their tag.
+func ByIdOrTag(id string) db.Q {
+	return db.Query(bson.M{
+		"$or": []bson.M{
+			bson.M{IdKey: id},
+			bson.M{TagKey: id},
+		},
+	})
+}
+
+// ByDrainedUnreported produces a query that returns the idle, running hosts
+// of the given provider that are draining, either by themselves or as part
+// of one of the given distros, and that have not yet been found drained.
+func ByDrainedUnreported(provider string, drainingDistroIds []string) db.Q {
+	dId := fmt.Sprintf("%v.%v", DistroKey, distro.IdKey)
+	return db.Query(bson.M{
+		ProviderKey:    provider,
+		StatusKey:      evergreen.HostRunning,
+		DrainedTimeKey: bson.M{"$exists": false},
+		"$and": []bson.M{
+			bson.M{"$or": noRunningTask},
+			bson.M{"$or": []bson.M{
+				bson.M{DrainingKey: true},
+				bson.M{dId: bson.M{"$in": drainingDistroIds}},
+			}},
+		},
+	})
+}
+
 // ByIds produces a query that returns all hosts in the given list of ids.
 func ByIds(ids []string) db.Q {
 	return db.Query(bson.D{
@@ -229,6 +320,45 @@ func ByNotMonitoredSince(threshold time.Time) db.Q {
 	})
 }
 
+// ByActivityNotCheckedSince produces a query that returns all running
+// user-spawned hosts whose last check for the user's activity was before
+// the specified threshold.
+func ByActivityNotCheckedSince(threshold time.Time) db.Q {
+	return db.Query(bson.M{
+		StartedByKey: bson.M{"$ne": evergreen.User},
+		StatusKey:    evergreen.HostRunning,
+		"$or": []bson.M{
+			bson.M{LastActivityCheckKey: bson.M{"$lte": threshold}},
+			bson.M{LastActivityCheckKey: bson.M{"$exists": false}},
+		},
+	})
+}
+
+// ByKeyVersionBefore produces a query that returns running hosts that use
+// the named SSH key but have not yet been given the specified version of it.
+func ByKeyVersionBefore(keyName string, version int) db.Q {
+	return db.Query(bson.M{
+		fmt.Sprintf("%v.%v", DistroKey, distro.SSHKeyKey): keyName,
+		StatusKey:     evergreen.HostRunning,
+		KeyVersionKey: bson.M{"$not": bson.M{"$gte": version}},
+	})
+}
+
+// ByLiveWithKeyVersion produces a query that returns hosts that have not
+// been terminated and still use the specified version of the named SSH key.
+func ByLiveWithKeyVersion(keyName string, version int) db.Q {
+	versionQuery := interface{}(version)
+	if version == 0 {
+		// version 0 is left out of host documents
+		versionQuery = bson.M{"$in": []interface{}{0, nil}}
+	}
+	return db.Query(bson.M{
+		fmt.Sprintf("%v.%v", DistroKey, distro.SSHKeyKey): keyName,
+		StatusKey:     bson.M{"$ne": evergreen.HostTerminated},
+		KeyVersionKey: versionQuery,
+	})
+}
+
 // ByExpiringBetween produces a query that returns  any user-spawned hosts
 // that will expire between the specified times.
 func ByExpiringBetween(lowerBound time.Time, upperBound time.Time) db.Q {
@@ -308,3 +438,57 @@ func UpsertOne(query interface{}, update interface{}) (*mgo.ChangeInfo, error) {
 		update,
 	)
 }
+
+// ClearUndrainedTimes forgets when hosts were found drained if they are no
+// longer draining, either by themselves or as part of one of the given
+// distros.
+func ClearUndrainedTimes(drainingDistroIds []string) error {
+	dId := fmt.Sprintf("%v.%v", DistroKey, distro.IdKey)
+	return UpdateAll(
+		bson.M{
+			DrainedTimeKey: bson.M{"$exists": true},
+			DrainingKey:    bson.M{"$ne": true},
+			dId:            bson.M{"$nin": drainingDistroIds},
+		},
+		bson.M{"$unset": bson.M{DrainedTimeKey: 1}},
+	)
+}
+
+// TerminationCount is the number of hosts of a distro terminated for a
+// given reason.
+type TerminationCount struct {
+	Distro string `bson:"distro" json:"distro"`
+	Reason string `bson:"reason" json:"reason"`
+	Count  int    `bson:"count" json:"count"`
+}
+
+// CountTerminations returns how many hosts of each distro were terminated
+// for each reason since the given time, sorted by distro and then by count.
+func CountTerminations(since time.Time) ([]TerminationCount, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			StatusKey:          evergreen.HostTerminated,
+			TerminationTimeKey: bson.M{"$gte": since},
+		}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"distro": "$" + DistroKey + "." + distro.IdKey,
+				"reason": "$" + TerminationReasonKey,
+			},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$project": bson.M{
+			"_id":    0,
+			"distro": "$_id.distro",
+			"reason": "$_id.reason",
+			"count":  1,
+		}},
+		{"$sort": bson.D{{"distro", 1}, {"count", -1}}},
+	}
+
+	counts := []TerminationCount{}
+	if err := db.Aggregate(Collection, pipeline, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
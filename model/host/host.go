@@ -22,6 +22,11 @@ type Host struct {
 
 	// true if the host has been set up properly
 	Provisioned bool `bson:"provisioned" json:"provisioned"`
+	// how many times hostinit has started setting up the host, and when it
+	// last did; used to tell setups abandoned by a hostinit that stopped
+	// partway from ones still in progress
+	ProvisionAttempts  int       `bson:"provision_attempts,omitempty" json:"provision_attempts,omitempty"`
+	ProvisionStartTime time.Time `bson:"provision_start_time,omitempty" json:"provision_start_time"`
 
 	// the task that is currently running on the host
 	RunningTask string `bson:"running_task" json:"running_task"`
@@ -35,6 +40,9 @@ type Host struct {
 	CreationTime     time.Time `bson:"creation_time" json:"creation_time"`
 	TerminationTime  time.Time `bson:"termination_time" json:"termination_time"`
 
+	// why the host was terminated; set before terminating the host
+	TerminationReason string `bson:"termination_reason,omitempty" json:"termination_reason,omitempty"`
+
 	LastTaskCompletedTime time.Time `bson:"last_task_completed_time" json:"last_task_completed_time"`
 	LastTaskCompleted     string    `bson:"last_task" json:"last_task"`
 	Status                string    `bson:"status" json:"status"`
@@ -42,6 +50,9 @@ type Host struct {
 	// True if this host was created manually by a user (i.e. with spawnhost)
 	UserHost      bool   `bson:"user_host" json:"user_host"`
 	AgentRevision string `bson:"agent_revision" json:"agent_revision"`
+	// true if the host's agent was turned away by the API server for
+	// speaking an incompatible protocol, and must be replaced
+	NeedsNewAgent bool `bson:"needs_new_agent,omitempty" json:"needs_new_agent,omitempty"`
 	// for ec2 dynamic hosts, the instance type requested
 	InstanceType string `bson:"instance_type" json:"instance_type,omitempty"`
 	// stores information on expiration notifications for spawn hosts
@@ -52,6 +63,42 @@ type Host struct {
 
 	// the last time that the host's reachability was checked
 	LastReachabilityCheck time.Time `bson:"last_reachability_check" json:"last_reachability_check"`
+
+	// used by the agent on a pull-dispatch host to authenticate itself
+	// when requesting its next task
+	Secret string `bson:"secret,omitempty" json:"-"`
+
+	// true if no new tasks should be assigned to the host
+	Draining bool `bson:"draining,omitempty" json:"draining,omitempty"`
+	// when the host was found idle after being drained, either by itself
+	// or along with its distro
+	DrainedTime time.Time `bson:"drained_time,omitempty" json:"drained_time"`
+
+	// for a host past its distro's max lifetime, the host spawned to take
+	// its place
+	ReplacementId string `bson:"replacement_id,omitempty" json:"replacement_id,omitempty"`
+	// true once the host's replacement is running; a retiring host is
+	// given no new tasks and is terminated once its current task finishes
+	Retiring bool `bson:"retiring,omitempty" json:"retiring,omitempty"`
+
+	// for spawn hosts, the last time the user was seen using the host, and
+	// the last time the host was checked for the user's activity
+	LastUserActivity  time.Time `bson:"last_user_activity,omitempty" json:"last_user_activity"`
+	LastActivityCheck time.Time `bson:"last_activity_check,omitempty" json:"last_activity_check"`
+
+	// the version of the distro's SSH key that the host accepts; see
+	// evergreen.HostKey
+	KeyVersion int `bson:"key_version,omitempty" json:"key_version,omitempty"`
+}
+
+// UserIdleTime returns how long it has been since the user was last seen
+// using the spawn host, or since it was created if they never have been
+func (self *Host) UserIdleTime() time.Duration {
+	lastActive := self.CreationTime
+	if self.LastUserActivity.After(lastActive) {
+		lastActive = self.LastUserActivity
+	}
+	return time.Now().Sub(lastActive)
 }
 
 // IdleTime returns how long has this host been idle
@@ -95,20 +142,75 @@ func (self *Host) SetStatus(status string) error {
 	)
 }
 
-// SetInitializing marks the host as initializing. Only allow this
-// if the host is uninitialized.
+// SetInitializing marks the host as initializing, starting a new attempt
+// at setting it up. Only allow this if the host is uninitialized.
 func (self *Host) SetInitializing() error {
-	return UpdateOne(
+	now := time.Now()
+	err := UpdateOne(
 		bson.M{
 			IdKey:     self.Id,
 			StatusKey: evergreen.HostUninitialized,
 		},
 		bson.M{
 			"$set": bson.M{
-				StatusKey: evergreen.HostInitializing,
+				StatusKey:             evergreen.HostInitializing,
+				ProvisionStartTimeKey: now,
+			},
+			"$inc": bson.M{ProvisionAttemptsKey: 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	self.Status = evergreen.HostInitializing
+	self.ProvisionStartTime = now
+	self.ProvisionAttempts++
+	return nil
+}
+
+// ResumeInitializing starts a new attempt at setting up a host whose last
+// attempt was abandoned. Only allow this if no other attempt has been
+// started since the host was read.
+func (self *Host) ResumeInitializing() error {
+	now := time.Now()
+	// hosts from before attempts were counted have none recorded
+	var attempts interface{} = self.ProvisionAttempts
+	if self.ProvisionAttempts == 0 {
+		attempts = bson.M{"$in": []interface{}{0, nil}}
+	}
+	err := UpdateOne(
+		bson.M{
+			IdKey:                self.Id,
+			StatusKey:            evergreen.HostInitializing,
+			ProvisionAttemptsKey: attempts,
+		},
+		bson.M{
+			"$set": bson.M{
+				ProvisionStartTimeKey: now,
+				ProvisionAttemptsKey:  self.ProvisionAttempts + 1,
 			},
 		},
 	)
+	if err != nil {
+		return err
+	}
+	self.ProvisionStartTime = now
+	self.ProvisionAttempts++
+	return nil
+}
+
+// HasProvisionAttempt returns whether the host's latest attempt at being
+// set up is still the given one, and hasn't been given up on.
+func (self *Host) HasProvisionAttempt(attempt int) (bool, error) {
+	count, err := Count(db.Query(bson.M{
+		IdKey:                self.Id,
+		StatusKey:            evergreen.HostInitializing,
+		ProvisionAttemptsKey: attempt,
+	}))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
 
 func (self *Host) SetDecommissioned() error {
@@ -149,20 +251,27 @@ func (self *Host) SetQuarantined(status string) error {
 	return self.SetStatus(evergreen.HostQuarantined)
 }
 
+// Terminate marks the host as terminated, recording when and, if the
+// host's TerminationReason is set, why.
 func (self *Host) Terminate() error {
 	err := self.SetTerminated()
 	if err != nil {
 		return err
 	}
+	event.LogHostTerminated(self.Id, self.TerminationReason)
 	self.TerminationTime = time.Now()
+	update := bson.M{
+		TerminationTimeKey: self.TerminationTime,
+	}
+	if self.TerminationReason != "" {
+		update[TerminationReasonKey] = self.TerminationReason
+	}
 	return UpdateOne(
 		bson.M{
 			IdKey: self.Id,
 		},
 		bson.M{
-			"$set": bson.M{
-				TerminationTimeKey: self.TerminationTime,
-			},
+			"$set": update,
 		},
 	)
 }
@@ -208,6 +317,42 @@ func (self *Host) MarkAsProvisioned() error {
 	)
 }
 
+// MarkAsProvisionedIfCurrent marks the host as provisioned, but only if it
+// is still in the status and on the setup attempt it was read with, so that
+// a setup that finishes after the host was given up on, or after a newer
+// attempt took over, can't bring it back. It returns whether the host was
+// marked.
+func (self *Host) MarkAsProvisionedIfCurrent() (bool, error) {
+	var attempts interface{} = self.ProvisionAttempts
+	if self.ProvisionAttempts == 0 {
+		// hosts from before attempts were counted have none recorded
+		attempts = bson.M{"$in": []interface{}{0, nil}}
+	}
+	err := UpdateOne(
+		bson.M{
+			IdKey:                self.Id,
+			StatusKey:            self.Status,
+			ProvisionAttemptsKey: attempts,
+		},
+		bson.M{
+			"$set": bson.M{
+				StatusKey:      evergreen.HostRunning,
+				ProvisionedKey: true,
+			},
+		},
+	)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	event.LogHostProvisioned(self.Id)
+	self.Status = evergreen.HostRunning
+	self.Provisioned = true
+	return true, nil
+}
+
 // UpdateRunningTask takes two id strings - an old task and a new one - finds
 // the host running the task with Id, 'prevTaskId' and updates its running task
 // to 'newTaskId'; also setting the completion time of 'prevTaskId'
@@ -242,6 +387,8 @@ func (self *Host) SetRunningTask(taskId, agentRevision string,
 	self.RunningTask = taskId
 	self.AgentRevision = agentRevision
 	self.TaskDispatchTime = taskDispatchTime
+	// the agent picking up the task speaks the API server's protocol
+	self.NeedsNewAgent = false
 	return UpdateOne(
 		bson.M{
 			IdKey: self.Id,
@@ -252,6 +399,39 @@ func (self *Host) SetRunningTask(taskId, agentRevision string,
 				AgentRevisionKey:    agentRevision,
 				TaskDispatchTimeKey: taskDispatchTime,
 			},
+			"$unset": bson.M{
+				NeedsNewAgentKey: 1,
+			},
+		},
+	)
+}
+
+// SetNeedsNewAgent records whether the host's agent must be replaced by one
+// that speaks the API server's protocol.
+func (self *Host) SetNeedsNewAgent(needsAgent bool) error {
+	self.NeedsNewAgent = needsAgent
+	if needsAgent {
+		return UpdateOne(
+			bson.M{IdKey: self.Id},
+			bson.M{"$set": bson.M{NeedsNewAgentKey: true}},
+		)
+	}
+	return UpdateOne(
+		bson.M{IdKey: self.Id},
+		bson.M{"$unset": bson.M{NeedsNewAgentKey: 1}},
+	)
+}
+
+// SetAgentStarted records that a new agent was started on the host from the
+// given revision.
+func (self *Host) SetAgentStarted(agentRevision string) error {
+	self.AgentRevision = agentRevision
+	self.NeedsNewAgent = false
+	return UpdateOne(
+		bson.M{IdKey: self.Id},
+		bson.M{
+			"$set":   bson.M{AgentRevisionKey: agentRevision},
+			"$unset": bson.M{NeedsNewAgentKey: 1},
 		},
 	)
 }
@@ -292,6 +472,86 @@ func (self *Host) SetUserData(userData string) error {
 	)
 }
 
+// SetSecret updates the secret the host's agent uses to request tasks
+func (self *Host) SetSecret(secret string) error {
+	// update the in-memory host, then the database
+	self.Secret = secret
+	return UpdateOne(
+		bson.M{
+			IdKey: self.Id,
+		},
+		bson.M{
+			"$set": bson.M{
+				SecretKey: secret,
+			},
+		},
+	)
+}
+
+// SetDraining puts the host into or takes it out of drain mode on behalf of
+// the given user. No new tasks are assigned to a draining host.
+func (self *Host) SetDraining(draining bool, user string) error {
+	if draining {
+		event.LogHostDrainingSet(self.Id, user)
+	} else {
+		event.LogHostDrainingCleared(self.Id, user)
+	}
+
+	// update the in-memory host, then the database
+	self.Draining = draining
+	if draining {
+		return UpdateOne(
+			bson.M{IdKey: self.Id},
+			bson.M{"$set": bson.M{DrainingKey: true}},
+		)
+	}
+	self.DrainedTime = time.Time{}
+	return UpdateOne(
+		bson.M{IdKey: self.Id},
+		bson.M{"$unset": bson.M{DrainingKey: 1, DrainedTimeKey: 1}},
+	)
+}
+
+// SetDrained records that the draining host has finished its last task.
+func (self *Host) SetDrained() error {
+	event.LogHostDrained(self.Id)
+	self.DrainedTime = time.Now()
+	return UpdateOne(
+		bson.M{IdKey: self.Id},
+		bson.M{"$set": bson.M{DrainedTimeKey: self.DrainedTime}},
+	)
+}
+
+// SetReplacement records the host spawned to replace this one when it
+// outlived its distro's max lifetime. An empty id clears the replacement,
+// so that another can be spawned.
+func (self *Host) SetReplacement(replacementId string) error {
+	// update the in-memory host, then the database
+	self.ReplacementId = replacementId
+	if replacementId == "" {
+		return UpdateOne(
+			bson.M{IdKey: self.Id},
+			bson.M{"$unset": bson.M{ReplacementIdKey: 1}},
+		)
+	}
+	event.LogHostReplacementSpawned(self.Id, replacementId)
+	return UpdateOne(
+		bson.M{IdKey: self.Id},
+		bson.M{"$set": bson.M{ReplacementIdKey: replacementId}},
+	)
+}
+
+// SetRetiring marks the host to be terminated once its current task
+// finishes. No new tasks are assigned to a retiring host.
+func (self *Host) SetRetiring() error {
+	event.LogHostRetiring(self.Id, self.ReplacementId)
+	self.Retiring = true
+	return UpdateOne(
+		bson.M{IdKey: self.Id},
+		bson.M{"$set": bson.M{RetiringKey: true}},
+	)
+}
+
 // SetExpirationNotification updates the notification time for a spawn host
 func (self *Host) SetExpirationNotification(thresholdKey string) error {
 	// update the in-memory host, then the database
@@ -368,6 +628,40 @@ func (self *Host) UpdateReachability(reachable bool) error {
 	)
 }
 
+// SetActivityChecked records that the spawn host was checked for the user's
+// activity, and whether they were found to be using it.
+func (self *Host) SetActivityChecked(active bool) error {
+	now := time.Now()
+	update := bson.M{LastActivityCheckKey: now}
+	self.LastActivityCheck = now
+	if active {
+		update[LastUserActivityKey] = now
+		self.LastUserActivity = now
+	}
+	return UpdateOne(
+		bson.M{
+			IdKey: self.Id,
+		},
+		bson.M{
+			"$set": update,
+		},
+	)
+}
+
+// SetKeyVersion records the version of the distro's SSH key that the host
+// accepts.
+func (self *Host) SetKeyVersion(version int) error {
+	self.KeyVersion = version
+	return UpdateOne(
+		bson.M{
+			IdKey: self.Id,
+		},
+		bson.M{
+			"$set": bson.M{KeyVersionKey: version},
+		},
+	)
+}
+
 func (self *Host) Upsert() (*mgo.ChangeInfo, error) {
 	return UpsertOne(
 		bson.M{
@@ -196,6 +196,58 @@ func TestSetHostTerminated(t *testing.T) {
 
 		})
 
+		Convey("the termination reason should be saved if set", func() {
+
+			host.TerminationReason = evergreen.TerminationReasonIdle
+			So(host.Terminate(), ShouldBeNil)
+
+			host, err := FindOne(ById(host.Id))
+			So(err, ShouldBeNil)
+			So(host.TerminationReason, ShouldEqual, evergreen.TerminationReasonIdle)
+
+		})
+
+	})
+}
+
+func TestCountTerminations(t *testing.T) {
+
+	Convey("When counting host terminations", t, func() {
+
+		testutil.HandleTestingErr(db.Clear(Collection), t, "Error"+
+			" clearing '%v' collection", Collection)
+
+		now := time.Now()
+		hosts := []Host{
+			{Id: "h1", Distro: distro.Distro{Id: "d1"}, Status: evergreen.HostTerminated,
+				TerminationTime: now, TerminationReason: evergreen.TerminationReasonIdle},
+			{Id: "h2", Distro: distro.Distro{Id: "d1"}, Status: evergreen.HostTerminated,
+				TerminationTime: now, TerminationReason: evergreen.TerminationReasonIdle},
+			{Id: "h3", Distro: distro.Distro{Id: "d1"}, Status: evergreen.HostTerminated,
+				TerminationTime: now, TerminationReason: evergreen.TerminationReasonExcess},
+			{Id: "h4", Distro: distro.Distro{Id: "d2"}, Status: evergreen.HostTerminated,
+				TerminationTime: now, TerminationReason: evergreen.TerminationReasonIdle},
+			{Id: "old", Distro: distro.Distro{Id: "d2"}, Status: evergreen.HostTerminated,
+				TerminationTime: now.Add(-48 * time.Hour), TerminationReason: evergreen.TerminationReasonIdle},
+			{Id: "running", Distro: distro.Distro{Id: "d2"}, Status: evergreen.HostRunning},
+		}
+		for _, h := range hosts {
+			So(h.Insert(), ShouldBeNil)
+		}
+
+		Convey("terminations since the cutoff should be grouped by distro"+
+			" and reason", func() {
+
+			counts, err := CountTerminations(now.Add(-24 * time.Hour))
+			So(err, ShouldBeNil)
+			So(counts, ShouldResemble, []TerminationCount{
+				{"d1", evergreen.TerminationReasonIdle, 2},
+				{"d1", evergreen.TerminationReasonExcess, 1},
+				{"d2", evergreen.TerminationReasonIdle, 1},
+			})
+
+		})
+
 	})
 }
 
@@ -264,6 +316,111 @@ func TestMarkAsProvisioned(t *testing.T) {
 	})
 }
 
+func TestMarkAsProvisionedIfCurrent(t *testing.T) {
+
+	Convey("With an uninitialized host", t, func() {
+
+		testutil.HandleTestingErr(db.Clear(Collection), t, "Error"+
+			" clearing '%v' collection", Collection)
+
+		host := &Host{
+			Id:     "hostOne",
+			Status: evergreen.HostUninitialized,
+		}
+		So(host.Insert(), ShouldBeNil)
+
+		Convey("it should be marked provisioned if it hasn't changed", func() {
+			marked, err := host.MarkAsProvisionedIfCurrent()
+			So(err, ShouldBeNil)
+			So(marked, ShouldBeTrue)
+			So(host.Status, ShouldEqual, evergreen.HostRunning)
+
+			dbHost, err := FindOne(ById(host.Id))
+			So(err, ShouldBeNil)
+			So(dbHost.Status, ShouldEqual, evergreen.HostRunning)
+			So(dbHost.Provisioned, ShouldBeTrue)
+		})
+
+		Convey("it should be left alone once its setup was given up on", func() {
+			stale := *host
+			So(host.SetStatus(evergreen.HostProvisionFailed), ShouldBeNil)
+			marked, err := stale.MarkAsProvisionedIfCurrent()
+			So(err, ShouldBeNil)
+			So(marked, ShouldBeFalse)
+
+			dbHost, err := FindOne(ById(host.Id))
+			So(err, ShouldBeNil)
+			So(dbHost.Status, ShouldEqual, evergreen.HostProvisionFailed)
+			So(dbHost.Provisioned, ShouldBeFalse)
+		})
+
+		Convey("it should be left alone once a newer attempt started", func() {
+			So(host.SetInitializing(), ShouldBeNil)
+			stale := *host
+			So(host.ResumeInitializing(), ShouldBeNil)
+			marked, err := stale.MarkAsProvisionedIfCurrent()
+			So(err, ShouldBeNil)
+			So(marked, ShouldBeFalse)
+		})
+
+	})
+}
+
+func TestProvisionAttempts(t *testing.T) {
+
+	Convey("With an uninitialized host", t, func() {
+
+		testutil.HandleTestingErr(db.Clear(Collection), t, "Error"+
+			" clearing '%v' collection", Collection)
+
+		host := &Host{
+			Id:        "hostOne",
+			Status:    evergreen.HostUninitialized,
+			StartedBy: evergreen.User,
+		}
+		So(host.Insert(), ShouldBeNil)
+
+		Convey("marking it initializing should start its first attempt", func() {
+			So(host.SetInitializing(), ShouldBeNil)
+			So(host.ProvisionAttempts, ShouldEqual, 1)
+
+			dbHost, err := FindOne(ById(host.Id))
+			So(err, ShouldBeNil)
+			So(dbHost.Status, ShouldEqual, evergreen.HostInitializing)
+			So(dbHost.ProvisionAttempts, ShouldEqual, 1)
+			So(dbHost.ProvisionStartTime.IsZero(), ShouldBeFalse)
+
+			current, err := host.HasProvisionAttempt(1)
+			So(err, ShouldBeNil)
+			So(current, ShouldBeTrue)
+
+			// a second hostinit can't also start setting it up
+			So(dbHost.SetInitializing(), ShouldNotBeNil)
+
+			Convey("an abandoned attempt should only be resumed once", func() {
+				abandoned, err := Find(ByAbandonedInitializingSince(time.Now().Add(time.Minute)))
+				So(err, ShouldBeNil)
+				So(len(abandoned), ShouldEqual, 1)
+
+				other := abandoned[0]
+				So(abandoned[0].ResumeInitializing(), ShouldBeNil)
+				So(abandoned[0].ProvisionAttempts, ShouldEqual, 2)
+				So(host.ResumeInitializing(), ShouldNotBeNil)
+				So(other.ProvisionAttempts, ShouldEqual, 1)
+
+				current, err := host.HasProvisionAttempt(1)
+				So(err, ShouldBeNil)
+				So(current, ShouldBeFalse)
+
+				abandoned, err = Find(ByAbandonedInitializingSince(time.Now().Add(-time.Minute)))
+				So(err, ShouldBeNil)
+				So(len(abandoned), ShouldEqual, 0)
+			})
+		})
+
+	})
+}
+
 func TestHostSetRunningTask(t *testing.T) {
 
 	Convey("With a host", t, func() {
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/db/bsonutil"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+const (
+	InventoryReportsCollection = "inventory_reports"
+)
+
+// InventoryReport records the outcome of reconciling the instances a cloud
+// provider is running for a distro against the hosts collection.
+type InventoryReport struct {
+	Id        bson.ObjectId `bson:"_id"        json:"id"`
+	Distro    string        `bson:"distro"     json:"distro"`
+	Provider  string        `bson:"provider"   json:"provider"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+
+	// number of instances the provider reported
+	Instances int `bson:"instances" json:"instances"`
+
+	// tagged instances with no host document, split by whether they were
+	// terminated or are still within their grace period
+	OrphansTerminated []string `bson:"orphans_terminated,omitempty" json:"orphans_terminated,omitempty"`
+	OrphansPending    []string `bson:"orphans_pending,omitempty"    json:"orphans_pending,omitempty"`
+
+	// instances with no host document that carry no Evergreen tags, and
+	// so are left alone
+	Untagged []string `bson:"untagged,omitempty" json:"untagged,omitempty"`
+
+	// host documents whose instance no longer exists, which were marked
+	// as terminated
+	GhostHosts []string `bson:"ghost_hosts,omitempty" json:"ghost_hosts,omitempty"`
+
+	Errors []string `bson:"errors,omitempty" json:"errors,omitempty"`
+}
+
+var (
+	InventoryReportIdKey        = bsonutil.MustHaveTag(InventoryReport{}, "Id")
+	InventoryReportDistroKey    = bsonutil.MustHaveTag(InventoryReport{}, "Distro")
+	InventoryReportCreatedAtKey = bsonutil.MustHaveTag(InventoryReport{}, "CreatedAt")
+)
+
+// Insert writes the report to the database.
+func (r *InventoryReport) Insert() error {
+	if r.Id == "" {
+		r.Id = bson.NewObjectId()
+	}
+	return db.Insert(InventoryReportsCollection, r)
+}
+
+// FindLastInventoryReport returns the most recent inventory report for
+// the given distro, or nil if there is none.
+func FindLastInventoryReport(distroId string) (*InventoryReport, error) {
+	report := &InventoryReport{}
+	err := db.FindOne(
+		InventoryReportsCollection,
+		bson.M{InventoryReportDistroKey: distroId},
+		db.NoProjection,
+		[]string{"-" + InventoryReportCreatedAtKey},
+		report,
+	)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	return report, err
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/db/bsonutil"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+const (
+	ManagedImagesCollection = "managed_images"
+)
+
+// ManagedImage records an image that a distro has run its hosts from on the
+// machine behind a provider, such as a Docker host. Only images recorded
+// here are ever pruned from those machines, so that images put there by
+// anything else are left alone.
+type ManagedImage struct {
+	Endpoint string    `bson:"endpoint"  json:"endpoint"`
+	Name     string    `bson:"name"      json:"name"`
+	LastUsed time.Time `bson:"last_used" json:"last_used"`
+}
+
+var (
+	ManagedImageEndpointKey = bsonutil.MustHaveTag(ManagedImage{}, "Endpoint")
+	ManagedImageNameKey     = bsonutil.MustHaveTag(ManagedImage{}, "Name")
+	ManagedImageLastUsedKey = bsonutil.MustHaveTag(ManagedImage{}, "LastUsed")
+)
+
+// RecordManagedImage records that the named image was used on the machine
+// at the given endpoint.
+func RecordManagedImage(endpoint, name string) error {
+	_, err := db.Upsert(
+		ManagedImagesCollection,
+		bson.M{ManagedImageEndpointKey: endpoint, ManagedImageNameKey: name},
+		bson.M{"$set": bson.M{ManagedImageLastUsedKey: time.Now()}},
+	)
+	return err
+}
+
+// FindManagedImageNames returns the names of the images recorded for the
+// machine at the given endpoint.
+func FindManagedImageNames(endpoint string) ([]string, error) {
+	images := []ManagedImage{}
+	err := db.FindAll(
+		ManagedImagesCollection,
+		bson.M{ManagedImageEndpointKey: endpoint},
+		db.NoProjection,
+		db.NoSort,
+		db.NoSkip,
+		db.NoLimit,
+		&images,
+	)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(images))
+	for _, image := range images {
+		names = append(names, image.Name)
+	}
+	return names, nil
+}
+
+// RemoveManagedImage forgets the named image on the machine at the given
+// endpoint, once it has been removed from there.
+func RemoveManagedImage(endpoint, name string) error {
+	return db.RemoveAll(ManagedImagesCollection,
+		bson.M{ManagedImageEndpointKey: endpoint, ManagedImageNameKey: name})
+}
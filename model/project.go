@@ -39,6 +39,7 @@ type Project struct {
 	Pre                *YAMLCommandSet            `yaml:"pre" bson:"pre"`
 	Post               *YAMLCommandSet            `yaml:"post" bson:"post"`
 	Timeout            *YAMLCommandSet            `yaml:"timeout" bson:"timeout"`
+	TimeoutDiagnostics *TimeoutDiagnostics        `yaml:"timeout_diagnostics" bson:"timeout_diagnostics"`
 	Modules            []Module                   `yaml:"modules" bson:"modules"`
 	BuildVariants      []BuildVariant             `yaml:"buildvariants" bson:"build_variants"`
 	Functions          map[string]*YAMLCommandSet `yaml:"functions" bson:"functions"`
@@ -47,6 +48,16 @@ type Project struct {
 
 	// Flag that indicates a project as requiring user authentication
 	Private bool `yaml:"private" bson:"private"`
+
+	// how long, in seconds, the pre and post blocks and the timeout block
+	// may each run before they're stopped; zero means the agent's default
+	PreTimeoutSecs      int `yaml:"pre_timeout_secs" bson:"pre_timeout_secs"`
+	PostTimeoutSecs     int `yaml:"post_timeout_secs" bson:"post_timeout_secs"`
+	CallbackTimeoutSecs int `yaml:"callback_timeout_secs" bson:"callback_timeout_secs"`
+
+	// fail tasks as soon as a command in the pre block fails, instead of
+	// running them anyway
+	PreErrorFailsTask bool `yaml:"pre_error_fails_task" bson:"pre_error_fails_task"`
 }
 
 // Unmarshalled from the "tasks" list in an individual build variant
@@ -57,6 +68,9 @@ type BuildVariantTask struct {
 
 	// the distros that the task can be run on
 	Distros []string `yaml:"distros" bson:"distros"`
+
+	// the labels a distro must have for the task to be run on it
+	Labels []string `yaml:"labels" bson:"labels"`
 }
 
 type BuildVariant struct {
@@ -82,6 +96,10 @@ type BuildVariant struct {
 	// provided for the task
 	RunOn []string `yaml:"run_on" bson:"run_on"`
 
+	// the default labels a distro must have to run a task, if no distros
+	// or labels are provided for the task
+	RunOnLabels []string `yaml:"run_on_labels" bson:"run_on_labels"`
+
 	// all of the tasks to be run on the build variant, compile through tests.
 	Tasks                 []BuildVariantTask `yaml:"tasks" bson:"tasks"`
 	MatrixParameterValues map[string]string  `yaml:"matrix_parameter_values" bson:"matrix_parameter_values"`
@@ -180,6 +198,22 @@ func (c *YAMLCommandSet) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return err1
 }
 
+// TimeoutDiagnostics configures what the agent collects about a task that
+// times out, whether it went idle or ran past its exec timeout, before it
+// kills the task's processes. Projects that don't define it get the stack
+// dumps only. Everything collected is attached to the task as test logs.
+type TimeoutDiagnostics struct {
+	// unless this is set, the agent lists the task's processes and gets the
+	// stack of each one, with jstack for Java processes and gdb for the
+	// rest, or from /proc without those
+	DisableStackDumps bool `yaml:"disable_stack_dumps" bson:"disable_stack_dumps"`
+
+	// commands to run after the stack dumps, e.g. to attach a debugger or
+	// take a core dump; the files they write to ${timeout_diagnostics_dir}
+	// are attached along with the rest
+	Commands *YAMLCommandSet `yaml:"commands" bson:"commands"`
+}
+
 // The information about a task's dependency
 type TaskDependency struct {
 	Name    string `yaml:"name" bson:"name"`
@@ -209,6 +243,9 @@ type TaskConfig struct {
 	BuildVariant *BuildVariant
 	Expansions   *command.Expansions
 	WorkDir      string
+
+	// the names of the expansions that hold private project variables
+	PrivateExpansions map[string]bool
 }
 
 // TaskIdTable is a map of [variant, task display name]->[task id].
@@ -313,7 +350,7 @@ func NewTaskConfig(d *distro.Distro, p *Project, t *Task, r *ProjectRef) (*TaskC
 	}
 
 	e := populateExpansions(d, bv, t)
-	return &TaskConfig{d, r, p, t, bv, e, d.WorkDir}, nil
+	return &TaskConfig{d, r, p, t, bv, e, d.WorkDir, map[string]bool{}}, nil
 }
 
 func populateExpansions(d *distro.Distro, bv *BuildVariant, t *Task) *command.Expansions {
@@ -1,19 +1,28 @@
 package model
 
 import (
+	"fmt"
 	"github.com/evergreen-ci/evergreen/db"
 	"github.com/evergreen-ci/evergreen/db/bsonutil"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"sort"
+	"strings"
 )
 
 var (
 	ProjectVarIdKey   = bsonutil.MustHaveTag(ProjectVars{}, "Id")
 	ProjectVarsMapKey = bsonutil.MustHaveTag(ProjectVars{}, "Vars")
+	PrivateVarsMapKey = bsonutil.MustHaveTag(ProjectVars{}, "PrivateVars")
 )
 
 const (
 	ProjectVarsCollection = "project_vars"
+
+	// MinPrivateVarLength is the length of the shortest value a private
+	// variable may have. Shorter values would turn up in too many unrelated
+	// messages to be redacted from task logs.
+	MinPrivateVarLength = 4
 )
 
 //ProjectVars holds a map of variables specific to a given project.
@@ -27,6 +36,10 @@ type ProjectVars struct {
 
 	//The actual mapping of variables for this project
 	Vars map[string]string `bson:"vars" json:"vars"`
+
+	//The names of variables whose values are private: they are redacted
+	//from task logs and never shown in the UI
+	PrivateVars map[string]bool `bson:"private_vars" json:"private_vars"`
 }
 
 func FindOneProjectVars(projectId string) (*ProjectVars, error) {
@@ -58,7 +71,46 @@ func (projectVars *ProjectVars) Upsert() (*mgo.ChangeInfo, error) {
 		bson.M{
 			"$set": bson.M{
 				ProjectVarsMapKey: projectVars.Vars,
+				PrivateVarsMapKey: projectVars.PrivateVars,
 			},
 		},
 	)
 }
+
+// RedactPrivateVars returns a copy of the project variables with the values
+// of the private ones blanked out, for showing to users.
+func (projectVars *ProjectVars) RedactPrivateVars() *ProjectVars {
+	redacted := &ProjectVars{
+		Id:          projectVars.Id,
+		Vars:        map[string]string{},
+		PrivateVars: map[string]bool{},
+	}
+	for name, value := range projectVars.Vars {
+		if projectVars.PrivateVars[name] {
+			value = ""
+		}
+		redacted.Vars[name] = value
+	}
+	for name, private := range projectVars.PrivateVars {
+		redacted.PrivateVars[name] = private
+	}
+	return redacted
+}
+
+// ValidatePrivateVars returns an error naming the private variables whose
+// values are too short to be redacted from task logs.
+func (projectVars *ProjectVars) ValidatePrivateVars() error {
+	short := []string{}
+	for name, private := range projectVars.PrivateVars {
+		value := projectVars.Vars[name]
+		if private && value != "" && len(value) < MinPrivateVarLength {
+			short = append(short, name)
+		}
+	}
+	if len(short) == 0 {
+		return nil
+	}
+	sort.Strings(short)
+	return fmt.Errorf("the values of private variables must be at least %v characters long,"+
+		" so they can be redacted from task logs: %v", MinPrivateVarLength, strings.Join(short, ", "))
+}
@@ -31,3 +31,42 @@ func TestFindOneProjectVar(t *testing.T) {
 		})
 	})
 }
+
+func TestRedactPrivateVars(t *testing.T) {
+	Convey("With project vars, some of which are private", t, func() {
+		projectVars := &ProjectVars{
+			Id:          "mongodb",
+			Vars:        map[string]string{"a": "b", "token": "secret"},
+			PrivateVars: map[string]bool{"token": true},
+		}
+
+		Convey("redacting them should blank out only the private values", func() {
+			redacted := projectVars.RedactPrivateVars()
+			So(redacted.Vars, ShouldResemble, map[string]string{"a": "b", "token": ""})
+			So(redacted.PrivateVars["token"], ShouldBeTrue)
+			So(projectVars.Vars["token"], ShouldEqual, "secret")
+		})
+	})
+}
+
+func TestValidatePrivateVars(t *testing.T) {
+	Convey("With project vars, some of which are private", t, func() {
+		projectVars := &ProjectVars{
+			Id:          "mongodb",
+			Vars:        map[string]string{"a": "b", "token": "secret", "pin": "123", "blank": ""},
+			PrivateVars: map[string]bool{"token": true, "blank": true},
+		}
+
+		Convey("private values long enough to redact should be accepted", func() {
+			So(projectVars.ValidatePrivateVars(), ShouldBeNil)
+		})
+
+		Convey("private values too short to redact should be rejected", func() {
+			projectVars.PrivateVars["pin"] = true
+			err := projectVars.ValidatePrivateVars()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "pin")
+			So(err.Error(), ShouldNotContainSubstring, "token")
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"fmt"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/db/bsonutil"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const ResourceStatsCollection = "task_resource_stats"
+
+// TaskResourceStats holds the resource samples the agent took while running
+// an execution of a task.
+type TaskResourceStats struct {
+	Id        string                     `bson:"_id" json:"_id"`
+	TaskId    string                     `bson:"task_id" json:"task_id"`
+	Execution int                        `bson:"execution" json:"execution"`
+	Samples   []apimodels.ResourceSample `bson:"samples" json:"samples"`
+}
+
+var (
+	ResourceStatsIdKey        = bsonutil.MustHaveTag(TaskResourceStats{}, "Id")
+	ResourceStatsTaskIdKey    = bsonutil.MustHaveTag(TaskResourceStats{}, "TaskId")
+	ResourceStatsExecutionKey = bsonutil.MustHaveTag(TaskResourceStats{}, "Execution")
+	ResourceStatsSamplesKey   = bsonutil.MustHaveTag(TaskResourceStats{}, "Samples")
+)
+
+// resourceStatsId returns the id of the stats for an execution of a task.
+func resourceStatsId(taskId string, execution int) string {
+	return fmt.Sprintf("%v_%v", taskId, execution)
+}
+
+// AppendResourceSamples adds the samples to those stored for an execution of
+// a task.
+func AppendResourceSamples(taskId string, execution int, samples []apimodels.ResourceSample) error {
+	_, err := db.Upsert(
+		ResourceStatsCollection,
+		bson.M{
+			ResourceStatsIdKey: resourceStatsId(taskId, execution),
+		},
+		bson.M{
+			"$set": bson.M{
+				ResourceStatsTaskIdKey:    taskId,
+				ResourceStatsExecutionKey: execution,
+			},
+			"$push": bson.M{
+				ResourceStatsSamplesKey: bson.M{"$each": samples},
+			},
+		},
+	)
+	return err
+}
+
+// FindTaskResourceStats returns the resource samples stored for an
+// execution of a task, or nil if there are none.
+func FindTaskResourceStats(taskId string, execution int) (*TaskResourceStats, error) {
+	stats := &TaskResourceStats{}
+	err := db.FindOne(
+		ResourceStatsCollection,
+		bson.M{
+			ResourceStatsIdKey: resourceStatsId(taskId, execution),
+		},
+		db.NoProjection,
+		db.NoSort,
+		stats,
+	)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	return stats, err
+}
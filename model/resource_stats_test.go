@@ -0,0 +1,43 @@
+package model
+
+import (
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestAppendResourceSamples(t *testing.T) {
+	Convey("With no resource stats stored", t, func() {
+		testutil.HandleTestingErr(
+			db.Clear(ResourceStatsCollection), t,
+			"error clearing resource stats collection")
+
+		Convey("samples appended for a task should be stored in order", func() {
+			So(AppendResourceSamples("task", 1, []apimodels.ResourceSample{
+				{CPUPercent: 10}, {CPUPercent: 20},
+			}), ShouldBeNil)
+			So(AppendResourceSamples("task", 1, []apimodels.ResourceSample{
+				{CPUPercent: 30},
+			}), ShouldBeNil)
+
+			stats, err := FindTaskResourceStats("task", 1)
+			So(err, ShouldBeNil)
+			So(stats, ShouldNotBeNil)
+			So(stats.TaskId, ShouldEqual, "task")
+			So(len(stats.Samples), ShouldEqual, 3)
+			So(stats.Samples[2].CPUPercent, ShouldEqual, 30)
+		})
+
+		Convey("each execution of a task should have its own samples", func() {
+			So(AppendResourceSamples("task", 0, []apimodels.ResourceSample{
+				{CPUPercent: 10},
+			}), ShouldBeNil)
+
+			stats, err := FindTaskResourceStats("task", 1)
+			So(err, ShouldBeNil)
+			So(stats, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/db/bsonutil"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+const (
+	SpotFallbacksCollection = "spot_fallbacks"
+)
+
+// SpotFallbackCount tracks how often hosts of a spot distro have had to be
+// replaced by on-demand hosts, which is a sign that its bid is too low.
+type SpotFallbackCount struct {
+	DistroId     string    `bson:"_id"           json:"distro_id"`
+	Count        int       `bson:"count"         json:"count"`
+	LastFallback time.Time `bson:"last_fallback" json:"last_fallback"`
+}
+
+var (
+	SpotFallbackDistroIdKey     = bsonutil.MustHaveTag(SpotFallbackCount{}, "DistroId")
+	SpotFallbackCountKey        = bsonutil.MustHaveTag(SpotFallbackCount{}, "Count")
+	SpotFallbackLastFallbackKey = bsonutil.MustHaveTag(SpotFallbackCount{}, "LastFallback")
+)
+
+// IncSpotFallbackCount records a fallback for the given distro.
+func IncSpotFallbackCount(distroId string) error {
+	_, err := db.Upsert(
+		SpotFallbacksCollection,
+		bson.M{SpotFallbackDistroIdKey: distroId},
+		bson.M{
+			"$inc": bson.M{SpotFallbackCountKey: 1},
+			"$set": bson.M{SpotFallbackLastFallbackKey: time.Now()},
+		},
+	)
+	return err
+}
+
+// FindAllSpotFallbackCounts returns the fallback counts of every distro
+// that has had a fallback.
+func FindAllSpotFallbackCounts() ([]SpotFallbackCount, error) {
+	counts := []SpotFallbackCount{}
+	err := db.FindAll(
+		SpotFallbacksCollection,
+		bson.M{},
+		db.NoProjection,
+		db.NoSort,
+		db.NoSkip,
+		db.NoLimit,
+		&counts,
+	)
+	return counts, err
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestIncSpotFallbackCount(t *testing.T) {
+	Convey("When counting spot fallbacks", t, func() {
+		testutil.HandleTestingErr(db.Clear(SpotFallbacksCollection), t,
+			"error clearing spot fallbacks collection")
+
+		Convey("each distro should get its own running count", func() {
+			So(IncSpotFallbackCount("d1"), ShouldBeNil)
+			So(IncSpotFallbackCount("d1"), ShouldBeNil)
+			So(IncSpotFallbackCount("d2"), ShouldBeNil)
+
+			counts, err := FindAllSpotFallbackCounts()
+			So(err, ShouldBeNil)
+			So(len(counts), ShouldEqual, 2)
+			for _, c := range counts {
+				switch c.DistroId {
+				case "d1":
+					So(c.Count, ShouldEqual, 2)
+				case "d2":
+					So(c.Count, ShouldEqual, 1)
+				}
+				So(c.LastFallback.IsZero(), ShouldBeFalse)
+			}
+		})
+	})
+}
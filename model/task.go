@@ -96,6 +96,9 @@ type Task struct {
 	// test results captured and sent back by agent
 	TestResults []TestResult `bson:"test_results" json:"test_results"`
 
+	// records of the commands the agent ran, in the order it ran them
+	CommandRecords []apimodels.CommandRecord `bson:"command_records,omitempty" json:"command_records,omitempty"`
+
 	// position in queue for the queue where it's closest to the top
 	MinQueuePos int `bson:"min_queue_pos" json:"min_queue_pos,omitempty"`
 }
@@ -186,6 +189,7 @@ var (
 	TaskTimeTakenKey           = bsonutil.MustHaveTag(Task{}, "TimeTaken")
 	TaskExpectedDurationKey    = bsonutil.MustHaveTag(Task{}, "ExpectedDuration")
 	TaskTestResultsKey         = bsonutil.MustHaveTag(Task{}, "TestResults")
+	TaskCommandRecordsKey      = bsonutil.MustHaveTag(Task{}, "CommandRecords")
 	TaskPriorityKey            = bsonutil.MustHaveTag(Task{}, "Priority")
 	TaskMinQueuePosKey         = bsonutil.MustHaveTag(Task{}, "MinQueuePos")
 
@@ -780,10 +784,11 @@ func (t *Task) MarkAsDispatched(host *host.Host, dispatchTime time.Time) error {
 				TaskDistroIdKey:      host.Distro.Id,
 			},
 			"$unset": bson.M{
-				TaskAbortedKey:     "",
-				TaskTestResultsKey: "",
-				TaskDetailsKey:     "",
-				TaskMinQueuePosKey: "",
+				TaskAbortedKey:        "",
+				TaskTestResultsKey:    "",
+				TaskCommandRecordsKey: "",
+				TaskDetailsKey:        "",
+				TaskMinQueuePosKey:    "",
 			},
 		},
 	)
@@ -818,14 +823,15 @@ func (task *Task) MarkAsUndispatched(host *host.Host) error {
 				TaskStatusKey: evergreen.TaskUndispatched,
 			},
 			"$unset": bson.M{
-				TaskDispatchTimeKey:  ZeroTime,
-				TaskLastHeartbeatKey: ZeroTime,
-				TaskDistroIdKey:      "",
-				TaskHostIdKey:        "",
-				TaskAbortedKey:       "",
-				TaskTestResultsKey:   "",
-				TaskDetailsKey:       "",
-				TaskMinQueuePosKey:   "",
+				TaskDispatchTimeKey:   ZeroTime,
+				TaskLastHeartbeatKey:  ZeroTime,
+				TaskDistroIdKey:       "",
+				TaskHostIdKey:         "",
+				TaskAbortedKey:        "",
+				TaskTestResultsKey:    "",
+				TaskCommandRecordsKey: "",
+				TaskDetailsKey:        "",
+				TaskMinQueuePosKey:    "",
 			},
 		},
 	)
@@ -1090,7 +1096,8 @@ func (t *Task) reset() error {
 			TaskTestResultsKey:   []TestResult{},
 		},
 		"$unset": bson.M{
-			TaskDetailsKey: "",
+			TaskDetailsKey:        "",
+			TaskCommandRecordsKey: "",
 		},
 	}
 
@@ -1441,6 +1448,20 @@ func (t *Task) SetResults(results []TestResult) error {
 	)
 }
 
+// AddCommandRecord appends a record of one of the task's commands.
+func (t *Task) AddCommandRecord(record apimodels.CommandRecord) error {
+	return UpdateOneTask(
+		bson.M{
+			TaskIdKey: t.Id,
+		},
+		bson.M{
+			"$push": bson.M{
+				TaskCommandRecordsKey: record,
+			},
+		},
+	)
+}
+
 func (t *Task) MarkUnscheduled() error {
 	return UpdateOneTask(
 		bson.M{
@@ -31,7 +31,8 @@ var (
 )
 
 var (
-	SettingsTZKey = bsonutil.MustHaveTag(UserSettings{}, "Timezone")
+	SettingsTZKey                 = bsonutil.MustHaveTag(UserSettings{}, "Timezone")
+	SettingsSpawnHostIdleHoursKey = bsonutil.MustHaveTag(UserSettings{}, "SpawnHostIdleHours")
 )
 
 func ById(userId string) db.Q {
@@ -27,8 +27,31 @@ type PubKey struct {
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 }
 
+const (
+	// how long a user's spawn hosts may go unused before they are
+	// terminated, if the user hasn't chosen otherwise
+	DefaultSpawnHostIdleTimeout = 24 * time.Hour
+
+	// the longest idle timeout a user may choose, in hours
+	MaxSpawnHostIdleHours = 7 * 24
+)
+
 type UserSettings struct {
 	Timezone string `json:"timezone" bson:"timezone"`
+
+	// the number of hours the user's spawn hosts may go unused before they
+	// are terminated; zero means the default. Idle hosts are terminated
+	// rather than stopped, so anything left on them is lost
+	SpawnHostIdleHours int `json:"spawn_host_idle_hours" bson:"spawn_host_idle_hours,omitempty"`
+}
+
+// SpawnHostIdleTimeout returns how long the user's spawn hosts may go unused
+// before they are terminated.
+func (s UserSettings) SpawnHostIdleTimeout() time.Duration {
+	if s.SpawnHostIdleHours <= 0 {
+		return DefaultSpawnHostIdleTimeout
+	}
+	return time.Duration(s.SpawnHostIdleHours) * time.Hour
 }
 
 func (u *DBUser) Username() string {
@@ -91,3 +114,17 @@ func (u *DBUser) IncPatchNumber() (int, error) {
 	return dbUser.PatchNumber, nil
 
 }
+
+// FindSpawnHostIdleTimeout returns how long the given user's spawn hosts may
+// go unused before they are terminated. Users with no document get the
+// default.
+func FindSpawnHostIdleTimeout(userId string) (time.Duration, error) {
+	u, err := FindOne(ById(userId))
+	if err != nil {
+		return 0, err
+	}
+	if u == nil {
+		return DefaultSpawnHostIdleTimeout, nil
+	}
+	return u.Settings.SpawnHostIdleTimeout(), nil
+}
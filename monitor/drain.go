@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+)
+
+// reportDrainedHosts is a hostMonitoringFunc responsible for finding static
+// hosts that have finished their last task since being put into drain mode,
+// either by themselves or along with their distro. Each is reported once, so
+// that it can be taken down for maintenance. Cloud hosts are left to the
+// idle host checks.
+func reportDrainedHosts(settings *evergreen.Settings) []error {
+
+	evergreen.Logger.Logf(slogger.INFO, "Checking for drained hosts...")
+
+	// used to store any errors that occur
+	var errors []error
+
+	drainingDistros, err := distro.Find(distro.ByDraining())
+	if err != nil {
+		errors = append(errors, fmt.Errorf("error finding draining distros: %v", err))
+		return errors
+	}
+	drainingDistroIds := make([]string, 0, len(drainingDistros))
+	for _, d := range drainingDistros {
+		drainingDistroIds = append(drainingDistroIds, d.Id)
+	}
+
+	// forget about hosts that were drained but have since been put back
+	// into service, so they are reported again if drained again
+	if err = host.ClearUndrainedTimes(drainingDistroIds); err != nil {
+		errors = append(errors, fmt.Errorf("error clearing drained time of"+
+			" undrained hosts: %v", err))
+	}
+
+	hosts, err := host.Find(host.ByDrainedUnreported(evergreen.HostTypeStatic,
+		drainingDistroIds))
+	if err != nil {
+		errors = append(errors, fmt.Errorf("error finding drained hosts: %v", err))
+		return errors
+	}
+
+	// continue on error so that other hosts can be reported
+	for _, h := range hosts {
+		evergreen.Logger.Logf(slogger.INFO, "Host %v of distro %v is drained"+
+			" and idle", h.Id, h.Distro.Id)
+		if err := h.SetDrained(); err != nil {
+			errors = append(errors, fmt.Errorf("error marking host %v"+
+				" drained: %v", h.Id, err))
+		}
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Finished checking for drained hosts")
+
+	return errors
+}
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestReportDrainedHosts(t *testing.T) {
+
+	testConfig := evergreen.TestConfig()
+
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testConfig))
+
+	Convey("When checking for drained hosts", t, func() {
+
+		testutil.HandleTestingErr(db.ClearCollections(host.Collection,
+			distro.Collection), t, "error clearing collections")
+
+		drainingDistro := &distro.Distro{Id: "draining", Draining: true}
+		testutil.HandleTestingErr(drainingDistro.Insert(), t, "error inserting distro")
+		activeDistro := &distro.Distro{Id: "active"}
+		testutil.HandleTestingErr(activeDistro.Insert(), t, "error inserting distro")
+
+		hosts := []host.Host{
+			{Id: "draining-idle", Distro: *activeDistro, Draining: true,
+				Provider: evergreen.HostTypeStatic, Status: evergreen.HostRunning},
+			{Id: "draining-busy", Distro: *activeDistro, Draining: true,
+				RunningTask: "t1", Provider: evergreen.HostTypeStatic,
+				Status: evergreen.HostRunning},
+			{Id: "distro-draining-idle", Distro: *drainingDistro,
+				Provider: evergreen.HostTypeStatic, Status: evergreen.HostRunning},
+			{Id: "active-idle", Distro: *activeDistro,
+				Provider: evergreen.HostTypeStatic, Status: evergreen.HostRunning},
+			{Id: "cloud-draining-idle", Distro: *activeDistro, Draining: true,
+				Provider: "ec2", Status: evergreen.HostRunning},
+		}
+		for _, h := range hosts {
+			testutil.HandleTestingErr(h.Insert(), t, "error inserting host")
+		}
+
+		So(reportDrainedHosts(testConfig), ShouldBeNil)
+
+		Convey("only idle static hosts that are draining should be marked"+
+			" drained", func() {
+			for _, h := range hosts {
+				found, err := host.FindOne(host.ById(h.Id))
+				So(err, ShouldBeNil)
+				drained := h.Id == "draining-idle" || h.Id == "distro-draining-idle"
+				So(found.DrainedTime.IsZero(), ShouldEqual, !drained)
+			}
+		})
+
+		Convey("hosts that are taken out of drain mode should be forgotten",
+			func() {
+				drainingDistro.Draining = false
+				So(drainingDistro.Update(), ShouldBeNil)
+
+				So(reportDrainedHosts(testConfig), ShouldBeNil)
+
+				h, err := host.FindOne(host.ById("distro-draining-idle"))
+				So(err, ShouldBeNil)
+				So(h.DrainedTime.IsZero(), ShouldBeTrue)
+
+				h, err = host.FindOne(host.ById("draining-idle"))
+				So(err, ShouldBeNil)
+				So(h.DrainedTime.IsZero(), ShouldBeFalse)
+			})
+
+	})
+
+}
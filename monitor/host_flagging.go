@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"github.com/10gen-labs/slogger/v1"
 	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/alerts"
 	"github.com/evergreen-ci/evergreen/cloud/providers"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/model/user"
 	"time"
 )
 
@@ -20,6 +22,13 @@ const (
 // and spits out a list of hosts to be terminated
 type hostFlaggingFunc func([]distro.Distro, *evergreen.Settings) ([]host.Host, error)
 
+// a hostFlaggingFunc, along with the termination reason recorded for the
+// hosts it flags
+type hostFlagger struct {
+	flag   hostFlaggingFunc
+	reason string
+}
+
 // flagDecommissionedHosts is a hostFlaggingFunc to get all hosts which should
 // be terminated because they are decommissioned
 func flagDecommissionedHosts(d []distro.Distro, s *evergreen.Settings) ([]host.Host, error) {
@@ -112,9 +121,18 @@ func flagExcessHosts(distros []distro.Distro, s *evergreen.Settings) ([]host.Hos
 			return nil, fmt.Errorf("error fetching hosts for distro %v: %v", d.Id, err)
 		}
 
+		// hosts being recycled are briefly doubled up with their
+		// replacements, so they don't count towards max hosts
+		numRecyclingHosts := 0
+		for _, host := range allHostsForDistro {
+			if host.ReplacementId != "" {
+				numRecyclingHosts++
+			}
+		}
+
 		// if there are more than the specified max hosts, then terminate
 		// some, if they are not running tasks
-		numExcessHosts := len(allHostsForDistro) - d.PoolSize
+		numExcessHosts := len(allHostsForDistro) - numRecyclingHosts - d.PoolSize
 		if numExcessHosts > 0 {
 
 			// track how many hosts for the distro are terminated
@@ -214,6 +232,78 @@ func flagExpiredHosts(d []distro.Distro, s *evergreen.Settings) ([]host.Host, er
 
 }
 
+// flagRetiredHosts is a hostFlaggingFunc to get all hosts that were
+// replaced for outliving their distro's max lifetime, and have since
+// finished their last task
+func flagRetiredHosts(d []distro.Distro, s *evergreen.Settings) ([]host.Host, error) {
+
+	evergreen.Logger.Logf(slogger.INFO, "Finding retired hosts...")
+
+	hosts, err := host.Find(host.IsRetiredAndFree)
+	if err != nil {
+		return nil, fmt.Errorf("error finding retired hosts: %v", err)
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Found %v retired hosts", len(hosts))
+
+	return hosts, nil
+
+}
+
+// flagIdleSpawnHosts is a hostFlaggingFunc to get all user-spawned hosts
+// that have gone unused for longer than their user's idle timeout. A host
+// is only flagged once its user has been warned, and has had the warning's
+// full lead time to start using it again.
+func flagIdleSpawnHosts(d []distro.Distro, s *evergreen.Settings) ([]host.Host, error) {
+
+	evergreen.Logger.Logf(slogger.INFO, "Finding idle spawn hosts...")
+
+	hosts, err := host.Find(host.IsRunningAndSpawned)
+	if err != nil {
+		return nil, fmt.Errorf("error finding spawned hosts: %v", err)
+	}
+
+	// cache the idle timeouts of the hosts' users
+	timeouts := map[string]time.Duration{}
+
+	idleHosts := []host.Host{}
+	for _, h := range hosts {
+		// only act on recent activity checks, so a host isn't terminated
+		// because the checks stopped running
+		if h.Status != evergreen.HostRunning ||
+			time.Since(h.LastActivityCheck) > 2*SpawnActivityCheckInterval {
+			continue
+		}
+
+		timeout, ok := timeouts[h.StartedBy]
+		if !ok {
+			timeout, err = user.FindSpawnHostIdleTimeout(h.StartedBy)
+			if err != nil {
+				return nil, fmt.Errorf("error finding idle timeout of user %v: %v",
+					h.StartedBy, err)
+			}
+			timeouts[h.StartedBy] = timeout
+		}
+		if h.UserIdleTime() < timeout {
+			continue
+		}
+
+		warned, err := alerts.LastSpawnIdleWarning(&h)
+		if err != nil {
+			return nil, fmt.Errorf("error finding idle warning for host %v: %v",
+				h.Id, err)
+		}
+		if warned.IsZero() || time.Since(warned) < timeout-alerts.SpawnIdleWarningTime(timeout) {
+			continue
+		}
+		idleHosts = append(idleHosts, h)
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Found %v idle spawn hosts", len(idleHosts))
+
+	return idleHosts, nil
+}
+
 // helper to check if a host can be terminated
 func hostCanBeTerminated(h host.Host, s *evergreen.Settings) (bool, error) {
 
@@ -108,7 +108,8 @@ func checkHostReachability(host host.Host,
 			" db status to terminated", host.Id)
 
 		// the instance was terminated from outside our control
-		if err := host.SetTerminated(); err != nil {
+		host.TerminationReason = evergreen.TerminationReasonExternal
+		if err := host.Terminate(); err != nil {
 			return fmt.Errorf("error setting host %v terminated: %v",
 				host.Id, err)
 		}
@@ -15,7 +15,7 @@ import (
 // responsible for running regular monitoring of hosts
 type HostMonitor struct {
 	// will be used to determine what hosts need to be terminated
-	flaggingFuncs []hostFlaggingFunc
+	flaggingFuncs []hostFlagger
 
 	// will be used to perform regular checks on hosts
 	monitoringFuncs []hostMonitoringFunc
@@ -58,7 +58,7 @@ func (self *HostMonitor) CleanupHosts(distros []distro.Distro, settings *evergre
 
 	for idx, f := range self.flaggingFuncs {
 		// find the next batch of hosts to terminate
-		hostsToTerminate, err := f(distros, settings)
+		hostsToTerminate, err := f.flag(distros, settings)
 
 		// continuing on error so that one wonky flagging function doesn't
 		// stop others from running
@@ -73,7 +73,7 @@ func (self *HostMonitor) CleanupHosts(distros []distro.Distro, settings *evergre
 
 		// terminate all of the dead hosts. continue on error to allow further
 		// termination to work
-		if errs := terminateHosts(hostsToTerminate, settings); errs != nil {
+		if errs := terminateHosts(hostsToTerminate, f.reason, settings); errs != nil {
 			for _, err := range errs {
 				errors = append(errors, fmt.Errorf("error terminating host:"+
 					" %v", err))
@@ -87,9 +87,9 @@ func (self *HostMonitor) CleanupHosts(distros []distro.Distro, settings *evergre
 
 }
 
-// terminate the passed-in slice of hosts. returns any errors that occur
-// terminating the hosts
-func terminateHosts(hosts []host.Host, settings *evergreen.Settings) []error {
+// terminate the passed-in slice of hosts, recording the given reason on
+// each. returns any errors that occur terminating the hosts
+func terminateHosts(hosts []host.Host, reason string, settings *evergreen.Settings) []error {
 
 	// used to store any errors that occur
 	var errors []error
@@ -102,7 +102,8 @@ func terminateHosts(hosts []host.Host, settings *evergreen.Settings) []error {
 
 	for _, h := range hosts {
 
-		evergreen.Logger.Logf(slogger.INFO, "Terminating host %v...", h.Id)
+		evergreen.Logger.Logf(slogger.INFO, "Terminating host %v (%v)...", h.Id, reason)
+		h.TerminationReason = reason
 
 		waitGroup.Add(1)
 
@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/model/distro"
+)
+
+// pruneImages is a hostMonitoringFunc responsible for removing unused
+// images that Evergreen put on the machines that back each provider's hosts, such as
+// Docker hosts, so that they don't run out of disk.
+func pruneImages(settings *evergreen.Settings) []error {
+
+	evergreen.Logger.Logf(slogger.INFO, "Pruning unused images...")
+
+	// used to store any errors that occur
+	var errors []error
+
+	distros, err := distro.Find(distro.All)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("error finding distros: %v", err))
+		return errors
+	}
+
+	// each provider prunes all of its distros at once, since they may
+	// share the machines their images are on
+	byProvider := map[string][]distro.Distro{}
+	for _, d := range distros {
+		byProvider[d.Provider] = append(byProvider[d.Provider], d)
+	}
+
+	// continue on error so that other providers can be pruned
+	for provider, providerDistros := range byProvider {
+		cloudManager, err := providers.GetCloudManager(provider, settings)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("error getting cloud manager"+
+				" for provider %v: %v", provider, err))
+			continue
+		}
+		pruner, ok := cloudManager.(cloud.ImagePruner)
+		if !ok {
+			continue
+		}
+
+		pruned, err := pruner.PruneImages(providerDistros)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("error pruning images for"+
+				" provider %v: %v", provider, err))
+		}
+		if len(pruned) > 0 {
+			evergreen.Logger.Logf(slogger.INFO, "Pruned %v unused images for"+
+				" provider %v", len(pruned), provider)
+		}
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Finished pruning unused images")
+
+	return errors
+}
@@ -0,0 +1,217 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"gopkg.in/mgo.v2"
+	"time"
+)
+
+const (
+	// how long an instance or host may exist before a disagreement between
+	// the provider and the database is acted upon
+	InventoryGracePeriod = 30 * time.Minute
+)
+
+// reconcileInventory is a hostMonitoringFunc responsible for comparing the
+// instances each cloud provider is running against the hosts collection.
+// Tagged instances with no host document are terminated, and hosts whose
+// instance no longer exists are marked as terminated. A report is written
+// for every distro whose provider can list its instances.
+func reconcileInventory(settings *evergreen.Settings) []error {
+
+	evergreen.Logger.Logf(slogger.INFO, "Running cloud inventory reconciliation...")
+
+	// used to store any errors that occur
+	var errors []error
+
+	distros, err := distro.Find(distro.All)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("error finding distros: %v", err))
+		return errors
+	}
+
+	// continue on error so that other distros can be reconciled
+	for _, d := range distros {
+		report, err := reconcileDistroInventory(&d, settings)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("error reconciling inventory"+
+				" for distro %v: %v", d.Id, err))
+			continue
+		}
+
+		// the distro's provider can't list its instances
+		if report == nil {
+			continue
+		}
+
+		evergreen.Logger.Logf(slogger.INFO, "Inventory of distro %v: %v instances,"+
+			" %v orphans terminated, %v orphans pending, %v untagged, %v ghost hosts",
+			d.Id, report.Instances, len(report.OrphansTerminated),
+			len(report.OrphansPending), len(report.Untagged), len(report.GhostHosts))
+
+		if err := report.Insert(); err != nil {
+			errors = append(errors, fmt.Errorf("error saving inventory report"+
+				" for distro %v: %v", d.Id, err))
+		}
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Finished running cloud inventory reconciliation")
+
+	return errors
+}
+
+// reconcileDistroInventory reconciles the instances the distro's provider
+// is running for it. Returns a nil report if the provider can't list its
+// instances.
+func reconcileDistroInventory(d *distro.Distro,
+	settings *evergreen.Settings) (*model.InventoryReport, error) {
+
+	cloudManager, err := providers.GetCloudManager(d.Provider, settings)
+	if err != nil {
+		return nil, fmt.Errorf("error getting cloud manager: %v", err)
+	}
+	lister, ok := cloudManager.(cloud.InstanceLister)
+	if !ok {
+		return nil, nil
+	}
+
+	instances, err := lister.ListInstances(d)
+	if err != nil {
+		return nil, fmt.Errorf("error listing instances: %v", err)
+	}
+
+	// compare against the hosts of every distro using the provider, since
+	// distros may share the instances' backing, e.g. a docker host
+	hosts, err := host.Find(host.ByUnterminatedProvider(d.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("error finding hosts: %v", err)
+	}
+	known := map[string]bool{}
+	for _, h := range hosts {
+		known[h.Id] = true
+		if h.Tag != "" {
+			known[h.Tag] = true
+		}
+	}
+
+	now := time.Now()
+	report := &model.InventoryReport{
+		Distro:    d.Id,
+		Provider:  d.Provider,
+		CreatedAt: now,
+		Instances: len(instances),
+	}
+
+	// find instances with no host document
+	listed := map[string]bool{}
+	for _, instance := range instances {
+		listed[instance.Id] = true
+		if instance.Tag != "" {
+			listed[instance.Tag] = true
+		}
+
+		if known[instance.Id] || (instance.Tag != "" && known[instance.Tag]) {
+			continue
+		}
+
+		// leave alone anything we can't be sure Evergreen created
+		if instance.Tag == "" {
+			report.Untagged = append(report.Untagged, instance.Id)
+			continue
+		}
+
+		// the host document may not have been written yet
+		if now.Sub(instance.LaunchTime) < InventoryGracePeriod {
+			report.OrphansPending = append(report.OrphansPending, instance.Id)
+			continue
+		}
+
+		evergreen.Logger.Logf(slogger.INFO, "Terminating orphaned instance %v (%v) of distro %v",
+			instance.Id, instance.Tag, d.Id)
+		if err := terminateOrphan(d, instance, settings); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("error terminating"+
+				" orphaned instance %v: %v", instance.Id, err))
+			continue
+		}
+		report.OrphansTerminated = append(report.OrphansTerminated, instance.Id)
+	}
+
+	// find hosts whose instance is gone. uninitialized hosts are skipped,
+	// since they may not have an instance yet
+	for _, h := range hosts {
+		if h.Distro.Id != d.Id || h.Status == evergreen.HostUninitialized {
+			continue
+		}
+		if listed[h.Id] || (h.Tag != "" && listed[h.Tag]) {
+			continue
+		}
+		if now.Sub(h.CreationTime) < InventoryGracePeriod {
+			continue
+		}
+
+		// the listing only covers instances the provider can attribute to
+		// the distro, so make sure the instance is really gone
+		if gone, err := isInstanceGone(&h, settings); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		} else if !gone {
+			continue
+		}
+
+		evergreen.Logger.Logf(slogger.INFO, "Host %v of distro %v no longer has an instance;"+
+			" marking it terminated", h.Id, d.Id)
+		h.TerminationReason = evergreen.TerminationReasonExternal
+		if err := h.Terminate(); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("error marking"+
+				" host %v terminated: %v", h.Id, err))
+			continue
+		}
+		report.GhostHosts = append(report.GhostHosts, h.Id)
+	}
+
+	return report, nil
+}
+
+// isInstanceGone returns whether the provider reports the host's instance
+// as terminated. A failure to look the instance up is returned, so that the
+// host is left alone until a later pass can tell for sure.
+func isInstanceGone(h *host.Host, settings *evergreen.Settings) (bool, error) {
+	cloudHost, err := providers.GetCloudHost(h, settings)
+	if err != nil {
+		return false, fmt.Errorf("error getting cloud host for %v: %v", h.Id, err)
+	}
+	status, err := cloudHost.GetInstanceStatus()
+	if err != nil {
+		return false, fmt.Errorf("error getting status of host %v: %v", h.Id, err)
+	}
+	return status == cloud.StatusTerminated, nil
+}
+
+// terminateOrphan terminates an instance that has no host document.
+func terminateOrphan(d *distro.Distro, instance cloud.CloudInstance,
+	settings *evergreen.Settings) error {
+
+	// terminate through a stand-in host; when the provider goes to mark it
+	// terminated in the database, there is nothing to update
+	orphan := &host.Host{
+		Id:       instance.Id,
+		Tag:      instance.Tag,
+		Distro:   *d,
+		Provider: d.Provider,
+	}
+	cloudHost, err := providers.GetCloudHost(orphan, settings)
+	if err != nil {
+		return fmt.Errorf("error getting cloud host: %v", err)
+	}
+	if err := cloudHost.TerminateInstance(); err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+	return nil
+}
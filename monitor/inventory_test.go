@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/cloud/providers/mock"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestReconcileInventory(t *testing.T) {
+
+	testConfig := evergreen.TestConfig()
+
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testConfig))
+
+	Convey("When reconciling a distro's cloud inventory", t, func() {
+
+		testutil.HandleTestingErr(db.ClearCollections(host.Collection,
+			distro.Collection, model.InventoryReportsCollection), t,
+			"error clearing collections")
+
+		d := &distro.Distro{Id: "d1", Provider: mock.ProviderName}
+		testutil.HandleTestingErr(d.Insert(), t, "error inserting distro")
+
+		old := time.Now().Add(-2 * InventoryGracePeriod)
+		mock.MockInstances = map[string][]cloud.CloudInstance{
+			"d1": []cloud.CloudInstance{
+				{Id: "known", Tag: "t-known", Status: cloud.StatusRunning, LaunchTime: old},
+				{Id: "orphan", Tag: "t-orphan", Status: cloud.StatusRunning, LaunchTime: old},
+				{Id: "new", Tag: "t-new", Status: cloud.StatusRunning, LaunchTime: time.Now()},
+				{Id: "untagged", Status: cloud.StatusRunning, LaunchTime: old},
+			},
+		}
+		mock.MockStatusErrors = map[string]error{
+			"unreachable": fmt.Errorf("provider unavailable"),
+		}
+		Reset(func() {
+			mock.MockInstances = map[string][]cloud.CloudInstance{}
+			mock.MockStatusErrors = map[string]error{}
+		})
+
+		hosts := []host.Host{
+			{Id: "known", Distro: *d, Provider: mock.ProviderName,
+				Status: evergreen.HostRunning, CreationTime: old},
+			{Id: "ghost", Distro: *d, Provider: mock.ProviderName,
+				Status: evergreen.HostRunning, CreationTime: old},
+			{Id: "young", Distro: *d, Provider: mock.ProviderName,
+				Status: evergreen.HostRunning, CreationTime: time.Now()},
+			{Id: "booting", Distro: *d, Provider: mock.ProviderName,
+				Status: evergreen.HostUninitialized, CreationTime: old},
+			{Id: "unreachable", Distro: *d, Provider: mock.ProviderName,
+				Status: evergreen.HostRunning, CreationTime: old},
+		}
+		for _, h := range hosts {
+			testutil.HandleTestingErr(h.Insert(), t, "error inserting host")
+		}
+
+		So(reconcileInventory(testConfig), ShouldBeNil)
+
+		report, err := model.FindLastInventoryReport("d1")
+		So(err, ShouldBeNil)
+		So(report, ShouldNotBeNil)
+
+		Convey("every listed instance should be counted", func() {
+			So(report.Instances, ShouldEqual, 4)
+		})
+
+		Convey("only tagged orphans past the grace period should be"+
+			" terminated", func() {
+			So(report.OrphansTerminated, ShouldResemble, []string{"orphan"})
+			So(report.OrphansPending, ShouldResemble, []string{"new"})
+			So(report.Untagged, ShouldResemble, []string{"untagged"})
+		})
+
+		Convey("only hosts past the grace period whose instance is gone"+
+			" should be marked terminated", func() {
+			So(report.GhostHosts, ShouldResemble, []string{"ghost"})
+
+			ghost, err := host.FindOne(host.ById("ghost"))
+			So(err, ShouldBeNil)
+			So(ghost.Status, ShouldEqual, evergreen.HostTerminated)
+
+			for _, id := range []string{"known", "young", "booting", "unreachable"} {
+				h, err := host.FindOne(host.ById(id))
+				So(err, ShouldBeNil)
+				So(h.Status, ShouldNotEqual, evergreen.HostTerminated)
+			}
+		})
+
+		Convey("hosts whose instance status can't be looked up should be"+
+			" left alone and the error reported", func() {
+			So(len(report.Errors), ShouldEqual, 1)
+			So(report.Errors[0], ShouldContainSubstring, "provider unavailable")
+		})
+
+	})
+
+}
@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/hostutil"
+	"github.com/evergreen-ci/evergreen/model/host"
+)
+
+// rotateHostKeys is a hostMonitoringFunc responsible for moving running
+// hosts onto the newest registered version of their distro's SSH key. The
+// new key is authorized on each host, the host is recorded as using it, and
+// only then is the old key revoked. Hosts start out on the key their
+// provider booted them with, so hosts that couldn't be spawned with the
+// newest version are rotated once they are running. Older versions of a key
+// that no live host uses anymore are reported as ready to be retired.
+func rotateHostKeys(settings *evergreen.Settings) []error {
+
+	evergreen.Logger.Logf(slogger.INFO, "Rotating host keys...")
+
+	// used to store any errors that occur
+	var errors []error
+
+	for keyName, versions := range settings.KeyVersions {
+		current := settings.CurrentKeyVersion(keyName)
+		hosts, err := host.Find(host.ByKeyVersionBefore(keyName, current))
+		if err != nil {
+			errors = append(errors, fmt.Errorf("error finding hosts to rotate"+
+				" to version %v of key %v: %v", current, keyName, err))
+			continue
+		}
+
+		// continue on error so that other hosts can be rotated
+		for _, h := range hosts {
+			if err := rotateHostKey(&h, settings, current); err != nil {
+				errors = append(errors, fmt.Errorf("error rotating host %v to"+
+					" version %v of key %v: %v", h.Id, current, keyName, err))
+			}
+		}
+
+		// version 0 is the key's original path, which isn't in the list
+		older := []int{0}
+		for _, key := range versions {
+			if key.Version != current {
+				older = append(older, key.Version)
+			}
+		}
+		for _, version := range older {
+			count, err := host.Count(host.ByLiveWithKeyVersion(keyName, version))
+			if err != nil {
+				errors = append(errors, fmt.Errorf("error counting hosts using"+
+					" version %v of key %v: %v", version, keyName, err))
+				continue
+			}
+			if count == 0 {
+				evergreen.Logger.Logf(slogger.INFO, "Version %v of key %v is no longer"+
+					" used by any host and can be retired", version, keyName)
+			} else {
+				evergreen.Logger.Logf(slogger.INFO, "Version %v of key %v is still used"+
+					" by %v hosts; don't retire it yet", version, keyName, count)
+			}
+		}
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Finished rotating host keys")
+
+	return errors
+}
+
+// rotateHostKey moves the host from the version of its distro's key that it
+// uses now onto the given version.
+func rotateHostKey(h *host.Host, settings *evergreen.Settings, version int) error {
+	keyName := h.Distro.SSHKey
+	oldVersion := h.KeyVersion
+	oldKeyfile := settings.KeyPath(keyName, oldVersion)
+	if oldKeyfile == "" {
+		return fmt.Errorf("version %v of the key the host uses is not"+
+			" registered", oldVersion)
+	}
+	newKeyfile := settings.KeyPath(keyName, version)
+
+	if err := hostutil.AuthorizeKey(h, oldKeyfile, newKeyfile); err != nil {
+		return err
+	}
+	if err := h.SetKeyVersion(version); err != nil {
+		return fmt.Errorf("error recording key version: %v", err)
+	}
+	if err := hostutil.RevokeKey(h, newKeyfile, oldKeyfile); err != nil {
+		return err
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Rotated host %v from version %v to"+
+		" version %v of key %v", h.Id, oldVersion, version, keyName)
+	return nil
+}
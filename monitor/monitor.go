@@ -21,18 +21,26 @@ var (
 
 	// the functions the host monitor will run through to find hosts needing
 	// to be terminated
-	defaultHostFlaggingFuncs = []hostFlaggingFunc{
-		flagDecommissionedHosts,
-		flagIdleHosts,
-		flagExcessHosts,
-		flagUnprovisionedHosts,
-		flagProvisioningFailedHosts,
-		flagExpiredHosts,
+	defaultHostFlaggingFuncs = []hostFlagger{
+		{flagDecommissionedHosts, evergreen.TerminationReasonDecommissioned},
+		{flagIdleHosts, evergreen.TerminationReasonIdle},
+		{flagExcessHosts, evergreen.TerminationReasonExcess},
+		{flagUnprovisionedHosts, evergreen.TerminationReasonProvisionTimeout},
+		{flagProvisioningFailedHosts, evergreen.TerminationReasonProvisionFailed},
+		{flagExpiredHosts, evergreen.TerminationReasonExpired},
+		{flagRetiredHosts, evergreen.TerminationReasonRetired},
+		{flagIdleSpawnHosts, evergreen.TerminationReasonSpawnIdle},
 	}
 
 	// the functions the host monitor will run through to do simpler checks
 	defaultHostMonitoringFuncs = []hostMonitoringFunc{
 		monitorReachability,
+		reconcileInventory,
+		reportDrainedHosts,
+		recycleHosts,
+		pruneImages,
+		checkSpawnHostActivity,
+		rotateHostKeys,
 	}
 
 	// the functions the notifier will use to build notifications that need
@@ -138,6 +146,24 @@ func RunAllMonitoring(settings *evergreen.Settings) error {
 		}
 	}
 
+	// any running spawn host may have gone unused for long enough to warn
+	// its user that it will be terminated
+	spawnHosts, err := host.Find(host.IsRunningAndSpawned)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range spawnHosts {
+		if h.Status == evergreen.HostQuarantined {
+			continue
+		}
+		err := alerts.RunSpawnIdleTriggers(&h)
+
+		if err != nil {
+			evergreen.Logger.Logf(slogger.ERROR, "Error queueing alert: %v", err)
+		}
+	}
+
 	return nil
 
 }
@@ -0,0 +1,172 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"sort"
+	"time"
+)
+
+const (
+	// the largest fraction of a distro's pool that may be recycled at once,
+	// so that the whole pool doesn't turn over together
+	MaxRecyclingFraction = 0.1
+)
+
+// recycleHosts is a hostMonitoringFunc responsible for replacing dynamic
+// hosts that have outlived their distro's max lifetime. A replacement is
+// spawned for each expired host first; once it is running, the old host is
+// marked as retiring, and is terminated when its current task finishes.
+func recycleHosts(settings *evergreen.Settings) []error {
+
+	evergreen.Logger.Logf(slogger.INFO, "Recycling hosts past their max lifetime...")
+
+	// used to store any errors that occur
+	var errors []error
+
+	distros, err := distro.Find(distro.All)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("error finding distros: %v", err))
+		return errors
+	}
+
+	// continue on error so that other distros can be recycled
+	for _, d := range distros {
+		if d.MaxLifetime <= 0 {
+			continue
+		}
+		if errs := recycleDistroHosts(&d, settings); errs != nil {
+			for _, err := range errs {
+				errors = append(errors, fmt.Errorf("error recycling hosts"+
+					" of distro %v: %v", d.Id, err))
+			}
+		}
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Finished recycling hosts")
+
+	return errors
+}
+
+// recycleDistroHosts moves the distro's expired hosts along the recycling
+// process, starting on no more than the distro's share of new ones.
+func recycleDistroHosts(d *distro.Distro, settings *evergreen.Settings) []error {
+	cloudManager, err := providers.GetCloudManager(d.Provider, settings)
+	if err != nil {
+		return []error{fmt.Errorf("error getting cloud manager: %v", err)}
+	}
+	canSpawn, err := cloudManager.CanSpawn()
+	if err != nil {
+		return []error{fmt.Errorf("error checking if provider %v can spawn"+
+			" hosts: %v", d.Provider, err)}
+	}
+	if !canSpawn {
+		return nil
+	}
+
+	hosts, err := host.Find(host.ByDistroId(d.Id))
+	if err != nil {
+		return []error{fmt.Errorf("error finding hosts: %v", err)}
+	}
+
+	// used to store any errors that occur
+	var errors []error
+
+	cutoff := time.Now().Add(-time.Duration(d.MaxLifetime) * time.Minute)
+	expired := []host.Host{}
+	recycling := 0
+	for _, h := range hosts {
+		if h.ReplacementId != "" {
+			recycling++
+			if !h.Retiring {
+				if err := checkReplacement(&h); err != nil {
+					errors = append(errors, err)
+				}
+			}
+			continue
+		}
+		if h.Status == evergreen.HostRunning && h.CreationTime.Before(cutoff) {
+			expired = append(expired, h)
+		}
+	}
+
+	// replacements are new hosts, which draining distros don't get
+	if d.Draining || len(expired) == 0 {
+		return errors
+	}
+
+	limit := int(float64(d.PoolSize) * MaxRecyclingFraction)
+	if limit < 1 {
+		limit = 1
+	}
+
+	// recycle the oldest hosts first
+	sort.Sort(byCreationTime(expired))
+	for _, h := range expired {
+		if recycling >= limit {
+			evergreen.Logger.Logf(slogger.INFO, "%v hosts of distro %v are already"+
+				" being recycled; %v expired hosts will wait", recycling, d.Id,
+				len(expired))
+			break
+		}
+
+		replacement, err := cloudManager.SpawnInstance(d, evergreen.User, false)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("error spawning replacement for"+
+				" host %v: %v", h.Id, err))
+			continue
+		}
+		evergreen.Logger.Logf(slogger.INFO, "Host %v of distro %v is past its max"+
+			" lifetime; spawned replacement %v", h.Id, d.Id, replacement.Id)
+		if err := h.SetReplacement(replacement.Id); err != nil {
+			errors = append(errors, fmt.Errorf("error recording replacement %v"+
+				" of host %v: %v", replacement.Id, h.Id, err))
+			continue
+		}
+		recycling++
+	}
+
+	return errors
+}
+
+// checkReplacement retires the host if its replacement is running. If the
+// replacement failed, it is forgotten so another can be spawned.
+func checkReplacement(h *host.Host) error {
+	replacement, err := host.FindOne(host.ByIdOrTag(h.ReplacementId))
+	if err != nil {
+		return fmt.Errorf("error finding replacement %v of host %v: %v",
+			h.ReplacementId, h.Id, err)
+	}
+
+	status := evergreen.HostTerminated
+	if replacement != nil {
+		status = replacement.Status
+	}
+
+	switch status {
+	case evergreen.HostRunning:
+		evergreen.Logger.Logf(slogger.INFO, "Replacement %v of host %v is running;"+
+			" retiring host", h.ReplacementId, h.Id)
+		if err := h.SetRetiring(); err != nil {
+			return fmt.Errorf("error retiring host %v: %v", h.Id, err)
+		}
+	case evergreen.HostTerminated, evergreen.HostProvisionFailed,
+		evergreen.HostDecommissioned, evergreen.HostQuarantined:
+		evergreen.Logger.Logf(slogger.WARN, "Replacement %v of host %v is %v;"+
+			" a new one will be spawned", h.ReplacementId, h.Id, status)
+		if err := h.SetReplacement(""); err != nil {
+			return fmt.Errorf("error clearing replacement of host %v: %v", h.Id, err)
+		}
+	}
+	return nil
+}
+
+type byCreationTime []host.Host
+
+func (b byCreationTime) Len() int           { return len(b) }
+func (b byCreationTime) Less(i, j int) bool { return b[i].CreationTime.Before(b[j].CreationTime) }
+func (b byCreationTime) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud/providers/mock"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestRecycleHosts(t *testing.T) {
+
+	testConfig := evergreen.TestConfig()
+
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testConfig))
+
+	Convey("When recycling hosts past their distro's max lifetime", t, func() {
+
+		testutil.HandleTestingErr(db.ClearCollections(host.Collection,
+			distro.Collection), t, "error clearing collections")
+
+		d := &distro.Distro{Id: "d1", Provider: mock.ProviderName,
+			PoolSize: 10, MaxLifetime: 60}
+		testutil.HandleTestingErr(d.Insert(), t, "error inserting distro")
+
+		now := time.Now()
+		hosts := []host.Host{
+			{Id: "oldest", Distro: *d, Provider: mock.ProviderName,
+				StartedBy: evergreen.User, Status: evergreen.HostRunning,
+				CreationTime: now.Add(-3 * time.Hour)},
+			{Id: "old", Distro: *d, Provider: mock.ProviderName,
+				StartedBy: evergreen.User, Status: evergreen.HostRunning,
+				CreationTime: now.Add(-2 * time.Hour)},
+			{Id: "young", Distro: *d, Provider: mock.ProviderName,
+				StartedBy: evergreen.User, Status: evergreen.HostRunning,
+				CreationTime: now},
+		}
+		for _, h := range hosts {
+			testutil.HandleTestingErr(h.Insert(), t, "error inserting host")
+		}
+
+		So(recycleHosts(testConfig), ShouldBeNil)
+
+		Convey("only the oldest expired host should be replaced at once", func() {
+			oldest, err := host.FindOne(host.ById("oldest"))
+			So(err, ShouldBeNil)
+			So(oldest.ReplacementId, ShouldNotEqual, "")
+			So(oldest.Retiring, ShouldBeFalse)
+
+			for _, id := range []string{"old", "young"} {
+				h, err := host.FindOne(host.ById(id))
+				So(err, ShouldBeNil)
+				So(h.ReplacementId, ShouldEqual, "")
+			}
+
+			Convey("the host should retire once its replacement is running",
+				func() {
+					replacement := &host.Host{Id: oldest.ReplacementId,
+						Distro: *d, Provider: mock.ProviderName,
+						StartedBy: evergreen.User, Status: evergreen.HostRunning,
+						CreationTime: now}
+					So(replacement.Insert(), ShouldBeNil)
+
+					So(recycleHosts(testConfig), ShouldBeNil)
+
+					oldest, err := host.FindOne(host.ById("oldest"))
+					So(err, ShouldBeNil)
+					So(oldest.Retiring, ShouldBeTrue)
+
+					retired, err := flagRetiredHosts(nil, testConfig)
+					So(err, ShouldBeNil)
+					So(len(retired), ShouldEqual, 1)
+					So(retired[0].Id, ShouldEqual, "oldest")
+				})
+
+			Convey("a failed replacement should be forgotten", func() {
+				So(recycleHosts(testConfig), ShouldBeNil)
+
+				oldest, err := host.FindOne(host.ById("oldest"))
+				So(err, ShouldBeNil)
+				So(oldest.ReplacementId, ShouldEqual, "")
+				So(oldest.Retiring, ShouldBeFalse)
+			})
+		})
+
+	})
+
+}
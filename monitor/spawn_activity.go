@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/hostutil"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"time"
+)
+
+const (
+	// how long to wait in between checks of a spawn host for its user's
+	// activity
+	SpawnActivityCheckInterval = 10 * time.Minute
+)
+
+// checkSpawnHostActivity is a hostMonitoringFunc responsible for probing
+// spawn hosts over SSH to see if their users have used them since they were
+// last checked. Hosts that can't be checked are taken to be in use, so that
+// nobody's host is terminated for a failure to probe it.
+func checkSpawnHostActivity(settings *evergreen.Settings) []error {
+
+	evergreen.Logger.Logf(slogger.INFO, "Checking spawn hosts for user activity...")
+
+	// used to store any errors that occur
+	var errors []error
+
+	threshold := time.Now().Add(-SpawnActivityCheckInterval)
+	hosts, err := host.Find(host.ByActivityNotCheckedSince(threshold))
+	if err != nil {
+		errors = append(errors, fmt.Errorf("error finding spawn hosts not"+
+			" checked for activity recently: %v", err))
+		return errors
+	}
+
+	// continue on error so that other hosts can be checked
+	for _, h := range hosts {
+		active, err := checkUserActivity(&h, settings)
+		if err != nil {
+			evergreen.Logger.Logf(slogger.WARN, "Could not check spawn host %v for"+
+				" user activity, assuming it is in use: %v", h.Id, err)
+			active = true
+		}
+		if err := h.SetActivityChecked(active); err != nil {
+			errors = append(errors, fmt.Errorf("error recording activity check"+
+				" for host %v: %v", h.Id, err))
+		}
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Finished checking spawn hosts for user activity")
+
+	return errors
+}
+
+// checkUserActivity returns whether the host's user has used it since it
+// was last checked.
+func checkUserActivity(h *host.Host, settings *evergreen.Settings) (bool, error) {
+	cloudHost, err := providers.GetCloudHost(h, settings)
+	if err != nil {
+		return false, fmt.Errorf("error getting cloud host: %v", err)
+	}
+	sshOptions, err := cloudHost.GetSSHOptions()
+	if err != nil {
+		return false, fmt.Errorf("error getting ssh options: %v", err)
+	}
+
+	since := h.CreationTime
+	if h.LastActivityCheck.After(since) {
+		since = h.LastActivityCheck
+	}
+	return hostutil.CheckUserActivity(h, sshOptions, time.Since(since))
+}
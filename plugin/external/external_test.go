@@ -0,0 +1,343 @@
+package external
+
+import (
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/plugin"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// greetPlugin is served over the protocol in tests. Its greet command logs a
+// greeting and reports a test result, and its wait command runs until it is
+// stopped.
+type greetPlugin struct{}
+
+func (gp *greetPlugin) Name() string                                 { return "greet" }
+func (gp *greetPlugin) Configure(map[string]interface{}) error       { return nil }
+func (gp *greetPlugin) GetAPIHandler() http.Handler                  { return nil }
+func (gp *greetPlugin) GetUIHandler() http.Handler                   { return nil }
+func (gp *greetPlugin) GetPanelConfig() (*plugin.PanelConfig, error) { return nil, nil }
+
+func (gp *greetPlugin) NewCommand(name string) (plugin.Command, error) {
+	if name != "greet" && name != "wait" {
+		return nil, &plugin.ErrUnknownCommand{CommandName: name}
+	}
+	return &greetCommand{name: name}, nil
+}
+
+var greetSchemas = map[string]ParamsSchema{
+	"greet": {"name": {Type: TypeString, Required: true}},
+	"wait":  {},
+}
+
+type greetCommand struct {
+	name     string
+	greeting string
+}
+
+func (gc *greetCommand) Name() string   { return gc.name }
+func (gc *greetCommand) Plugin() string { return "greet" }
+
+func (gc *greetCommand) ParseParams(params map[string]interface{}) error {
+	if gc.name == "greet" {
+		gc.greeting, _ = params["name"].(string)
+		if gc.greeting == "nobody" {
+			return fmt.Errorf("can't greet nobody")
+		}
+	}
+	return nil
+}
+
+func (gc *greetCommand) Execute(logger plugin.Logger, pluginCom plugin.PluginCommunicator,
+	conf *model.TaskConfig, stop chan bool) error {
+	if gc.name == "wait" {
+		<-stop
+		return fmt.Errorf("stopped")
+	}
+	greeting, err := conf.Expansions.ExpandString("hello " + gc.greeting + " from ${where}")
+	if err != nil {
+		return err
+	}
+	logger.LogTask(slogger.INFO, "%v", greeting)
+	io.WriteString(logger.GetTaskLogWriter(slogger.WARN), "line one\n\nline two\n")
+	if err = pluginCom.TaskPostResults(&model.TestResults{
+		Results: []model.TestResult{{Status: "pass", TestFile: conf.Task.Id}},
+	}); err != nil {
+		return err
+	}
+	if gc.greeting == "failure" {
+		return fmt.Errorf("failed to greet")
+	}
+	return nil
+}
+
+// pipeStarter runs the plugin in the test process, over pipes.
+func pipeStarter(p plugin.Plugin, schemas map[string]ParamsSchema) func(io.Writer) (*process, error) {
+	return func(stderr io.Writer) (*process, error) {
+		toPluginR, toPluginW := io.Pipe()
+		fromPluginR, fromPluginW := io.Pipe()
+		exited := make(chan error, 1)
+		go func() {
+			err := serve(p, schemas, toPluginR, fromPluginW)
+			fromPluginW.Close()
+			exited <- err
+		}()
+		return &process{
+			conn:  newConn(fromPluginR, toPluginW),
+			stdin: toPluginW,
+			wait:  func() error { return <-exited },
+			kill: func() error {
+				toPluginR.Close()
+				return fromPluginW.Close()
+			},
+		}, nil
+	}
+}
+
+type recordingLogger struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (rl *recordingLogger) record(stream string, level slogger.Level, messageFmt string, args ...interface{}) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	rl.messages = append(rl.messages, fmt.Sprintf("%v %v: ", stream, levelName(level))+
+		fmt.Sprintf(messageFmt, args...))
+}
+
+func (rl *recordingLogger) LogLocal(level slogger.Level, messageFmt string, args ...interface{}) {
+	rl.record(StreamLocal, level, messageFmt, args...)
+}
+func (rl *recordingLogger) LogExecution(level slogger.Level, messageFmt string, args ...interface{}) {
+	rl.record(StreamExecution, level, messageFmt, args...)
+}
+func (rl *recordingLogger) LogTask(level slogger.Level, messageFmt string, args ...interface{}) {
+	rl.record(StreamTask, level, messageFmt, args...)
+}
+func (rl *recordingLogger) LogSystem(level slogger.Level, messageFmt string, args ...interface{}) {
+	rl.record(StreamSystem, level, messageFmt, args...)
+}
+func (rl *recordingLogger) GetTaskLogWriter(level slogger.Level) io.Writer { return ioutil.Discard }
+func (rl *recordingLogger) Flush()                                         {}
+
+type recordingCommunicator struct {
+	results *model.TestResults
+}
+
+func (rc *recordingCommunicator) TaskPostJSON(endpoint string, data interface{}) (*http.Response, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (rc *recordingCommunicator) TaskGetJSON(endpoint string) (*http.Response, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (rc *recordingCommunicator) TaskPostResults(results *model.TestResults) error {
+	rc.results = results
+	return nil
+}
+func (rc *recordingCommunicator) TaskPostTestLog(log *model.TestLog) (string, error) {
+	return "", nil
+}
+func (rc *recordingCommunicator) PostTaskFiles(files []*artifact.File) error { return nil }
+
+func TestExternalPlugin(t *testing.T) {
+	Convey("With a plugin served over the protocol", t, func() {
+		p := &Plugin{Path: "greet", start: pipeStarter(&greetPlugin{}, greetSchemas)}
+		So(p.describe(), ShouldBeNil)
+		conf := &model.TaskConfig{
+			Task:       &model.Task{Id: "t1"},
+			Expansions: command.NewExpansions(map[string]string{"where": "the plugin"}),
+		}
+
+		Convey("private expansions should be marked as such", func() {
+			conf.PrivateExpansions = map[string]bool{"where": true}
+			taskConfig := newTaskConfig(conf)
+			So(taskConfig.PrivateExpansions, ShouldResemble, []string{"where"})
+			So(taskConfig.modelConfig().PrivateExpansions["where"], ShouldBeTrue)
+		})
+
+		Convey("it should describe itself", func() {
+			So(p.Name(), ShouldEqual, "greet")
+			_, err := p.NewCommand("wave")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("parameters should be checked against the schema and by the plugin", func() {
+			cmd, err := p.NewCommand("greet")
+			So(err, ShouldBeNil)
+			So(cmd.ParseParams(map[string]interface{}{}), ShouldNotBeNil)
+			So(cmd.ParseParams(map[string]interface{}{"name": 5}), ShouldNotBeNil)
+			err = cmd.ParseParams(map[string]interface{}{"name": "nobody"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "can't greet nobody")
+			So(cmd.ParseParams(map[string]interface{}{"name": "world"}), ShouldBeNil)
+
+			Convey("unless the plugin only checks schemas", func() {
+				p.SchemaOnly = true
+				So(cmd.ParseParams(map[string]interface{}{"name": "nobody"}), ShouldBeNil)
+			})
+		})
+
+		Convey("executing a command should pass its logs and results to the agent", func() {
+			cmd, err := p.NewCommand("greet")
+			So(err, ShouldBeNil)
+			So(cmd.ParseParams(map[string]interface{}{"name": "world"}), ShouldBeNil)
+			logger := &recordingLogger{}
+			pluginCom := &recordingCommunicator{}
+			So(cmd.Execute(logger, pluginCom, conf, make(chan bool)), ShouldBeNil)
+			So(logger.messages, ShouldResemble, []string{
+				"task info: hello world from the plugin",
+				"task warn: line one",
+				"task warn: line two",
+			})
+			So(pluginCom.results, ShouldNotBeNil)
+			So(pluginCom.results.Results[0].TestFile, ShouldEqual, "t1")
+		})
+
+		Convey("a command's error should be returned", func() {
+			cmd, err := p.NewCommand("greet")
+			So(err, ShouldBeNil)
+			So(cmd.ParseParams(map[string]interface{}{"name": "failure"}), ShouldBeNil)
+			err = cmd.Execute(&recordingLogger{}, &recordingCommunicator{}, conf, make(chan bool))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "failed to greet")
+		})
+
+		Convey("a command should be stopped when the agent says", func() {
+			cmd, err := p.NewCommand("wait")
+			So(err, ShouldBeNil)
+			So(cmd.ParseParams(nil), ShouldBeNil)
+			stop := make(chan bool)
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				close(stop)
+			}()
+			err = cmd.Execute(&recordingLogger{}, &recordingCommunicator{}, conf, stop)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "stopped")
+		})
+	})
+}
+
+// shellPlugin answers describe calls with the given description.
+const shellPlugin = `#!/bin/sh
+read call
+echo '{"result": %v}'
+`
+
+func TestLoad(t *testing.T) {
+	Convey("With a directory of plugin executables", t, func() {
+		dir, err := ioutil.TempDir("", "external_plugins")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		writePlugin := func(name, description string, mode os.FileMode) {
+			So(ioutil.WriteFile(filepath.Join(dir, name),
+				[]byte(fmt.Sprintf(shellPlugin, description)), mode), ShouldBeNil)
+		}
+		writePlugin("hello", `{"protocol_version": 1, "name": "hello", "commands": {"say": {}}}`, 0755)
+		writePlugin("README", `{}`, 0644)
+
+		Convey("executables should be loaded as plugins", func() {
+			plugins, err := Load(dir)
+			So(err, ShouldBeNil)
+			So(len(plugins), ShouldEqual, 1)
+			So(plugins[0].Name(), ShouldEqual, "hello")
+			_, err = plugins[0].NewCommand("say")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("plugins that speak another protocol version should be left out", func() {
+			writePlugin("old", `{"protocol_version": 0, "name": "old"}`, 0755)
+			plugins, err := Load(dir)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "protocol version 0")
+			So(len(plugins), ShouldEqual, 1)
+		})
+
+		Convey("plugins should only describe themselves again once they change", func() {
+			runs := filepath.Join(dir, "runs")
+			counting := "#!/bin/sh\necho run >> " + runs + "\n" + fmt.Sprintf(shellPlugin[len("#!/bin/sh\n"):],
+				`{"protocol_version": 1, "name": "counted"}`)
+			countRuns := func() int {
+				out, err := ioutil.ReadFile(runs)
+				So(err, ShouldBeNil)
+				return strings.Count(string(out), "run")
+			}
+			path := filepath.Join(dir, "counted")
+			So(ioutil.WriteFile(path, []byte(counting), 0755), ShouldBeNil)
+
+			for i := 0; i < 2; i++ {
+				plugins, err := Load(dir)
+				So(err, ShouldBeNil)
+				So(len(plugins), ShouldEqual, 2)
+			}
+			So(countRuns(), ShouldEqual, 1)
+
+			later := time.Now().Add(time.Minute)
+			So(os.Chtimes(path, later, later), ShouldBeNil)
+			_, err := Load(dir)
+			So(err, ShouldBeNil)
+			So(countRuns(), ShouldEqual, 2)
+		})
+
+		Convey("a missing directory should have no plugins", func() {
+			plugins, err := Load(filepath.Join(dir, "missing"))
+			So(err, ShouldBeNil)
+			So(plugins, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestParamsSchema(t *testing.T) {
+	Convey("With a schema for a command's parameters", t, func() {
+		schema := ParamsSchema{
+			"file":    {Type: TypeString, Required: true},
+			"retries": {Type: TypeNumber},
+			"env":     {Type: TypeMap},
+			"args":    {Type: TypeList},
+			"extra":   {Type: TypeAny},
+		}
+
+		Convey("parameters from a project's YAML should be checked", func() {
+			params, err := normalizeParams(map[string]interface{}{
+				"file":    "a.txt",
+				"retries": 3,
+				"env":     map[interface{}]interface{}{"PATH": "/bin"},
+				"args":    []interface{}{map[interface{}]interface{}{"x": 1}},
+			})
+			So(err, ShouldBeNil)
+			So(schema.Validate(params), ShouldBeNil)
+			So(params["env"], ShouldResemble, map[string]interface{}{"PATH": "/bin"})
+		})
+
+		Convey("problems with parameters should be reported", func() {
+			err := schema.Validate(map[string]interface{}{"file": "a.txt", "bogus": true})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unknown parameter 'bogus'")
+
+			err = schema.Validate(map[string]interface{}{"retries": 1})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "missing required parameter 'file'")
+
+			err = schema.Validate(map[string]interface{}{"file": true})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "must be of type string")
+
+			_, err = normalizeParams(map[string]interface{}{"env": map[interface{}]interface{}{1: "x"}})
+			So(strings.Contains(err.Error(), "not a string"), ShouldBeTrue)
+		})
+	})
+}
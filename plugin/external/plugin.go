@@ -0,0 +1,527 @@
+package external
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CallTimeout is how long a plugin may take to answer a call other than
+	// MethodExecute before it is killed.
+	CallTimeout = time.Minute
+	// StopWait is how long a plugin is given to stop a command once it has
+	// been told to, before it is killed.
+	StopWait = 10 * time.Second
+)
+
+// Plugin is an external plugin. It implements plugin.Plugin so that it can be
+// registered like any other, but has no routes or UI of its own.
+type Plugin struct {
+	// Path is the plugin's executable.
+	Path string
+
+	// SchemaOnly has the parameters of the plugin's commands checked only
+	// against their schemas, without running the plugin, as the servers do
+	// when validating projects.
+	SchemaOnly bool
+
+	description Description
+
+	// start runs the plugin, sending anything it writes to stderr to the
+	// given writer.
+	start func(stderr io.Writer) (*process, error)
+}
+
+// process is a single run of a plugin, spoken to over its stdin and stdout.
+type process struct {
+	*conn
+	stdin io.Closer
+	wait  func() error
+	kill  func() error
+}
+
+// finish closes the plugin's stdin and waits for it to exit, killing it if it
+// doesn't within the given time.
+func (proc *process) finish(timeout time.Duration) error {
+	proc.stdin.Close()
+	exited := make(chan error, 1)
+	go func() { exited <- proc.wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(timeout):
+		proc.kill()
+		return fmt.Errorf("plugin did not exit within %v", timeout)
+	}
+}
+
+// executableStarter returns a function that runs the executable at path.
+func executableStarter(path string) func(io.Writer) (*process, error) {
+	return func(stderr io.Writer) (*process, error) {
+		cmd := exec.Command(path)
+		cmd.Stderr = stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err = cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &process{
+			conn:  newConn(stdout, stdin),
+			stdin: stdin,
+			wait:  cmd.Wait,
+			kill:  cmd.Process.Kill,
+		}, nil
+	}
+}
+
+// NewPlugin loads the plugin with the executable at path, asking it to
+// describe itself.
+func NewPlugin(path string) (*Plugin, error) {
+	p := &Plugin{Path: path, start: executableStarter(path)}
+	if err := p.describe(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// loaded is what came of loading a plugin's executable, kept for as long as
+// the executable is unchanged.
+type loaded struct {
+	modTime time.Time
+	size    int64
+	plugin  *Plugin
+	err     error
+}
+
+// loadCache holds what came of loading each executable, by path, so that a
+// long-lived agent doesn't run every plugin again for each task, nor wait
+// on a plugin that failed to describe itself for each task.
+var loadCache = struct {
+	sync.Mutex
+	byPath map[string]loaded
+}{byPath: map[string]loaded{}}
+
+// loadCached loads the plugin with the executable described by info in dir,
+// unless it has been loaded before and is unchanged since.
+func loadCached(dir string, info os.FileInfo) (*Plugin, error) {
+	path := filepath.Join(dir, info.Name())
+	loadCache.Lock()
+	defer loadCache.Unlock()
+	cached, ok := loadCache.byPath[path]
+	if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+		cached = loaded{modTime: info.ModTime(), size: info.Size()}
+		cached.plugin, cached.err = NewPlugin(path)
+		loadCache.byPath[path] = cached
+	}
+	if cached.err != nil {
+		return nil, cached.err
+	}
+	// callers may change the plugin, as Publish does
+	p := *cached.plugin
+	return &p, nil
+}
+
+// Load loads every plugin with an executable in dir. It returns the plugins
+// that could be loaded, along with an error describing any that couldn't. A
+// missing dir has no plugins in it. Plugins are only run to describe
+// themselves the first time they're loaded and whenever their executable
+// changes.
+func Load(dir string) ([]*Plugin, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading plugin directory %v: %v", dir, err)
+	}
+
+	plugins := []*Plugin{}
+	failed := []string{}
+	for _, info := range infos {
+		if info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		p, err := loadCached(dir, info)
+		if err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		plugins = append(plugins, p)
+	}
+	if len(failed) > 0 {
+		return plugins, fmt.Errorf("failed to load plugins: %v", strings.Join(failed, "; "))
+	}
+	return plugins, nil
+}
+
+// Publish loads the plugins in dir and publishes them with SchemaOnly set,
+// so that the servers can validate the use of their commands in projects.
+// A plugin with the same name as one already published is left out.
+func Publish(dir string) error {
+	plugins, err := Load(dir)
+	errs := []string{}
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	published := map[string]bool{}
+	for _, pl := range plugin.Published {
+		published[pl.Name()] = true
+	}
+	for _, p := range plugins {
+		if published[p.Name()] {
+			errs = append(errs, fmt.Sprintf("plugin %v at %v has the name of an"+
+				" existing plugin", p.Name(), p.Path))
+			continue
+		}
+		p.SchemaOnly = true
+		plugin.Publish(p)
+		published[p.Name()] = true
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// describe asks the plugin for its name and commands.
+func (p *Plugin) describe() error {
+	description := Description{}
+	if err := p.call(MethodDescribe, nil, &description); err != nil {
+		return err
+	}
+	if description.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("plugin %v speaks protocol version %v, not %v",
+			p.Path, description.ProtocolVersion, ProtocolVersion)
+	}
+	if description.Name == "" || strings.Contains(description.Name, ".") {
+		return fmt.Errorf("plugin %v has invalid name '%v'", p.Path, description.Name)
+	}
+	p.description = description
+	return nil
+}
+
+// call runs the plugin to answer a single call, during which the plugin may
+// not make calls of its own. An error the plugin answers with is returned
+// as is; any other is described along with what the plugin wrote to stderr.
+func (p *Plugin) call(method string, params, result interface{}) error {
+	stderr := &bytes.Buffer{}
+	proc, err := p.start(stderr)
+	if err != nil {
+		return fmt.Errorf("error running plugin %v: %v", p.Path, err)
+	}
+
+	answered := make(chan *Message, 1)
+	failed := make(chan error, 1)
+	go func() {
+		if err := proc.sendCall(method, params); err != nil {
+			failed <- err
+			return
+		}
+		msg, err := proc.receive()
+		if err != nil {
+			failed <- err
+			return
+		}
+		answered <- msg
+	}()
+
+	select {
+	case msg := <-answered:
+		proc.finish(CallTimeout)
+		return decodeResponse(msg, result)
+	case err = <-failed:
+		proc.finish(CallTimeout)
+	case <-time.After(CallTimeout):
+		proc.kill()
+		err = fmt.Errorf("no answer within %v", CallTimeout)
+	}
+	return fmt.Errorf("error calling %v on plugin %v: %v%v", method, p.Path, err, stderrSuffix(stderr))
+}
+
+// stderrSuffix returns what a plugin wrote to stderr, if anything, to add to
+// an error.
+func stderrSuffix(stderr *bytes.Buffer) string {
+	if output := strings.TrimSpace(stderr.String()); output != "" {
+		return fmt.Sprintf(" (stderr: %v)", output)
+	}
+	return ""
+}
+
+// Name implements plugin.Plugin, returning the name the plugin described
+// itself with.
+func (p *Plugin) Name() string {
+	return p.description.Name
+}
+
+// Configure implements plugin.Plugin. External plugins take no settings.
+func (p *Plugin) Configure(map[string]interface{}) error {
+	return nil
+}
+
+// GetAPIHandler implements plugin.Plugin. External plugins have no routes.
+func (p *Plugin) GetAPIHandler() http.Handler {
+	return nil
+}
+
+// GetUIHandler implements plugin.Plugin. External plugins have no routes.
+func (p *Plugin) GetUIHandler() http.Handler {
+	return nil
+}
+
+// GetPanelConfig implements plugin.Plugin. External plugins have no UI.
+func (p *Plugin) GetPanelConfig() (*plugin.PanelConfig, error) {
+	return nil, nil
+}
+
+// NewCommand implements plugin.Plugin, returning one of the commands the
+// plugin described.
+func (p *Plugin) NewCommand(commandName string) (plugin.Command, error) {
+	schema, ok := p.description.Commands[commandName]
+	if !ok {
+		return nil, &plugin.ErrUnknownCommand{CommandName: commandName}
+	}
+	return &Command{plugin: p, name: commandName, schema: schema}, nil
+}
+
+// Command is a command of an external plugin.
+type Command struct {
+	plugin *Plugin
+	name   string
+	schema ParamsSchema
+	params map[string]interface{}
+}
+
+// Name returns the name of the command.
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Plugin returns the name of the command's plugin.
+func (c *Command) Plugin() string {
+	return c.plugin.Name()
+}
+
+// ParseParams checks the parameters against the command's schema, and then,
+// unless the plugin is SchemaOnly, has the plugin check them.
+func (c *Command) ParseParams(params map[string]interface{}) error {
+	normalized, err := normalizeParams(params)
+	if err != nil {
+		return fmt.Errorf("error parsing '%v' params: %v", c.name, err)
+	}
+	if err = c.schema.Validate(normalized); err != nil {
+		return fmt.Errorf("error parsing '%v' params: %v", c.name, err)
+	}
+	c.params = normalized
+	if c.plugin.SchemaOnly {
+		return nil
+	}
+	return c.plugin.call(MethodParseParams, &CommandParams{c.name, normalized}, nil)
+}
+
+// Execute runs the plugin to execute the command, answering the calls it
+// makes with the given logger and communicator until it finishes. The plugin
+// is told to stop if a value is received on stop, and killed if it doesn't.
+func (c *Command) Execute(logger plugin.Logger, pluginCom plugin.PluginCommunicator,
+	conf *model.TaskConfig, stop chan bool) error {
+	proc, err := c.plugin.start(logger.GetTaskLogWriter(slogger.ERROR))
+	if err != nil {
+		return fmt.Errorf("error running plugin %v: %v", c.plugin.Path, err)
+	}
+	defer proc.finish(StopWait)
+
+	err = proc.sendCall(MethodExecute, &ExecuteParams{c.name, c.params, newTaskConfig(conf)})
+	if err != nil {
+		return fmt.Errorf("error calling %v on plugin %v: %v", MethodExecute, c.plugin.Path, err)
+	}
+
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			logger.LogExecution(slogger.INFO, "Stopping plugin %v", c.plugin.Name())
+			proc.sendCall(MethodStop, nil)
+			select {
+			case <-done:
+			case <-time.After(StopWait):
+				logger.LogExecution(slogger.WARN, "Killing plugin %v, which did not stop within %v",
+					c.plugin.Name(), StopWait)
+				proc.kill()
+			}
+		case <-done:
+		}
+	}()
+
+	for {
+		msg, err := proc.receive()
+		if err != nil {
+			return fmt.Errorf("plugin %v exited before finishing '%v': %v", c.plugin.Name(), c.name, err)
+		}
+		if msg.Method == "" {
+			return decodeResponse(msg, nil)
+		}
+		result, callErr := answerCall(msg, logger, pluginCom)
+		if err = proc.sendResponse(result, callErr); err != nil {
+			return fmt.Errorf("error answering plugin %v: %v", c.plugin.Name(), err)
+		}
+	}
+}
+
+// newTaskConfig picks out the part of the agent's task configuration that
+// is sent to plugins. Every expansion is sent, private ones included, along
+// with which of them are private.
+func newTaskConfig(conf *model.TaskConfig) TaskConfig {
+	taskConfig := TaskConfig{WorkDir: conf.WorkDir, Expansions: map[string]string{}}
+	if conf.Task != nil {
+		taskConfig.TaskId = conf.Task.Id
+		taskConfig.TaskName = conf.Task.DisplayName
+		taskConfig.Execution = conf.Task.Execution
+		taskConfig.BuildVariant = conf.Task.BuildVariant
+		taskConfig.Project = conf.Task.Project
+		taskConfig.Revision = conf.Task.Revision
+		taskConfig.Requester = conf.Task.Requester
+	}
+	if conf.Distro != nil {
+		taskConfig.Distro = conf.Distro.Id
+	}
+	if conf.Expansions != nil {
+		for key, value := range *conf.Expansions {
+			taskConfig.Expansions[key] = value
+		}
+	}
+	for name, private := range conf.PrivateExpansions {
+		if private {
+			taskConfig.PrivateExpansions = append(taskConfig.PrivateExpansions, name)
+		}
+	}
+	sort.Strings(taskConfig.PrivateExpansions)
+	return taskConfig
+}
+
+// answerCall answers a call a plugin made while executing a command.
+func answerCall(msg *Message, logger plugin.Logger, pluginCom plugin.PluginCommunicator) (interface{}, error) {
+	switch msg.Method {
+	case MethodLog:
+		params := &LogParams{}
+		if err := json.Unmarshal(msg.Params, params); err != nil {
+			return nil, err
+		}
+		level, err := levelFromName(params.Level)
+		if err != nil {
+			return nil, err
+		}
+		switch params.Stream {
+		case StreamLocal:
+			logger.LogLocal(level, "%v", params.Message)
+		case StreamExecution:
+			logger.LogExecution(level, "%v", params.Message)
+		case StreamTask:
+			logger.LogTask(level, "%v", params.Message)
+		case StreamSystem:
+			logger.LogSystem(level, "%v", params.Message)
+		default:
+			return nil, fmt.Errorf("unknown log stream '%v'", params.Stream)
+		}
+		return nil, nil
+	case MethodFlush:
+		logger.Flush()
+		return nil, nil
+	case MethodPostJSON, MethodGetJSON:
+		params := &RequestParams{}
+		if err := json.Unmarshal(msg.Params, params); err != nil {
+			return nil, err
+		}
+		var resp *http.Response
+		var err error
+		if msg.Method == MethodPostJSON {
+			resp, err = pluginCom.TaskPostJSON(params.Endpoint, params.Data)
+		} else {
+			resp, err = pluginCom.TaskGetJSON(params.Endpoint)
+		}
+		if resp != nil {
+			defer resp.Body.Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			return nil, fmt.Errorf("no response from %v", params.Endpoint)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &ResponseResult{StatusCode: resp.StatusCode, Body: string(body)}, nil
+	case MethodPostResults:
+		results := &model.TestResults{}
+		if err := json.Unmarshal(msg.Params, results); err != nil {
+			return nil, err
+		}
+		return nil, pluginCom.TaskPostResults(results)
+	case MethodPostTestLog:
+		log := &model.TestLog{}
+		if err := json.Unmarshal(msg.Params, log); err != nil {
+			return nil, err
+		}
+		return pluginCom.TaskPostTestLog(log)
+	case MethodPostFiles:
+		files := []*artifact.File{}
+		if err := json.Unmarshal(msg.Params, &files); err != nil {
+			return nil, err
+		}
+		return nil, pluginCom.PostTaskFiles(files)
+	}
+	return nil, fmt.Errorf("unknown method '%v'", msg.Method)
+}
+
+func levelFromName(name string) (slogger.Level, error) {
+	switch name {
+	case LevelDebug:
+		return slogger.DEBUG, nil
+	case LevelInfo:
+		return slogger.INFO, nil
+	case LevelWarn:
+		return slogger.WARN, nil
+	case LevelError:
+		return slogger.ERROR, nil
+	}
+	return slogger.INFO, fmt.Errorf("unknown log level '%v'", name)
+}
+
+func levelName(level slogger.Level) string {
+	switch level {
+	case slogger.DEBUG:
+		return LevelDebug
+	case slogger.WARN:
+		return LevelWarn
+	case slogger.ERROR:
+		return LevelError
+	}
+	return LevelInfo
+}
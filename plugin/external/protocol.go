@@ -0,0 +1,199 @@
+// Package external lets commands be provided by plugins that run as their own
+// executables, instead of being compiled into the agent.
+//
+// An external plugin is an executable the agent runs for each call it makes to
+// the plugin. The agent writes a request to the plugin's stdin as a single
+// line of JSON, and the plugin writes its response to stdout the same way.
+// While it executes a command, the plugin may also make calls of its own, for
+// logging and for reporting results, files and test logs to the API server,
+// which the agent answers before the plugin writes its final response. A plugin must write
+// nothing else to stdout; anything it writes to stderr while executing a
+// command goes to the task's logs.
+//
+// Plugins written in Go can use Serve to speak the protocol for them, and so
+// implement their commands as ordinary plugin.Commands.
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ProtocolVersion is the version of the protocol spoken with external
+// plugins. Plugins report the version they speak when described, and are
+// not loaded if it doesn't match.
+const ProtocolVersion = 1
+
+// Methods the agent calls on a plugin.
+const (
+	// MethodDescribe asks a plugin for its name and commands.
+	MethodDescribe = "describe"
+	// MethodParseParams asks a plugin to check the parameters of a command.
+	MethodParseParams = "parse_params"
+	// MethodExecute asks a plugin to run a command.
+	MethodExecute = "execute"
+	// MethodStop tells a plugin to stop the command it is running.
+	MethodStop = "stop"
+)
+
+// Methods a plugin calls on the agent while it runs a command.
+const (
+	MethodLog         = "log"
+	MethodFlush       = "flush"
+	MethodPostJSON    = "post_json"
+	MethodGetJSON     = "get_json"
+	MethodPostResults = "post_results"
+	MethodPostTestLog = "post_test_log"
+	MethodPostFiles   = "post_files"
+)
+
+// Message is a single line of the protocol. Calls have a Method and Params;
+// responses have no Method, and either a Result or an Error.
+type Message struct {
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Description is a plugin's response to MethodDescribe.
+type Description struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Name            string `json:"name"`
+	// the schema for the parameters of each of the plugin's commands, by
+	// command name
+	Commands map[string]ParamsSchema `json:"commands"`
+}
+
+// CommandParams are the params of MethodParseParams.
+type CommandParams struct {
+	Command string                 `json:"command"`
+	Params  map[string]interface{} `json:"params"`
+}
+
+// ExecuteParams are the params of MethodExecute.
+type ExecuteParams struct {
+	Command    string                 `json:"command"`
+	Params     map[string]interface{} `json:"params"`
+	TaskConfig TaskConfig             `json:"task_config"`
+}
+
+// TaskConfig is the part of the agent's configuration for a task that is
+// sent to plugins.
+type TaskConfig struct {
+	TaskId       string            `json:"task_id"`
+	TaskName     string            `json:"task_name"`
+	Execution    int               `json:"execution"`
+	BuildVariant string            `json:"build_variant"`
+	Project      string            `json:"project"`
+	Revision     string            `json:"revision"`
+	Requester    string            `json:"requester"`
+	Distro       string            `json:"distro"`
+	WorkDir      string            `json:"work_dir"`
+	Expansions   map[string]string `json:"expansions"`
+	// the names of the expansions that hold private project variables,
+	// which plugins must keep out of anything they log, store or send
+	PrivateExpansions []string `json:"private_expansions,omitempty"`
+}
+
+// LogParams are the params of MethodLog.
+type LogParams struct {
+	// one of the Stream constants
+	Stream string `json:"stream"`
+	// one of the Level constants
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// The log streams a plugin can log to, matching those of plugin.Logger.
+const (
+	StreamLocal     = "local"
+	StreamExecution = "execution"
+	StreamTask      = "task"
+	StreamSystem    = "system"
+)
+
+// The levels a plugin can log at.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// RequestParams are the params of MethodPostJSON and MethodGetJSON.
+type RequestParams struct {
+	Endpoint string          `json:"endpoint"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// ResponseResult is the result of MethodPostJSON and MethodGetJSON.
+type ResponseResult struct {
+	StatusCode int    `json:"status_code"`
+	Body       string `json:"body"`
+}
+
+// conn reads and writes the lines of the protocol. Writes may come from
+// more than one goroutine; reads may not.
+type conn struct {
+	writeLock sync.Mutex
+	enc       *json.Encoder
+	dec       *json.Decoder
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{enc: json.NewEncoder(w), dec: json.NewDecoder(r)}
+}
+
+func (c *conn) send(msg *Message) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.enc.Encode(msg)
+}
+
+func (c *conn) receive() (*Message, error) {
+	msg := &Message{}
+	if err := c.dec.Decode(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// sendCall sends a call of the given method.
+func (c *conn) sendCall(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("error encoding params of %v: %v", method, err)
+	}
+	return c.send(&Message{Method: method, Params: raw})
+}
+
+// sendResponse sends the response to a call, with either its result or the
+// error it failed with.
+func (c *conn) sendResponse(result interface{}, callErr error) error {
+	if callErr != nil {
+		return c.send(&Message{Error: callErr.Error()})
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return c.send(&Message{Error: fmt.Sprintf("error encoding result: %v", err)})
+	}
+	return c.send(&Message{Result: raw})
+}
+
+// decodeResponse unmarshals the result of a response into result, which
+// may be nil if the call has none, or returns the error it carries.
+func decodeResponse(msg *Message, result interface{}) error {
+	if msg.Method != "" {
+		return fmt.Errorf("expected a response, got a call of %v", msg.Method)
+	}
+	if msg.Error != "" {
+		return fmt.Errorf("%v", msg.Error)
+	}
+	if result == nil || len(msg.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(msg.Result, result)
+}
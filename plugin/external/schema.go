@@ -0,0 +1,131 @@
+package external
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The types a parameter can be declared with in a ParamsSchema.
+const (
+	TypeString = "string"
+	TypeBool   = "bool"
+	TypeNumber = "number"
+	TypeList   = "list"
+	TypeMap    = "map"
+	// TypeAny accepts a value of any type.
+	TypeAny = ""
+)
+
+// ParamSpec describes one parameter of a command.
+type ParamSpec struct {
+	Type     string `json:"type,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// ParamsSchema describes the parameters a command takes, by name. It lets
+// the API server check the parameters of a plugin's commands in a project
+// without running the plugin.
+type ParamsSchema map[string]ParamSpec
+
+// Validate returns an error describing the first problem with the given
+// parameters, if any: a parameter the command doesn't take, a required one
+// that is missing, or one of the wrong type.
+func (schema ParamsSchema) Validate(params map[string]interface{}) error {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		spec, ok := schema[name]
+		if !ok {
+			return fmt.Errorf("unknown parameter '%v'", name)
+		}
+		if !hasType(params[name], spec.Type) {
+			return fmt.Errorf("parameter '%v' must be of type %v", name, spec.Type)
+		}
+	}
+
+	names = make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := params[name]; schema[name].Required && !ok {
+			return fmt.Errorf("missing required parameter '%v'", name)
+		}
+	}
+	return nil
+}
+
+// hasType returns whether a parameter value, as normalized by
+// normalizeParams, is of the given schema type.
+func hasType(value interface{}, paramType string) bool {
+	switch paramType {
+	case TypeAny:
+		return true
+	case TypeString:
+		_, ok := value.(string)
+		return ok
+	case TypeBool:
+		_, ok := value.(bool)
+		return ok
+	case TypeNumber:
+		switch value.(type) {
+		case int, int64, float64:
+			return true
+		}
+		return false
+	case TypeList:
+		_, ok := value.([]interface{})
+		return ok
+	case TypeMap:
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+// normalizeParams returns a copy of the parameters of a command, as parsed
+// from a project's YAML, with any nested maps keyed by strings so that they
+// can be sent to a plugin as JSON.
+func normalizeParams(params map[string]interface{}) (map[string]interface{}, error) {
+	normalized := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		v, err := normalizeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%v': %v", name, err)
+		}
+		normalized[name] = v
+	}
+	return normalized, nil
+}
+
+func normalizeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return normalizeParams(v)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key %v is not a string", key)
+			}
+			m[name] = elem
+		}
+		return normalizeParams(m)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			normalizedElem, err := normalizeValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = normalizedElem
+		}
+		return list, nil
+	}
+	return value, nil
+}
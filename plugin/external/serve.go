@@ -0,0 +1,261 @@
+package external
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Serve answers the call the agent makes on a run of a plugin executable,
+// using the commands of p, whose parameters are described by schemas. It is
+// meant to be all that the main function of a plugin written in Go does:
+//
+//	func main() {
+//		if err := external.Serve(&MyPlugin{}, mySchemas); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+//
+// Only NewCommand and Name are used of p; the commands get a Logger and a
+// PluginCommunicator that pass everything on to the agent.
+func Serve(p plugin.Plugin, schemas map[string]ParamsSchema) error {
+	return serve(p, schemas, os.Stdin, os.Stdout)
+}
+
+func serve(p plugin.Plugin, schemas map[string]ParamsSchema, r io.Reader, w io.Writer) error {
+	c := newConn(r, w)
+	msg, err := c.receive()
+	if err != nil {
+		return fmt.Errorf("error reading call: %v", err)
+	}
+
+	switch msg.Method {
+	case MethodDescribe:
+		return c.sendResponse(&Description{
+			ProtocolVersion: ProtocolVersion,
+			Name:            p.Name(),
+			Commands:        schemas,
+		}, nil)
+	case MethodParseParams:
+		params := &CommandParams{}
+		if err = json.Unmarshal(msg.Params, params); err != nil {
+			return c.sendResponse(nil, err)
+		}
+		_, err = parseCommand(p, params.Command, params.Params)
+		return c.sendResponse(nil, err)
+	case MethodExecute:
+		params := &ExecuteParams{}
+		if err = json.Unmarshal(msg.Params, params); err != nil {
+			return c.sendResponse(nil, err)
+		}
+		cmd, err := parseCommand(p, params.Command, params.Params)
+		if err != nil {
+			return c.sendResponse(nil, err)
+		}
+		return execute(c, cmd, &params.TaskConfig)
+	}
+	return c.sendResponse(nil, fmt.Errorf("unknown method '%v'", msg.Method))
+}
+
+// parseCommand makes the named command of p with the given parameters.
+func parseCommand(p plugin.Plugin, name string, params map[string]interface{}) (plugin.Command, error) {
+	cmd, err := p.NewCommand(name)
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.ParseParams(params); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// execute runs the command, passing the calls it makes on to the agent, and
+// sends the agent the error it finishes with.
+func execute(c *conn, cmd plugin.Command, taskConfig *TaskConfig) error {
+	s := &session{conn: c, responses: make(chan *Message)}
+	stop := make(chan bool)
+
+	// everything the agent sends from here on is either the response to a
+	// call or a request to stop
+	go func() {
+		defer close(s.responses)
+		stopped := false
+		for {
+			msg, err := c.receive()
+			if err != nil {
+				return
+			}
+			if msg.Method == MethodStop {
+				if !stopped {
+					close(stop)
+					stopped = true
+				}
+				continue
+			}
+			s.responses <- msg
+		}
+	}()
+
+	err := cmd.Execute(&remoteLogger{s}, &remoteCommunicator{s}, taskConfig.modelConfig(), stop)
+	return c.sendResponse(nil, err)
+}
+
+// modelConfig returns a task configuration for the commands of a plugin to
+// run with, filled in as far as the TaskConfig allows.
+func (tc *TaskConfig) modelConfig() *model.TaskConfig {
+	privateExpansions := map[string]bool{}
+	for _, name := range tc.PrivateExpansions {
+		privateExpansions[name] = true
+	}
+	return &model.TaskConfig{
+		Distro:     &distro.Distro{Id: tc.Distro},
+		ProjectRef: &model.ProjectRef{Identifier: tc.Project},
+		Project:    &model.Project{Identifier: tc.Project},
+		Task: &model.Task{
+			Id:           tc.TaskId,
+			DisplayName:  tc.TaskName,
+			Execution:    tc.Execution,
+			BuildVariant: tc.BuildVariant,
+			Project:      tc.Project,
+			Revision:     tc.Revision,
+			Requester:    tc.Requester,
+		},
+		BuildVariant:      &model.BuildVariant{Name: tc.BuildVariant},
+		Expansions:        command.NewExpansions(tc.Expansions),
+		WorkDir:           tc.WorkDir,
+		PrivateExpansions: privateExpansions,
+	}
+}
+
+// session makes calls on the agent for a command a plugin is executing,
+// one at a time.
+type session struct {
+	*conn
+	callLock  sync.Mutex
+	responses chan *Message
+}
+
+func (s *session) call(method string, params, result interface{}) error {
+	s.callLock.Lock()
+	defer s.callLock.Unlock()
+	if err := s.sendCall(method, params); err != nil {
+		return err
+	}
+	msg, ok := <-s.responses
+	if !ok {
+		return fmt.Errorf("the agent closed the connection")
+	}
+	return decodeResponse(msg, result)
+}
+
+// remoteLogger is the plugin.Logger for commands run by Serve.
+type remoteLogger struct {
+	*session
+}
+
+func (l *remoteLogger) log(stream string, level slogger.Level, messageFmt string, args ...interface{}) {
+	// there's nowhere left to log a failure to log to
+	l.call(MethodLog, &LogParams{stream, levelName(level), fmt.Sprintf(messageFmt, args...)}, nil)
+}
+
+func (l *remoteLogger) LogLocal(level slogger.Level, messageFmt string, args ...interface{}) {
+	l.log(StreamLocal, level, messageFmt, args...)
+}
+
+func (l *remoteLogger) LogExecution(level slogger.Level, messageFmt string, args ...interface{}) {
+	l.log(StreamExecution, level, messageFmt, args...)
+}
+
+func (l *remoteLogger) LogTask(level slogger.Level, messageFmt string, args ...interface{}) {
+	l.log(StreamTask, level, messageFmt, args...)
+}
+
+func (l *remoteLogger) LogSystem(level slogger.Level, messageFmt string, args ...interface{}) {
+	l.log(StreamSystem, level, messageFmt, args...)
+}
+
+func (l *remoteLogger) GetTaskLogWriter(level slogger.Level) io.Writer {
+	return &remoteLogWriter{l, level}
+}
+
+func (l *remoteLogger) Flush() {
+	l.call(MethodFlush, nil, nil)
+}
+
+// remoteLogWriter logs each non-blank line written to it to the task log,
+// like evergreen.LoggingWriter.
+type remoteLogWriter struct {
+	logger *remoteLogger
+	level  slogger.Level
+}
+
+func (w *remoteLogWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		if strings.Trim(string(line), " ") != "" {
+			w.logger.LogTask(w.level, "%s", line)
+		}
+	}
+	return len(p), nil
+}
+
+// remoteCommunicator is the plugin.PluginCommunicator for commands run by
+// Serve.
+type remoteCommunicator struct {
+	*session
+}
+
+func (rc *remoteCommunicator) request(method, endpoint string, data interface{}) (*http.Response, error) {
+	params := &RequestParams{Endpoint: endpoint}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		params.Data = raw
+	}
+	result := &ResponseResult{}
+	if err := rc.call(method, params, result); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     http.StatusText(result.StatusCode),
+		StatusCode: result.StatusCode,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(result.Body)),
+	}, nil
+}
+
+func (rc *remoteCommunicator) TaskPostJSON(endpoint string, data interface{}) (*http.Response, error) {
+	return rc.request(MethodPostJSON, endpoint, data)
+}
+
+func (rc *remoteCommunicator) TaskGetJSON(endpoint string) (*http.Response, error) {
+	return rc.request(MethodGetJSON, endpoint, nil)
+}
+
+func (rc *remoteCommunicator) TaskPostResults(results *model.TestResults) error {
+	return rc.call(MethodPostResults, results, nil)
+}
+
+func (rc *remoteCommunicator) TaskPostTestLog(log *model.TestLog) (string, error) {
+	id := ""
+	err := rc.call(MethodPostTestLog, log, &id)
+	return id, err
+}
+
+func (rc *remoteCommunicator) PostTaskFiles(files []*artifact.File) error {
+	return rc.call(MethodPostFiles, files, nil)
+}
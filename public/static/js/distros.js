@@ -2,6 +2,8 @@ mciModule.controller('DistrosCtrl', function($scope, $window, mciDistroRestServi
 
   $scope.distros = $window.distros;
 
+  $scope.spotFallbacks = $window.spotFallbacks || {};
+
   $scope.providers = [{
     'id': 'ec2',
     'display': 'EC2 (On-Demand Instance)'
@@ -42,14 +44,54 @@ mciModule.controller('DistrosCtrl', function($scope, $window, mciDistroRestServi
     'display': 'Solaris 64-bit'
   }];
 
+  $scope.dispatchModes = [{
+    'id': 'push',
+    'display': 'Push (taskrunner starts agent over SSH)'
+  }, {
+    'id': 'pull',
+    'display': 'Pull (long-lived agent requests tasks)'
+  }];
+
+  $scope.provisionModes = [{
+    'id': 'ssh',
+    'display': 'SSH (hostinit runs setup script)'
+  }, {
+    'id': 'userdata',
+    'display': 'Userdata (host runs setup script at boot)'
+  }];
+
   $scope.ids = [];
 
   $scope.keys = [];
 
   $scope.modalOpen = false;
 
+  $scope.revisions = [];
+
+  $scope.loadRevisions = function() {
+    $scope.revisions = [];
+    if ($scope.activeDistro == null || $scope.activeDistro.new) {
+      return;
+    }
+    var distroId = $scope.activeDistro._id;
+    mciDistroRestService.getRevisions(
+      distroId, {
+        success: function(revisions, status) {
+          // ignore responses for a distro that's no longer selected
+          if ($scope.activeDistro._id === distroId) {
+            $scope.revisions = revisions;
+          }
+        },
+        error: function(jqXHR, status, errorThrown) {
+          console.log(jqXHR);
+        }
+      }
+    );
+  };
+
   if ($scope.distros != null) {
     $scope.activeDistro = $scope.distros[0];
+    $scope.loadRevisions();
   }
 
   $scope.initOptions = function() {
@@ -77,6 +119,7 @@ mciModule.controller('DistrosCtrl', function($scope, $window, mciDistroRestServi
 
   $scope.setActiveDistro = function(distro) {
     $scope.activeDistro = distro;
+    $scope.loadRevisions();
   };
 
   $scope.setKeyValue = function(key, value) {
@@ -130,6 +173,32 @@ mciModule.controller('DistrosCtrl', function($scope, $window, mciDistroRestServi
     $scope.activeDistro.settings.mount_points.splice(index, 1);
   }
 
+  $scope.addDockerMount = function() {
+    if ($scope.activeDistro.settings.mounts == null) {
+      $scope.activeDistro.settings.mounts = [];
+    }
+    $scope.activeDistro.settings.mounts.push('');
+    $scope.scrollElement('#docker-mounts-table');
+  }
+
+  $scope.removeDockerMount = function(mount) {
+    var index = $scope.activeDistro.settings.mounts.indexOf(mount);
+    $scope.activeDistro.settings.mounts.splice(index, 1);
+  }
+
+  $scope.addLabel = function() {
+    if ($scope.activeDistro.labels == null) {
+      $scope.activeDistro.labels = [];
+    }
+    $scope.activeDistro.labels.push('');
+    $scope.scrollElement('#labels-table');
+  }
+
+  $scope.removeLabel = function(label) {
+    var index = $scope.activeDistro.labels.indexOf(label);
+    $scope.activeDistro.labels.splice(index, 1);
+  }
+
   $scope.addSSHOption = function() {
     if ($scope.activeDistro.ssh_options == null) {
       $scope.activeDistro.ssh_options = [];
@@ -199,6 +268,21 @@ mciModule.controller('DistrosCtrl', function($scope, $window, mciDistroRestServi
     );
   };
 
+  $scope.restoreRevision = function(revision) {
+    mciDistroRestService.restoreRevision(
+      $scope.activeDistro._id,
+      revision.number, {
+        success: function(resp, status) {
+          $window.location.reload(true);
+        },
+        error: function(jqXHR, status, errorThrown) {
+          $window.location.reload(true);
+          console.log(jqXHR);
+        }
+      }
+    );
+  };
+
   $scope.newDistro = function() {
     if (!$scope.hasNew) {
       var defaultOptions = {
@@ -285,6 +369,18 @@ mciModule.filter("archDisplay", function() {
   }
 });
 
+mciModule.filter("dispatchModeDisplay", function() {
+  return function(mode, scope) {
+    return scope.getKeyDisplay('dispatchModes', mode || 'push');
+  }
+});
+
+mciModule.filter("provisionModeDisplay", function() {
+  return function(mode, scope) {
+    return scope.getKeyDisplay('provisionModes', mode || 'ssh');
+  }
+});
+
 mciModule.directive('unique', function() {
   return {
     require: 'ngModel',
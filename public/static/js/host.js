@@ -8,4 +8,6 @@ mciModule.controller('HostCtrl', function($scope, $window) {
     } else {
       $scope.host.uptime = "N/A";
   }
+  // hosts that haven't been found drained have a zero drained time
+  $scope.isDrained = moment($scope.host.drained_time).isAfter(moment(0));
 });
@@ -27,6 +27,21 @@ mciModule.controller('AdminOptionsCtrl', ['$scope', 'mciHostRestService', functi
     );
   };
 
+  $scope.setDraining = function(draining) {
+    hostRestService.setDraining(
+      $scope.host.id,
+      draining,
+      {
+        success: function(data, status) {
+          window.location.reload();
+        },
+        error: function(jqXHR, status, errorThrown) {
+          alert('Error updating host drain mode: ' + jqXHR);
+        }
+      }
+    );
+  };
+
   $scope.setHostStatus = function(status) {
     $scope.newStatus = status;
   };
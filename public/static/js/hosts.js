@@ -37,6 +37,8 @@ mciModule.controller('HostsCtrl', function($scope, $filter, $window, $location)
     },
   ]
 
+  $scope.terminations = $window.terminations || [];
+
   $scope.toggleIncludeSpawnedHosts = function(includeSpawnedHosts) {
       $window.location.href = "/hosts?includeSpawnedHosts=" + includeSpawnedHosts;
   };
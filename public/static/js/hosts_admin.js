@@ -24,6 +24,26 @@ mciModule.controller('AdminOptionsCtrl', ['$scope', '$filter', 'mciHostsRestServ
     );
   };
 
+  $scope.setDraining = function(draining) {
+    var selectedHosts = $scope.selectedHosts();
+    var hostIds = [];
+    for (var i = 0; i < selectedHosts.length; ++i) {
+      hostIds.push(selectedHosts[i].id);
+    }
+    hostsRestService.setDraining(
+      hostIds,
+      draining,
+      {
+        success: function(data, status) {
+          window.location.reload();
+        },
+        error: function(jqXHR, status, errorThrown) {
+          alert('Error updating host drain mode: ' + jqXHR);
+        }
+      }
+    );
+  };
+
   $scope.setHostStatus = function(status) {
     $scope.newStatus = status;
   };
@@ -268,6 +268,11 @@ var mciModule = angular.module('MCI', [
       return 'success';
     } else if (task.status == 'failed') {
       if ('task_end_details' in task) {
+        // tasks whose pre block failed may still have gone on to run, and
+        // only failed in their own commands
+        if (task.task_end_details.setup_failed && task.task_end_details.type == 'system') {
+          return 'setup failed';
+        }
         if ('timed_out' in task.task_end_details) {
           if (task.task_end_details.timed_out && 'desc' in task.task_end_details && task.task_end_details.desc == 'heartbeat') {
             return 'unresponsive';
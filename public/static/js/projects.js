@@ -114,14 +114,17 @@ mciModule.controller('ProjectCtrl', function($scope, $window, $http) {
 
         if (data.ProjectVars) {
          $scope.projectVars = data.ProjectVars.vars;
+         $scope.privateVars = data.ProjectVars.private_vars || {};
         }
         else {
           $scope.projectVars = {};
+          $scope.privateVars = {};
         }
 
         $scope.settingsFormData = {
           identifier : $scope.projectRef.identifier,   
           project_vars: $scope.projectVars,
+          private_vars: $scope.privateVars,
           display_name : $scope.projectRef.display_name,
           remote_path:$scope.projectRef.remote_path,
           batch_time: parseInt($scope.projectRef.batch_time),
@@ -159,6 +162,9 @@ mciModule.controller('ProjectCtrl', function($scope, $window, $http) {
         $scope.isDirty = false;
       }).
       error(function(data, status, errorThrown) {
+        if (status == 400) {
+          $scope.saveMessage = "Settings not saved: " + data;
+        }
         console.log(status);
       });
   };
@@ -166,13 +172,20 @@ mciModule.controller('ProjectCtrl', function($scope, $window, $http) {
   $scope.addProjectVar = function() {
     if ($scope.proj_var.name && $scope.proj_var.value) {
       $scope.settingsFormData.project_vars[$scope.proj_var.name] = $scope.proj_var.value;
+      if ($scope.proj_var.private) {
+        $scope.settingsFormData.private_vars[$scope.proj_var.name] = true;
+      } else {
+        delete $scope.settingsFormData.private_vars[$scope.proj_var.name];
+      }
       $scope.proj_var.name="";
       $scope.proj_var.value="";
+      $scope.proj_var.private=false;
     }
   };
 
   $scope.removeProjectVar = function(name) {
     delete $scope.settingsFormData.project_vars[name];
+    delete $scope.settingsFormData.private_vars[name];
     $scope.isDirty = true;
   };
 
@@ -111,6 +111,16 @@ mciServices.rest.factory('mciHostRestService', ['mciBaseRestService', function(b
         baseSvc.putResource(resource, [hostId], config, callbacks);
     };
 
+    service.setDraining = function(hostId, draining, callbacks) {
+        var config = {
+            data: {
+                action: 'setDraining',
+                draining: draining
+            }
+        };
+        baseSvc.putResource(resource, [hostId], config, callbacks);
+    };
+
     return service;
 }]);
 
@@ -128,6 +138,17 @@ mciServices.rest.factory('mciHostsRestService', ['mciBaseRestService', function(
         baseSvc.putResource(resource, [], config, callbacks);
     };
 
+    service.setDraining = function(hostIds, draining, callbacks) {
+        var config = {
+            data: {
+                action: 'setDraining',
+                draining: draining,
+                host_ids: hostIds
+            }
+        };
+        baseSvc.putResource(resource, [], config, callbacks);
+    };
+
     return service;
 }]);
 
@@ -232,6 +253,7 @@ mciServices.rest.factory('mciSpawnRestService', ['mciBaseRestService', function(
         config.data['key_name'] = spawnInfo.spawnKey.name;
         config.data['public_key'] = spawnInfo.spawnKey.key;
         config.data['userdata'] = spawnInfo.userData;
+        config.data['task_id'] = spawnInfo.taskId;
         baseSvc.putResource(resource, [], config, callbacks);
     };
 
@@ -303,5 +325,13 @@ mciServices.rest.factory('mciDistroRestService', ['mciBaseRestService', function
         baseSvc.deleteResource(resource, [distroId], {}, callbacks);
     }
 
+    service.getRevisions = function(distroId, callbacks) {
+        baseSvc.getResource(resource, [distroId, 'revisions'], {}, callbacks);
+    }
+
+    service.restoreRevision = function(distroId, number, callbacks) {
+        baseSvc.postResource(resource, [distroId, 'revisions', number, 'restore'], {}, callbacks);
+    }
+
     return service;
 }]);
\ No newline at end of file
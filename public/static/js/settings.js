@@ -43,6 +43,7 @@ mciModule.controller('SettingsCtrl', function($scope, $http, $window) {
 
   $scope.user_tz = $window.user_tz;
   $scope.new_tz = $scope.user_tz || "America/New_York";
+  $scope.new_idle_hours = $window.user_idle_hours || null;
   $scope.userConf = $window.userConf;
 
 
@@ -64,8 +65,8 @@ mciModule.controller('SettingsCtrl', function($scope, $http, $window) {
       });
   }
 
-  $scope.updateUserSettings = function(new_tz) {
-    data = {timezone: new_tz};
+  $scope.updateUserSettings = function(new_tz, new_idle_hours) {
+    data = {timezone: new_tz, spawn_host_idle_hours: new_idle_hours || 0};
     $http.put('/settings/', data)
       .success(function(data, status) {
         window.location.reload()
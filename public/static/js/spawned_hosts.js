@@ -14,6 +14,9 @@ mciModule.controller('SpawnedHostsCtrl', function($scope, $window, $timeout, mci
     $scope.curHostData;
     $scope.hostExtensionLengths = {};
 
+    // the task to load onto the spawned host, if any
+    $scope.spawnTask = $window.spawnTask;
+
     // max of 7 days time to expiration
     $scope.maxHoursToExpiration = 24*7;
     $scope.saveKey = false;
@@ -106,6 +109,13 @@ mciModule.controller('SpawnedHostsCtrl', function($scope, $window, $timeout, mci
     $timeout($scope.fetchSpawnedHosts, 5000);
     setInterval(function(){$scope.fetchSpawnedHosts();}, 60000);
 
+    // go straight to spawning when sent here to debug a task
+    if ($scope.spawnTask) {
+      $timeout(function() {
+        $scope.openSpawnModal('spawnHost');
+      }, 1);
+    }
+
 
     $scope.fetchSpawnableDistros = function() {
       mciSpawnRestService.getSpawnableDistros(
@@ -210,10 +220,22 @@ mciModule.controller('SpawnedHostsCtrl', function($scope, $window, $timeout, mci
           return 0;
         });
         $scope.selectedDistro = $scope.spawnableDistros[0].distro;
+        // default to the distro the task ran on
+        if ($scope.spawnTask) {
+          var taskDistro = _.find($scope.spawnableDistros, function(spawnableDistro) {
+            return spawnableDistro.distro.name == $scope.spawnTask.distro;
+          });
+          if (taskDistro) {
+            $scope.selectedDistro = taskDistro.distro;
+          }
+        }
         $scope.spawnInfo = {
           'distroId': $scope.selectedDistro.name,
           'spawnKey': $scope.newKey,
         };
+        if ($scope.spawnTask) {
+          $scope.spawnInfo.taskId = $scope.spawnTask.id;
+        }
       };
     };
 
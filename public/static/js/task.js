@@ -238,6 +238,73 @@ mciModule.controller('TaskCtrl', function($scope, $now, $timeout, $interval, md5
     $locationHash.set(hash);
   };
 
+  // formatBytes renders a number of bytes in the largest unit it fills
+  var formatBytes = function(bytes) {
+    var units = ['B', 'KB', 'MB', 'GB', 'TB'];
+    var i = 0;
+    while (bytes >= 1024 && i < units.length - 1) {
+      bytes /= 1024;
+      i++;
+    }
+    return bytes.toFixed(i == 0 ? 0 : 1) + ' ' + units[i];
+  };
+
+  // resourceCharts turns the resource samples the agent took while running
+  // the task into line charts, one per resource, drawn on a 100 x 100 canvas
+  var resourceCharts = function(samples) {
+    if (samples.length < 2) {
+      return [];
+    }
+    var start = new Date(samples[0].time).getTime();
+    var span = (new Date(samples[samples.length - 1].time).getTime() - start) || 1;
+    var rate = function(bytes) {
+      return formatBytes(bytes) + '/s';
+    };
+    var charts = [{
+      title: 'CPU',
+      max: 100,
+      format: function(percent) {
+        return percent.toFixed(0) + '%';
+      },
+      series: [{name: 'used', key: 'cpu_percent'}]
+    }, {
+      title: 'Memory',
+      max: _.max(_.pluck(samples, 'memory_total')),
+      format: formatBytes,
+      series: [{name: 'used', key: 'memory_used'}]
+    }, {
+      title: 'Disk',
+      format: rate,
+      series: [{name: 'read', key: 'disk_read_rate'}, {name: 'write', key: 'disk_write_rate'}]
+    }, {
+      title: 'Task disk',
+      format: formatBytes,
+      series: [{name: 'used', key: 'disk_used'}]
+    }, {
+      title: 'Network',
+      format: rate,
+      series: [{name: 'received', key: 'network_recv_rate'}, {name: 'sent', key: 'network_send_rate'}]
+    }];
+
+    charts.forEach(function(chart) {
+      if (!chart.max) {
+        chart.max = _.max(_.flatten(_.map(chart.series, function(series) {
+          return _.pluck(samples, series.key);
+        }))) || 1;
+      }
+      chart.maxLabel = chart.format(chart.max);
+      chart.series.forEach(function(series) {
+        series.points = _.map(samples, function(sample) {
+          var x = (new Date(sample.time).getTime() - start) / span * 100;
+          var y = 100 - sample[series.key] / chart.max * 100;
+          return x.toFixed(2) + ',' + y.toFixed(2);
+        }).join(' ');
+        series.peak = chart.format(_.max(_.pluck(samples, series.key)));
+      });
+    });
+    return charts;
+  };
+
   $scope.linkToTest = function(testName) {
     if (hash.test === testName) {
       delete hash.test;
@@ -274,6 +341,11 @@ mciModule.controller('TaskCtrl', function($scope, $now, $timeout, $interval, md5
       testResult.time_taken = testResult.end - testResult.start;
       testResult.display_name = $filter('endOfPath')(testResult.test_file);
     });
+    (task.command_records || []).forEach(function(record) {
+      // in nanoseconds, to match the task's own time taken
+      record.time_taken = (new Date(record.end_time) - new Date(record.start_time)) * 1000000;
+    });
+    $scope.showSteps = task.status == 'failed';
 
     if (hash.sort) {
       var index = _.indexOf(_.pluck($scope.sortOrders, 'name'), hash.sort);
@@ -298,6 +370,12 @@ mciModule.controller('TaskCtrl', function($scope, $now, $timeout, $interval, md5
       alert('Error getting task dependencies: ' + JSON.stringify(data));
     });
 
+    $scope.resourceCharts = [];
+    $http.get('/task/resource_stats/' + task.id + '/' + task.execution).
+    success(function(samples) {
+      $scope.resourceCharts = resourceCharts(samples);
+    });
+
     $scope.isMet = function(dependency) {
       // check if a dependency is met, unmet, or in progress
       if (dependency.status != "failed" && dependency.status != "success") {
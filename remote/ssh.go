@@ -9,6 +9,7 @@ import (
 
 var (
 	ErrCmdTimedOut = fmt.Errorf("ssh command timed out")
+	ErrCmdStopped  = fmt.Errorf("ssh command stopped")
 )
 
 // SSHCommand abstracts a single command to be run via ssh, on a remote machine.
@@ -30,6 +31,9 @@ type SSHCommand struct {
 
 	// the threshold at which the command is considered to time out, and will be killed
 	Timeout time.Duration
+
+	// if set, the command is killed once this is closed
+	Stop <-chan bool
 }
 
 // Run the command via ssh. Returns the combined stdout and stderr, as well as any
@@ -88,6 +92,10 @@ func (cmd *SSHCommand) Run() ([]byte, error) {
 		// command timed out; kill the remote process
 		session.Signal(ssh.SIGKILL)
 		return nil, ErrCmdTimedOut
+
+	case <-cmd.Stop:
+		session.Signal(ssh.SIGKILL)
+		return nil, ErrCmdStopped
 	}
 
 }
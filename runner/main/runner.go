@@ -9,6 +9,7 @@ import (
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/db"
 	"github.com/evergreen-ci/evergreen/notify"
+	"github.com/evergreen-ci/evergreen/plugin/external"
 	. "github.com/evergreen-ci/evergreen/runner"
 	"os"
 	"os/signal"
@@ -62,6 +63,11 @@ func main() {
 
 	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(settings))
 
+	// make the commands of external plugins known to project validation
+	if err := external.Publish(settings.ExternalPluginsDir); err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Error publishing external plugins: %v", err)
+	}
+
 	// just run one process if an argument was passed in
 	if flag.Arg(0) != "" {
 		err := runProcessByName(flag.Arg(0), settings)
@@ -48,6 +48,11 @@ func (self *DeficitBasedHostAllocator) NewHostsNeeded(
 func (self *DeficitBasedHostAllocator) numNewHostsForDistro(
 	hostAllocatorData *HostAllocatorData, distro distro.Distro, settings *evergreen.Settings) int {
 
+	if distro.Draining {
+		evergreen.Logger.Logf(slogger.INFO, "Distro %v is draining; not spawning hosts", distro.Id)
+		return 0
+	}
+
 	cloudManager, err := providers.GetCloudManager(distro.Provider, settings)
 
 	if err != nil {
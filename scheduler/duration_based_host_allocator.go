@@ -416,6 +416,11 @@ func (self *DurationBasedHostAllocator) numNewHostsForDistro(
 	distroScheduleData map[string]DistroScheduleData, settings *evergreen.Settings) (numNewHosts int,
 	err error) {
 
+	if distro.Draining {
+		evergreen.Logger.Logf(slogger.INFO, "Distro %v is draining; not spawning hosts", distro.Id)
+		return 0, nil
+	}
+
 	projectTaskDurations := hostAllocatorData.projectTaskDurations
 	existingDistroHosts := hostAllocatorData.existingDistroHosts[distro.Id]
 	taskQueueItems := hostAllocatorData.taskQueueItems[distro.Id]
@@ -52,18 +52,18 @@ func (self *Scheduler) Schedule() error {
 
 	evergreen.Logger.Logf(slogger.INFO, "There are %v tasks ready to be run", len(runnableTasks))
 
-	// split the tasks by distro
-	tasksByDistro, taskRunDistros, err := self.splitTasksByDistro(runnableTasks)
-	if err != nil {
-		return fmt.Errorf("Error splitting tasks by distro to run on: %v", err)
-	}
-
 	// load in all of the distros
 	distros, err := distro.Find(distro.All)
 	if err != nil {
 		return fmt.Errorf("Error finding distros: %v", err)
 	}
 
+	// split the tasks by distro
+	tasksByDistro, taskRunDistros, err := self.splitTasksByDistro(runnableTasks, distros)
+	if err != nil {
+		return fmt.Errorf("Error splitting tasks by distro to run on: %v", err)
+	}
+
 	taskIdToMinQueuePos := make(map[string]int)
 
 	// get the expected run duration of all runnable tasks
@@ -211,9 +211,10 @@ func (self *Scheduler) updateVersionBuildVarMap(versionStr string,
 // Takes in a list of tasks, and splits them by distro.
 // Returns a map of distro name -> tasks that can be run on that distro
 // and a map of task id -> distros that the task can be run on (for tasks
-// that can be run on multiple distro)
-func (self *Scheduler) splitTasksByDistro(tasksToSplit []model.Task) (
-	map[string][]model.Task, map[string][]string, error) {
+// that can be run on multiple distro). Tasks that ask for distros by label
+// are queued on every matching distro, like tasks that name several.
+func (self *Scheduler) splitTasksByDistro(tasksToSplit []model.Task,
+	distros []distro.Distro) (map[string][]model.Task, map[string][]string, error) {
 	tasksByDistro := make(map[string][]model.Task)
 	taskRunDistros := make(map[string][]string)
 
@@ -257,11 +258,20 @@ func (self *Scheduler) splitTasksByDistro(tasksToSplit []model.Task) (
 			continue
 		}
 
-		// use the specified distros for the task, or, if none are specified,
-		// the default distros for the build variant
-		distrosToUse := buildVariant.RunOn
-		if len(taskSpec.Distros) != 0 {
-			distrosToUse = taskSpec.Distros
+		// use the specified distros and labels for the task, or, if none are
+		// specified, the defaults for the build variant
+		distrosToUse, labels := buildVariant.RunOn, buildVariant.RunOnLabels
+		if len(taskSpec.Distros) != 0 || len(taskSpec.Labels) != 0 {
+			distrosToUse, labels = taskSpec.Distros, taskSpec.Labels
+		}
+		if len(labels) != 0 {
+			candidates := labelCandidates(distros, distrosToUse, labels)
+			if len(candidates) == 0 {
+				evergreen.Logger.Logf(slogger.WARN, "task %v asks for labels %v, which no"+
+					" distro has", task.Id, labels)
+				continue
+			}
+			distrosToUse = candidates
 		}
 		for _, d := range distrosToUse {
 			tasksByDistro[d] = append(tasksByDistro[d], task)
@@ -278,6 +288,36 @@ func (self *Scheduler) splitTasksByDistro(tasksToSplit []model.Task) (
 
 }
 
+// labelCandidates returns the distros a task asking for the given distros
+// and labels may run on: the distros named, along with every distro with
+// all of the labels. Draining distros are left out, unless every candidate
+// is draining.
+func labelCandidates(distros []distro.Distro, named []string, labels []string) []string {
+	matches := map[string]bool{}
+	for _, id := range named {
+		matches[id] = true
+	}
+	for _, id := range distro.MatchLabels(distros, labels) {
+		matches[id] = true
+	}
+
+	candidates, draining := []string{}, []string{}
+	for _, d := range distros {
+		if !matches[d.Id] {
+			continue
+		}
+		if d.Draining {
+			draining = append(draining, d.Id)
+		} else {
+			candidates = append(candidates, d.Id)
+		}
+	}
+	if len(candidates) == 0 {
+		return draining
+	}
+	return candidates
+}
+
 // Call out to the embedded CloudManager to spawn hosts.  Takes in a map of
 // distro -> number of hosts to spawn for the distro.
 // Returns a map of distro -> hosts spawned, and an error if one occurs.
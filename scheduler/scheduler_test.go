@@ -208,3 +208,31 @@ func TestSpawnHosts(t *testing.T) {
 	})
 
 }
+
+func TestLabelPlacement(t *testing.T) {
+	Convey("When placing tasks that ask for distros by label", t, func() {
+		distros := []distro.Distro{
+			{Id: "linux1", Labels: []string{"os:linux"}},
+			{Id: "linux2", Labels: []string{"os:linux"}},
+			{Id: "linux3", Labels: []string{"os:linux"}, Draining: true},
+			{Id: "windows", Labels: []string{"os:windows"}},
+		}
+
+		Convey("the candidates should be the named and matching distros that"+
+			" aren't draining", func() {
+			So(labelCandidates(distros, nil, []string{"os:linux"}), ShouldResemble,
+				[]string{"linux1", "linux2"})
+			So(labelCandidates(distros, []string{"windows"}, []string{"os:linux"}),
+				ShouldResemble, []string{"linux1", "linux2", "windows"})
+			So(labelCandidates(distros, nil, []string{"os:mac"}), ShouldResemble,
+				[]string{})
+		})
+
+		Convey("draining distros should be used if no others match", func() {
+			distros[0].Draining = true
+			distros[1].Draining = true
+			So(labelCandidates(distros, nil, []string{"os:linux"}), ShouldResemble,
+				[]string{"linux1", "linux2", "linux3"})
+		})
+	})
+}
@@ -2,7 +2,6 @@ package spawn
 
 import (
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/10gen-labs/slogger/v1"
@@ -10,10 +9,9 @@ import (
 	"github.com/evergreen-ci/evergreen/cloud/providers"
 	"github.com/evergreen-ci/evergreen/command"
 	"github.com/evergreen-ci/evergreen/hostinit"
+	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
-	"gopkg.in/yaml.v2"
-	"net/url"
 	"strings"
 	"time"
 )
@@ -21,6 +19,9 @@ import (
 const (
 	MaxPerUser        = 3
 	DefaultExpiration = time.Duration(24 * time.Hour)
+
+	// how far in the future a spawned host's expiration may be pushed
+	MaxExpirationDuration = time.Duration(7 * 24 * time.Hour)
 )
 
 var (
@@ -47,6 +48,13 @@ type Options struct {
 	UserName  string
 	PublicKey string
 	UserData  string
+
+	// TaskId is the task to load onto the host for debugging, if any. The
+	// task's distro is used if no distro is given.
+	TaskId string
+
+	// the script that loads the task, built when the host is created
+	taskSetup string
 }
 
 // New returns an initialized Spawn controller.
@@ -58,6 +66,19 @@ func New(settings *evergreen.Settings) Spawn {
 // data, SpawnLimitErr if the user is already at the spawned host limit, or some other untyped
 // instance of Error if something fails during validation.
 func (sm Spawn) Validate(so Options) error {
+	if so.TaskId != "" {
+		t, err := model.FindTask(so.TaskId)
+		if err != nil {
+			return fmt.Errorf("Error finding task %v: %v", so.TaskId, err)
+		}
+		if t == nil {
+			return BadOptionsErr{fmt.Sprintf("Invalid task %v", so.TaskId)}
+		}
+		if so.Distro == "" {
+			so.Distro = t.DistroId
+		}
+	}
+
 	d, err := distro.FindOne(distro.ById(so.Distro))
 	if err != nil {
 		return BadOptionsErr{fmt.Sprintf("Invalid dist %v", so.Distro)}
@@ -105,18 +126,7 @@ func (sm Spawn) Validate(so Options) error {
 			return BadOptionsErr{}
 		}
 
-		var err error
-		switch d.UserData.Validate {
-		case distro.UserDataFormatFormURLEncoded:
-			_, err = url.ParseQuery(so.UserData)
-		case distro.UserDataFormatJSON:
-			var out map[string]interface{}
-			err = json.Unmarshal([]byte(so.UserData), &out)
-		case distro.UserDataFormatYAML:
-			var out map[string]interface{}
-			err = yaml.Unmarshal([]byte(so.UserData), &out)
-		}
-
+		err := d.UserData.ValidateData(so.UserData)
 		if err != nil {
 			return BadOptionsErr{fmt.Sprintf("invalid %v: %v", d.UserData.Validate, err)}
 		}
@@ -127,18 +137,45 @@ func (sm Spawn) Validate(so Options) error {
 // CreateHost spawns a host with the given options.
 func (sm Spawn) CreateHost(so Options) (*host.Host, error) {
 
+	// load in the task to set the host up with, if any
+	var t *model.Task
+	var err error
+	if so.TaskId != "" {
+		if t, err = model.FindTask(so.TaskId); err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, fmt.Errorf("task %v not found", so.TaskId)
+		}
+		if so.Distro == "" {
+			so.Distro = t.DistroId
+		}
+	}
+
 	// load in the appropriate distro
 	d, err := distro.FindOne(distro.ById(so.Distro))
 	if err != nil {
 		return nil, err
 	}
 
+	if t != nil {
+		if so.taskSetup, err = taskSetup(t, d); err != nil {
+			return nil, fmt.Errorf("error setting up task %v: %v", t.Id, err)
+		}
+	}
+
 	// get the appropriate cloud manager
 	cloudManager, err := providers.GetCloudManager(d.Provider, sm.settings)
 	if err != nil {
 		return nil, err
 	}
 
+	// hosts provisioned by userdata get their setup script at launch, so
+	// the user's additions have to be made before spawning
+	if d.UsesUserDataProvisioning() {
+		d.Setup = addUserSetup(d.Setup, d, so)
+	}
+
 	// spawn the host
 	h, err := cloudManager.SpawnInstance(d, so.UserName, true)
 	if err != nil {
@@ -162,6 +199,11 @@ func (sm Spawn) CreateHost(so Options) (*host.Host, error) {
 		}
 	}
 
+	// the host runs its own setup and reports back to the API server
+	if d.UsesUserDataProvisioning() {
+		return h, nil
+	}
+
 	// create a hostinit to take care of setting up the host
 	init := &hostinit.HostInit{
 		Settings: sm.settings,
@@ -204,22 +246,7 @@ func (sm Spawn) CreateHost(so Options) (*host.Host, error) {
 
 	evergreen.Logger.Logf(slogger.INFO, "Host %v is ready for its setup script to be run", h.Id)
 
-	// add any extra user-specified data into the setup script
-	if h.Distro.UserData.File != "" {
-		userDataCmd := fmt.Sprintf("echo \"%v\" > %v\n",
-			strings.Replace(so.UserData, "\"", "\\\"", -1), h.Distro.UserData.File)
-		// prepend the setup script to add the userdata file
-		if strings.HasPrefix(h.Distro.Setup, "#!") {
-			firstLF := strings.Index(h.Distro.Setup, "\n")
-			h.Distro.Setup = h.Distro.Setup[0:firstLF+1] + userDataCmd + h.Distro.Setup[firstLF+1:]
-		} else {
-			h.Distro.Setup = userDataCmd + h.Distro.Setup
-		}
-	}
-
-	// modify the setup script to add the user's public key
-	h.Distro.Setup += fmt.Sprintf("\necho \"\n%v\" >> ~%v/.ssh/authorized_keys\n",
-		so.PublicKey, h.Distro.User)
+	h.Distro.Setup = addUserSetup(h.Distro.Setup, &h.Distro, so)
 
 	// replace expansions in the script
 	exp := command.NewExpansions(init.Settings.Expansions)
@@ -236,3 +263,59 @@ func (sm Spawn) CreateHost(so Options) (*host.Host, error) {
 
 	return h, nil
 }
+
+// ExtendExpiration pushes the host's expiration time back by the given
+// duration. Returns a BadOptionsErr if that would put the expiration more
+// than MaxExpirationDuration from now.
+func (sm Spawn) ExtendExpiration(h *host.Host, extension time.Duration) error {
+	if extension <= 0 {
+		return BadOptionsErr{"extension must be positive"}
+	}
+	newExpiration := h.ExpirationTime.Add(extension)
+	if newExpiration.Sub(time.Now()) > MaxExpirationDuration {
+		return BadOptionsErr{fmt.Sprintf("can not extend %v expiration by %v."+
+			" Maximum expiration is %v from now", h.Id, extension, MaxExpirationDuration)}
+	}
+	return h.SetExpirationTime(newExpiration)
+}
+
+// RequestStatus returns the status of the spawn request that created the
+// host, as seen by the user who made it: whether the host is ready to use.
+func RequestStatus(h *host.Host) string {
+	switch h.Status {
+	case evergreen.HostRunning:
+		return evergreen.SpawnRequestReady
+	case evergreen.HostTerminated:
+		return evergreen.SpawnRequestTerminated
+	case evergreen.HostUninitialized, evergreen.HostInitializing:
+		return evergreen.SpawnRequestInit
+	default:
+		return evergreen.SpawnRequestUnusable
+	}
+}
+
+// addUserSetup returns the given setup script with commands added to write
+// the user's data file, if the distro has one, and to authorize the user's
+// public key.
+func addUserSetup(setup string, d *distro.Distro, so Options) string {
+	// add any extra user-specified data into the setup script
+	if d.UserData.File != "" {
+		userDataCmd := fmt.Sprintf("echo \"%v\" > %v\n",
+			strings.Replace(so.UserData, "\"", "\\\"", -1), d.UserData.File)
+		// prepend the setup script to add the userdata file
+		if strings.HasPrefix(setup, "#!") {
+			firstLF := strings.Index(setup, "\n")
+			setup = setup[0:firstLF+1] + userDataCmd + setup[firstLF+1:]
+		} else {
+			setup = userDataCmd + setup
+		}
+	}
+
+	// modify the setup script to add the user's public key
+	setup += fmt.Sprintf("\necho \"\n%v\" >> ~%v/.ssh/authorized_keys\n",
+		so.PublicKey, d.User)
+
+	// load the task the host is being spawned to debug
+	setup += so.taskSetup
+	return setup
+}
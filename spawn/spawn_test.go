@@ -0,0 +1,24 @@
+package spawn
+
+import (
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/host"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestRequestStatus(t *testing.T) {
+	Convey("A spawn request should only be ready once its host is running", t, func() {
+		statuses := map[string]string{
+			evergreen.HostUninitialized:   evergreen.SpawnRequestInit,
+			evergreen.HostInitializing:    evergreen.SpawnRequestInit,
+			evergreen.HostRunning:         evergreen.SpawnRequestReady,
+			evergreen.HostProvisionFailed: evergreen.SpawnRequestUnusable,
+			evergreen.HostDecommissioned:  evergreen.SpawnRequestUnusable,
+			evergreen.HostTerminated:      evergreen.SpawnRequestTerminated,
+		}
+		for hostStatus, requestStatus := range statuses {
+			So(RequestStatus(&host.Host{Status: hostStatus}), ShouldEqual, requestStatus)
+		}
+	})
+}
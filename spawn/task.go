@@ -0,0 +1,304 @@
+package spawn
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/patch"
+	"github.com/evergreen-ci/evergreen/model/version"
+	"github.com/evergreen-ci/evergreen/util"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/yaml.v2"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// files written to the working directory of a host spawned from a task
+	TaskLoadScript  = "load-task.sh"
+	TaskLoadLog     = "load-task.log"
+	TaskExpansions  = "expansions.yml"
+	TaskArtifactDir = "artifacts"
+
+	// the directory the source is cloned into if the task doesn't use
+	// git.get_project
+	DefaultTaskSourceDir = "src"
+)
+
+// taskSetup returns a script that loads the task onto a host of the given
+// distro: its expansions are written to a file, its source is cloned and
+// patched the way its git.get_project and git.apply_patch commands would,
+// and its artifacts are downloaded, all into the distro's working directory.
+// Project variables are left out, since they may hold secrets.
+func taskSetup(t *model.Task, d *distro.Distro) (string, error) {
+	v, err := version.FindOne(version.ById(t.Version))
+	if err != nil {
+		return "", fmt.Errorf("error finding version %v: %v", t.Version, err)
+	}
+	if v == nil {
+		return "", fmt.Errorf("version %v not found", t.Version)
+	}
+
+	project := &model.Project{}
+	if err = model.LoadProjectInto([]byte(v.Config), t.Project, project); err != nil {
+		return "", fmt.Errorf("error loading project for version %v: %v", v.Id, err)
+	}
+
+	ref, err := model.FindOneProjectRef(t.Project)
+	if err != nil {
+		return "", fmt.Errorf("error finding project ref %v: %v", t.Project, err)
+	}
+
+	conf, err := model.NewTaskConfig(d, project, t, ref)
+	if err != nil {
+		return "", err
+	}
+
+	var p *patch.Patch
+	if t.Requester == evergreen.PatchVersionRequester {
+		if p, err = t.FetchPatch(); err != nil {
+			return "", fmt.Errorf("error finding patch: %v", err)
+		}
+		if p == nil {
+			return "", fmt.Errorf("no patch found for task %v", t.Id)
+		}
+	}
+
+	entries, err := artifact.FindAll(artifact.ByTaskId(t.Id))
+	if err != nil {
+		return "", fmt.Errorf("error finding artifacts: %v", err)
+	}
+	files := []artifact.File{}
+	for _, entry := range entries {
+		files = append(files, entry.Files...)
+	}
+
+	loadScript, err := taskLoadScript(conf, p, files)
+	if err != nil {
+		return "", err
+	}
+
+	// the load script is written out and run separately, so that a failure
+	// to load the task leaves the user with a host to investigate it on, and
+	// so that the user can run it again. It's encoded since the setup script
+	// goes through expansion, which would mangle patches and expansions
+	workDir := quote(d.WorkDir)
+	delimiter := "EVERGREEN_LOAD_TASK_" + bson.NewObjectId().Hex()
+	lines := []string{
+		"",
+		fmt.Sprintf("# load task %v for debugging", t.Id),
+		fmt.Sprintf("mkdir -p %v", workDir),
+		fmt.Sprintf("base64 --decode > %v/%v <<'%v'", workDir, TaskLoadScript, delimiter),
+		encode(loadScript),
+		delimiter,
+		fmt.Sprintf("(cd %v && bash %v > %v 2>&1) || echo \"Loading task %v failed; see %v/%v\"",
+			workDir, TaskLoadScript, TaskLoadLog, t.Id, d.WorkDir, TaskLoadLog),
+		// setup scripts may run as root, but the user logs in as the
+		// distro's user
+		fmt.Sprintf("chown -R %v %v || true", quote(d.User), workDir),
+		"",
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// taskLoadScript returns the script that loads the task's expansions,
+// source, patch and artifacts into the current directory.
+func taskLoadScript(conf *model.TaskConfig, p *patch.Patch, files []artifact.File) (string, error) {
+	lines := []string{
+		"set -o errexit",
+		"set -o verbose",
+	}
+
+	// expansions are written as YAML, for the expansions.update command
+	expansionsYAML, err := yaml.Marshal(conf.Expansions)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling expansions: %v", err)
+	}
+	lines = append(lines, heredoc(TaskExpansions, string(expansionsYAML))...)
+
+	// clone the source where the task's git.get_project would
+	sourceDir, err := commandDirectory(conf, "git.get_project")
+	if err != nil {
+		return "", err
+	}
+	if sourceDir == "" {
+		sourceDir = DefaultTaskSourceDir
+	}
+	if sourceDir, err = workDirPath(conf.WorkDir, sourceDir); err != nil {
+		return "", fmt.Errorf("error finding source directory: %v", err)
+	}
+	location, err := conf.ProjectRef.Location()
+	if err != nil {
+		return "", err
+	}
+	lines = append(lines,
+		fmt.Sprintf("rm -rf %v", quote(sourceDir)),
+		fmt.Sprintf("git clone %v %v", quote(location), quote(sourceDir)),
+		fmt.Sprintf("(cd %v && git checkout %v)", quote(sourceDir), quote(conf.Task.Revision)),
+	)
+	for _, moduleName := range conf.BuildVariant.Modules {
+		module, err := conf.Project.GetModuleByName(moduleName)
+		if err != nil || module == nil {
+			return "", fmt.Errorf("error finding module %v: %v", moduleName, err)
+		}
+		moduleBase, err := workDirPath("", path.Join(module.Prefix, module.Name))
+		if err != nil {
+			return "", fmt.Errorf("error finding directory of module %v: %v", module.Name, err)
+		}
+		lines = append(lines, fmt.Sprintf("(cd %v && git clone %v %v && cd %v && git checkout %v)",
+			quote(sourceDir), quote(module.Repo), quote(moduleBase), quote(moduleBase),
+			moduleRevision(conf, p, module)))
+	}
+
+	// apply the patch where the task's git.apply_patch would
+	if p != nil {
+		patchDir, err := commandDirectory(conf, "git.apply_patch")
+		if err != nil {
+			return "", err
+		}
+		if patchDir == "" {
+			patchDir = sourceDir
+		}
+		for i, part := range p.Patches {
+			dir := patchDir
+			if part.ModuleName != "" {
+				module, err := conf.Project.GetModuleByName(part.ModuleName)
+				if err != nil || module == nil {
+					return "", fmt.Errorf("error finding module %v: %v", part.ModuleName, err)
+				}
+				// skip modules the build variant doesn't use, as the
+				// git.apply_patch command does
+				if !util.SliceContains(conf.BuildVariant.Modules, module.Name) {
+					continue
+				}
+				dir = path.Join(patchDir, module.Prefix, module.Name)
+			}
+			patchFile := fmt.Sprintf("patch_%v.diff", i)
+			lines = append(lines, heredoc(patchFile, part.PatchSet.Patch)...)
+			lines = append(lines, fmt.Sprintf("(cd %v && git checkout %v && git apply --whitespace=fix %v)",
+				quote(dir), quote(part.Githash), quote(path.Join(conf.WorkDir, patchFile))))
+		}
+	}
+
+	// download the task's artifacts
+	if len(files) > 0 {
+		lines = append(lines, fmt.Sprintf("mkdir -p %v", TaskArtifactDir))
+	}
+	for _, file := range files {
+		lines = append(lines, fmt.Sprintf("curl --fail --location --silent --show-error -o %v %v",
+			quote(path.Join(TaskArtifactDir, artifactFileName(file))), quote(file.Link)))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// moduleRevision returns the shell word for the revision of the module to
+// check out. A patch pins the revision of the modules it has changes for;
+// other modules are checked out at the last commit on their branch from
+// before the task's version was created, rather than at the branch's head,
+// so that the host gets the module source the task ran with.
+func moduleRevision(conf *model.TaskConfig, p *patch.Patch, module *model.Module) string {
+	if p != nil {
+		for _, part := range p.Patches {
+			if part.ModuleName == module.Name && part.Githash != "" {
+				return quote(part.Githash)
+			}
+		}
+	}
+	return fmt.Sprintf("$(git rev-list -n 1 --before=%v %v)",
+		quote(conf.Task.CreateTime.UTC().Format(time.RFC3339)), quote("origin/"+module.Branch))
+}
+
+// workDirPath returns the path relative to the working directory, refusing
+// paths outside of it, so that nothing outside the working directory is
+// removed or overwritten when the task is loaded.
+func workDirPath(workDir, p string) (string, error) {
+	if workDir != "" && path.IsAbs(p) {
+		if rel, err := filepath.Rel(workDir, p); err == nil {
+			p = filepath.ToSlash(rel)
+		}
+	}
+	p = path.Clean(p)
+	if path.IsAbs(p) || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("'%v' is not a path inside the working directory", p)
+	}
+	return p, nil
+}
+
+// commandDirectory returns the expanded directory parameter of the first
+// command of the given name that the task runs, or an empty string if it
+// runs no such command.
+func commandDirectory(conf *model.TaskConfig, name string) (string, error) {
+	cmds := []model.PluginCommandConf{}
+	if conf.Project.Pre != nil {
+		cmds = append(cmds, conf.Project.Pre.List()...)
+	}
+	if projectTask := conf.Project.FindProjectTask(conf.Task.DisplayName); projectTask != nil {
+		cmds = append(cmds, projectTask.Commands...)
+	}
+
+	for _, cmd := range cmds {
+		expanded := []model.PluginCommandConf{cmd}
+		if cmd.Function != "" {
+			function, ok := conf.Project.Functions[cmd.Function]
+			if !ok {
+				continue
+			}
+			expanded = function.List()
+		}
+		for _, c := range expanded {
+			if c.Command != name || !c.RunOnVariant(conf.BuildVariant.Name) {
+				continue
+			}
+			dir, _ := c.Params["directory"].(string)
+			return conf.Expansions.ExpandString(dir)
+		}
+	}
+	return "", nil
+}
+
+// artifactFileName returns the name to save the artifact as: the last
+// element of its link, or its display name if the link has no path.
+func artifactFileName(file artifact.File) string {
+	if u, err := url.Parse(file.Link); err == nil {
+		if name := path.Base(u.Path); name != "/" && name != "." {
+			return name
+		}
+	}
+	return strings.Replace(file.Name, "/", "_", -1)
+}
+
+// heredoc returns the lines of a shell command that writes the contents to
+// the named file verbatim.
+func heredoc(file, contents string) []string {
+	delimiter := "EVERGREEN_EOF_" + bson.NewObjectId().Hex()
+	return []string{
+		fmt.Sprintf("cat > %v <<'%v'", quote(file), delimiter),
+		strings.TrimSuffix(contents, "\n"),
+		delimiter,
+	}
+}
+
+// encode returns the string base64 encoded, in lines of the length
+// base64 tools produce.
+func encode(s string) string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(s))
+	lines := []string{}
+	for len(encoded) > 76 {
+		lines = append(lines, encoded[:76])
+		encoded = encoded[76:]
+	}
+	lines = append(lines, encoded)
+	return strings.Join(lines, "\n")
+}
+
+// quote returns the string single-quoted for the shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,137 @@
+package spawn
+
+import (
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/model/patch"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskLoadScript(t *testing.T) {
+	Convey("With a task that fetches its source through a function", t, func() {
+		project := &model.Project{
+			Functions: map[string]*model.YAMLCommandSet{
+				"fetch source": &model.YAMLCommandSet{
+					MultiCommand: []model.PluginCommandConf{
+						{Command: "git.get_project", Params: map[string]interface{}{
+							"directory": "${workdir}/src",
+						}},
+						{Command: "git.apply_patch", Params: map[string]interface{}{
+							"directory": "${workdir}/src",
+						}},
+					},
+				},
+			},
+			Tasks: []model.ProjectTask{
+				{Name: "compile", Commands: []model.PluginCommandConf{
+					{Function: "fetch source"},
+					{Command: "shell.exec"},
+				}},
+			},
+			Modules: []model.Module{
+				{Name: "enterprise", Repo: "git@github.com:evergreen-ci/enterprise.git",
+					Branch: "master", Prefix: "src/modules"},
+				{Name: "unused", Repo: "git@github.com:evergreen-ci/unused.git",
+					Branch: "master", Prefix: "src/modules"},
+			},
+		}
+		bv := &model.BuildVariant{Name: "linux", Modules: []string{"enterprise"}}
+		task := &model.Task{Id: "t1", DisplayName: "compile", Revision: "abc123",
+			CreateTime: time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)}
+		conf := &model.TaskConfig{
+			ProjectRef:   &model.ProjectRef{Owner: "evergreen-ci", Repo: "sample"},
+			Project:      project,
+			Task:         task,
+			BuildVariant: bv,
+			Expansions: command.NewExpansions(map[string]string{
+				"workdir": "/data/mci",
+				"secret":  "${not_an_expansion}",
+			}),
+			WorkDir: "/data/mci",
+		}
+
+		Convey("the source should be cloned where git.get_project would", func() {
+			dir, err := commandDirectory(conf, "git.get_project")
+			So(err, ShouldBeNil)
+			So(dir, ShouldEqual, "/data/mci/src")
+
+			script, err := taskLoadScript(conf, nil, nil)
+			So(err, ShouldBeNil)
+			So(script, ShouldContainSubstring,
+				"git clone 'git@github.com:evergreen-ci/sample.git' 'src'")
+			So(script, ShouldContainSubstring, "rm -rf 'src'")
+			So(script, ShouldContainSubstring, "git checkout 'abc123'")
+			So(script, ShouldContainSubstring, "git clone "+
+				"'git@github.com:evergreen-ci/enterprise.git' 'src/modules/enterprise'")
+			So(script, ShouldContainSubstring, "git checkout $(git rev-list -n 1"+
+				" --before='2016-01-02T03:04:05Z' 'origin/master')")
+			So(script, ShouldNotContainSubstring, "unused")
+			So(script, ShouldContainSubstring, "secret: ${not_an_expansion}")
+		})
+
+		Convey("a source directory outside the working directory should be refused", func() {
+			for _, dir := range []string{"/tmp/src", "${workdir}/../src", "${workdir}"} {
+				project.Functions["fetch source"].MultiCommand[0].Params["directory"] = dir
+				_, err := taskLoadScript(conf, nil, nil)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("a task without git.get_project should get the default"+
+			" source directory", func() {
+			project.Tasks[0].Commands = []model.PluginCommandConf{{Command: "shell.exec"}}
+			dir, err := commandDirectory(conf, "git.get_project")
+			So(err, ShouldBeNil)
+			So(dir, ShouldEqual, "")
+
+			script, err := taskLoadScript(conf, nil, nil)
+			So(err, ShouldBeNil)
+			So(script, ShouldContainSubstring, "git clone "+
+				"'git@github.com:evergreen-ci/sample.git' '"+DefaultTaskSourceDir+"'")
+		})
+
+		Convey("patches should be applied only to used modules", func() {
+			p := &patch.Patch{Patches: []patch.ModulePatch{
+				{Githash: "abc123", PatchSet: patch.PatchSet{Patch: "main diff"}},
+				{ModuleName: "enterprise", Githash: "def456",
+					PatchSet: patch.PatchSet{Patch: "module diff"}},
+				{ModuleName: "unused", Githash: "789abc",
+					PatchSet: patch.PatchSet{Patch: "unused diff"}},
+			}}
+			script, err := taskLoadScript(conf, p, nil)
+			So(err, ShouldBeNil)
+			So(script, ShouldContainSubstring, "main diff")
+			So(script, ShouldContainSubstring, "(cd '/data/mci/src' && git checkout"+
+				" 'abc123' && git apply --whitespace=fix '/data/mci/patch_0.diff')")
+			So(script, ShouldContainSubstring, "(cd '/data/mci/src/src/modules/enterprise'"+
+				" && git checkout 'def456'")
+			So(script, ShouldContainSubstring, "'src/modules/enterprise' && git checkout 'def456')")
+			So(script, ShouldNotContainSubstring, "unused diff")
+		})
+
+		Convey("artifacts should be downloaded by the name in their link", func() {
+			script, err := taskLoadScript(conf, nil, []artifact.File{
+				{Name: "Core Dump", Link: "https://s3.amazonaws.com/bucket/core.1234"},
+				{Name: "it's a file", Link: "https://example.com/"},
+			})
+			So(err, ShouldBeNil)
+			So(script, ShouldContainSubstring, "-o 'artifacts/core.1234'"+
+				" 'https://s3.amazonaws.com/bucket/core.1234'")
+			So(script, ShouldContainSubstring, `-o 'artifacts/it'\''s a file'`)
+		})
+	})
+}
+
+func TestEncode(t *testing.T) {
+	Convey("Encoded scripts should have no expansions and short lines", t, func() {
+		encoded := encode(strings.Repeat("echo ${foo}\n", 20))
+		So(encoded, ShouldNotContainSubstring, "${")
+		for _, line := range strings.Split(encoded, "\n") {
+			So(len(line), ShouldBeLessThanOrEqualTo, 76)
+		}
+	})
+}
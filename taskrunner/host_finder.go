@@ -1,6 +1,7 @@
 package taskrunner
 
 import (
+	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
 )
 
@@ -15,12 +16,32 @@ type DBHostFinder struct{}
 
 // FindAvailableHosts finds all hosts available to have a task run on them.
 // It fetches hosts from the database whose status is "running" and who have
-// no task currently being run on them.
+// no task currently being run on them. Hosts that are draining, either by
+// themselves or as part of their distro, are left out.
 func (self *DBHostFinder) FindAvailableHosts() ([]host.Host, error) {
 	// find and return any hosts not currently running a task
 	availableHosts, err := host.Find(host.IsAvailableAndFree)
 	if err != nil {
 		return nil, err
 	}
-	return availableHosts, nil
+
+	drainingDistros, err := distro.Find(distro.ByDraining())
+	if err != nil {
+		return nil, err
+	}
+	if len(drainingDistros) == 0 {
+		return availableHosts, nil
+	}
+	draining := make(map[string]bool)
+	for _, d := range drainingDistros {
+		draining[d.Id] = true
+	}
+
+	undrainedHosts := make([]host.Host, 0, len(availableHosts))
+	for _, h := range availableHosts {
+		if !draining[h.Distro.Id] {
+			undrainedHosts = append(undrainedHosts, h)
+		}
+	}
+	return undrainedHosts, nil
 }
@@ -3,6 +3,7 @@ package taskrunner
 import (
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/evergreen-ci/evergreen/testutil"
 	. "github.com/smartystreets/goconvey/convey"
@@ -44,7 +45,7 @@ func TestDBHostFinder(t *testing.T) {
 				Status: evergreen.HostRunning},
 		}
 
-		So(db.Clear(host.Collection), ShouldBeNil)
+		So(db.ClearCollections(host.Collection, distro.Collection), ShouldBeNil)
 
 		Convey("hosts started by users other than the MCI user should not"+
 			" be returned", func() {
@@ -91,6 +92,37 @@ func TestDBHostFinder(t *testing.T) {
 			So(availableHosts[1].Id, ShouldEqual, hosts[1].Id)
 		})
 
+		Convey("hosts that are draining should not be returned", func() {
+			hosts[2].Draining = true
+			for _, host := range hosts {
+				testutil.HandleTestingErr(host.Insert(), t, "Error inserting host"+
+					" into database")
+			}
+
+			availableHosts, err := hostFinder.FindAvailableHosts()
+			testutil.HandleTestingErr(err, t, "Error finding available hosts")
+			So(len(availableHosts), ShouldEqual, 2)
+			So(availableHosts[0].Id, ShouldEqual, hosts[0].Id)
+			So(availableHosts[1].Id, ShouldEqual, hosts[1].Id)
+		})
+
+		Convey("hosts of draining distros should not be returned", func() {
+			d := &distro.Distro{Id: "d1", Draining: true}
+			testutil.HandleTestingErr(d.Insert(), t, "Error inserting distro"+
+				" into database")
+			hosts[2].Distro = *d
+			for _, host := range hosts {
+				testutil.HandleTestingErr(host.Insert(), t, "Error inserting host"+
+					" into database")
+			}
+
+			availableHosts, err := hostFinder.FindAvailableHosts()
+			testutil.HandleTestingErr(err, t, "Error finding available hosts")
+			So(len(availableHosts), ShouldEqual, 2)
+			So(availableHosts[0].Id, ShouldEqual, hosts[0].Id)
+			So(availableHosts[1].Id, ShouldEqual, hosts[1].Id)
+		})
+
 	})
 
 }
@@ -33,6 +33,10 @@ type HostGateway interface {
 	// run the specified task on the specified host, return the revision of the
 	// agent running the task on that host
 	RunTaskOnHost(*evergreen.Settings, model.Task, host.Host) (string, error)
+	// start a long-lived agent, which requests its own tasks, on the
+	// specified host of a pull-dispatch distro; return the revision of the
+	// agent started
+	StartAgentOnHost(*evergreen.Settings, host.Host) (string, error)
 	// gets the current revision of the agent
 	GetAgentRevision() (string, error)
 }
@@ -106,7 +110,8 @@ func (self *AgentBasedHostGateway) RunTaskOnHost(settings *evergreen.Settings,
 	evergreen.Logger.Logf(slogger.INFO, "Starting agent on host %v for task %v...",
 		hostObj.Id, taskToRun.Id)
 
-	err = self.startAgentOnRemote(settings, &taskToRun, &hostObj, sshOptions)
+	agentArgs := fmt.Sprintf(`-task_id "%v" -task_secret "%v"`, taskToRun.Id, taskToRun.Secret)
+	err = self.startAgentOnRemote(settings, &hostObj, sshOptions, agentArgs)
 	if err != nil {
 		return "", fmt.Errorf("error starting agent on %v for task %v: %v", hostObj.Id, taskToRun.Id, err)
 	}
@@ -115,6 +120,35 @@ func (self *AgentBasedHostGateway) RunTaskOnHost(settings *evergreen.Settings,
 	return agentRevision, nil
 }
 
+// StartAgentOnHost copies the current agent to a host of a pull-dispatch
+// distro, and starts it there to request tasks on the host's behalf. This is
+// how a host whose agent was turned away by the API server gets a new one.
+func (self *AgentBasedHostGateway) StartAgentOnHost(settings *evergreen.Settings,
+	hostObj host.Host) (string, error) {
+
+	cloudHost, err := providers.GetCloudHost(&hostObj, settings)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get cloud host for %v: %v", hostObj.Id, err)
+	}
+	sshOptions, err := cloudHost.GetSSHOptions()
+	if err != nil {
+		return "", fmt.Errorf("Error getting ssh options for host %v: %v", hostObj.Id, err)
+	}
+
+	evergreen.Logger.Logf(slogger.INFO, "Prepping remote host %v...", hostObj.Id)
+	agentRevision, err := self.prepRemoteHost(settings, hostObj, sshOptions,
+		evergreen.FindEvergreenHome())
+	if err != nil {
+		return "", fmt.Errorf("error prepping remote host %v: %v", hostObj.Id, err)
+	}
+
+	agentArgs := fmt.Sprintf(`-host_id "%v" -host_secret "%v"`, hostObj.Id, hostObj.Secret)
+	if err = self.startAgentOnRemote(settings, &hostObj, sshOptions, agentArgs); err != nil {
+		return "", fmt.Errorf("error starting agent on %v: %v", hostObj.Id, err)
+	}
+	return agentRevision, nil
+}
+
 // Gets the git revision of the currently built agent
 func (self *AgentBasedHostGateway) GetAgentRevision() (string, error) {
 
@@ -305,22 +339,24 @@ func (self *AgentBasedHostGateway) prepRemoteHost(settings *evergreen.Settings,
 	return preSCPAgentRevision, nil
 }
 
-// Start the agent process on the specified remote host, and have it run
-// the specified task.
+// Start the agent process on the specified remote host, passing it the
+// given arguments to tell it what to run.
 // Returns an error if starting the agent remotely fails.
-func (self *AgentBasedHostGateway) startAgentOnRemote(
-	settings *evergreen.Settings, task *model.Task,
-	hostObj *host.Host, sshOptions []string) error {
+func (self *AgentBasedHostGateway) startAgentOnRemote(settings *evergreen.Settings,
+	hostObj *host.Host, sshOptions []string, agentArgs string) error {
 
 	// the path to the agent binary on the remote machine
 	pathToExecutable := filepath.Join(hostObj.Distro.WorkDir, "main")
 
 	// build the command to run on the remote machine
 	remoteCmd := fmt.Sprintf(
-		`%v -api_server "%v" -task_id "%v" -task_secret "%v" -log_file "%v" -https_cert "%v"`,
-		pathToExecutable, settings.ApiUrl, task.Id, task.Secret, filepath.Join(hostObj.Distro.WorkDir,
+		`%v -api_server "%v" %v -log_file "%v" -https_cert "%v"`,
+		pathToExecutable, settings.ApiUrl, agentArgs, filepath.Join(hostObj.Distro.WorkDir,
 			agentFile), settings.Expansions["api_httpscert_path"],
 	)
+	if hostObj.Distro.PluginDir != "" {
+		remoteCmd += fmt.Sprintf(` -plugin_dir "%v"`, hostObj.Distro.PluginDir)
+	}
 	evergreen.Logger.Logf(slogger.INFO, "%v", remoteCmd)
 
 	// compute any info necessary to ssh into the host
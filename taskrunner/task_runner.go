@@ -56,6 +56,13 @@ func (self *TaskRunner) Run() error {
 	evergreen.Logger.Logf(slogger.INFO, "Found %v host(s) available to take a task",
 		len(availableHosts))
 
+	// hosts of pull-dispatch distros request their own tasks, but need a
+	// new agent started if theirs was turned away by the API server
+	availableHosts = filterPushDispatchHosts(availableHosts)
+	if err = self.replacePullAgents(); err != nil {
+		return err
+	}
+
 	// split the hosts by distro
 	hostsByDistro := self.splitHostsByDistro(availableHosts)
 
@@ -142,6 +149,37 @@ func (self *TaskRunner) Run() error {
 	return nil
 }
 
+// replacePullAgents starts new agents on the hosts of pull-dispatch distros
+// whose agents were turned away by the API server for speaking a protocol
+// it doesn't support.
+func (self *TaskRunner) replacePullAgents() error {
+	hosts, err := host.Find(host.NeedsNewAgent)
+	if err != nil {
+		return fmt.Errorf("error finding hosts that need new agents: %v", err)
+	}
+
+	waitGroup := &sync.WaitGroup{}
+	for _, h := range hosts {
+		waitGroup.Add(1)
+		go func(h host.Host) {
+			defer waitGroup.Done()
+			evergreen.Logger.Logf(slogger.INFO, "Starting new agent on host %v", h.Id)
+			agentRevision, err := self.StartAgentOnHost(self.Settings, h)
+			if err != nil {
+				evergreen.Logger.Logf(slogger.ERROR, "error starting new agent on host %v: %v",
+					h.Id, err)
+				return
+			}
+			if err = h.SetAgentStarted(agentRevision); err != nil {
+				evergreen.Logger.Logf(slogger.ERROR, "error recording new agent on host %v: %v",
+					h.Id, err)
+			}
+		}(h)
+	}
+	waitGroup.Wait()
+	return nil
+}
+
 // DispatchTaskForHost assigns the task at the head of the task queue to the
 // given host, dequeues the task and then marks it as dispatched for the host
 func DispatchTaskForHost(taskQueue *model.TaskQueue, assignedHost *host.Host) (
@@ -201,6 +239,19 @@ func shouldSkipTask(task *model.Task) bool {
 	return task.Status != evergreen.TaskUndispatched || !task.Activated
 }
 
+// filterPushDispatchHosts returns the hosts whose agents must be started by
+// the taskrunner, leaving out hosts of distros that use pull dispatch.
+func filterPushDispatchHosts(hosts []host.Host) []host.Host {
+	pushHosts := make([]host.Host, 0, len(hosts))
+	for _, h := range hosts {
+		if h.Distro.UsesPullDispatch() {
+			continue
+		}
+		pushHosts = append(pushHosts, h)
+	}
+	return pushHosts
+}
+
 // Takes in a list of hosts, and returns the hosts sorted by distro, in the
 // form of a map distro name -> list of hosts
 func (self *TaskRunner) splitHostsByDistro(hostsToSplit []host.Host) map[string][]host.Host {
@@ -5,6 +5,7 @@ import (
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/db"
 	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
@@ -50,6 +51,11 @@ func (self *MockHostGateway) RunTaskOnHost(settings *evergreen.Settings,
 	return "", fmt.Errorf("RunTaskOnHost not implemented")
 }
 
+func (self *MockHostGateway) StartAgentOnHost(settings *evergreen.Settings,
+	targetHost host.Host) (string, error) {
+	return "", fmt.Errorf("StartAgentOnHost not implemented")
+}
+
 func (self *MockHostGateway) AgentNeedsBuild() (bool, error) {
 	return false, fmt.Errorf("AgentNeedsBuild not implemented")
 }
@@ -89,3 +95,24 @@ func TestSplitHostsByDistro(t *testing.T) {
 
 	})
 }
+
+func TestFilterPushDispatchHosts(t *testing.T) {
+
+	Convey("Filtering hosts by dispatch mode should leave out hosts whose "+
+		"distro uses pull dispatch", t, func() {
+
+		hosts := []host.Host{
+			host.Host{Id: "h1", Distro: distro.Distro{Id: "d1"}},
+			host.Host{Id: "h2", Distro: distro.Distro{Id: "d2",
+				DispatchMode: distro.DispatchModePull}},
+			host.Host{Id: "h3", Distro: distro.Distro{Id: "d3",
+				DispatchMode: distro.DispatchModePush}},
+		}
+
+		pushHosts := filterPushDispatchHosts(hosts)
+		So(len(pushHosts), ShouldEqual, 2)
+		So(pushHosts[0].Id, ShouldEqual, "h1")
+		So(pushHosts[1].Id, ShouldEqual, "h3")
+
+	})
+}
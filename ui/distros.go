@@ -3,14 +3,19 @@ package ui
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/10gen-labs/slogger/v1"
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/event"
 	"github.com/evergreen-ci/evergreen/model/user"
 	"github.com/evergreen-ci/evergreen/validator"
 	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
 	"io/ioutil"
 	"net/http"
 	"sort"
+	"strconv"
 )
 
 func (uis *UIServer) distrosPage(w http.ResponseWriter, r *http.Request) {
@@ -26,13 +31,26 @@ func (uis *UIServer) distrosPage(w http.ResponseWriter, r *http.Request) {
 
 	sort.Sort(&sortableDistro{distros})
 
+	fallbackCounts, err := model.FindAllSpotFallbackCounts()
+	if err != nil {
+		message := fmt.Sprintf("error fetching spot fallback counts: %v", err)
+		PushFlash(uis.CookieStore, r, w, NewErrorFlash(message))
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+	spotFallbacks := map[string]model.SpotFallbackCount{}
+	for _, c := range fallbackCounts {
+		spotFallbacks[c.DistroId] = c
+	}
+
 	uis.WriteHTML(w, http.StatusOK, struct {
-		Distros     []distro.Distro
-		Keys        map[string]string
-		User        *user.DBUser
-		ProjectData projectContext
-		Flashes     []interface{}
-	}{distros, uis.Settings.Keys, GetUser(r), projCtx, PopFlashes(uis.CookieStore, r, w)},
+		Distros       []distro.Distro
+		Keys          map[string]string
+		SpotFallbacks map[string]model.SpotFallbackCount
+		User          *user.DBUser
+		ProjectData   projectContext
+		Flashes       []interface{}
+	}{distros, uis.Settings.Keys, spotFallbacks, GetUser(r), projCtx, PopFlashes(uis.CookieStore, r, w)},
 		"base", "distros.html", "base_angular.html", "menu.html")
 }
 
@@ -58,6 +76,15 @@ func (uis *UIServer) modifyDistro(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// distros created before revisions were kept need their current state
+	// recorded before it is changed, so the change can be rolled back
+	if err = saveBaselineRevision(oldDistro); err != nil {
+		message := fmt.Sprintf("error saving revision of distro '%v': %v", id, err)
+		PushFlash(uis.CookieStore, r, w, NewErrorFlash(message))
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+
 	newDistro := *oldDistro
 
 	// attempt to unmarshal data into distros field for type validation
@@ -87,6 +114,12 @@ func (uis *UIServer) modifyDistro(w http.ResponseWriter, r *http.Request) {
 
 	event.LogDistroModified(id, u.Username(), newDistro)
 
+	if _, err = distro.SaveRevision(newDistro, u.Username(), 0); err != nil {
+		message := fmt.Sprintf("error saving revision of distro '%v': %v", id, err)
+		PushFlash(uis.CookieStore, r, w, NewErrorFlash(message))
+		evergreen.Logger.Logf(slogger.ERROR, message)
+	}
+
 	PushFlash(uis.CookieStore, r, w, NewSuccessFlash(fmt.Sprintf("Distro %v successfully updated.", id)))
 	uis.WriteJSON(w, http.StatusOK, "distro successfully updated")
 }
@@ -131,6 +164,132 @@ func (uis *UIServer) getDistro(w http.ResponseWriter, r *http.Request) {
 	uis.WriteJSON(w, http.StatusOK, d)
 }
 
+// distroRevisions returns every saved revision of a distro, newest first,
+// along with the fields each one changed.
+func (uis *UIServer) distroRevisions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["distro_id"]
+
+	revs, err := distro.FindRevisions(id)
+	if err != nil {
+		message := fmt.Sprintf("error fetching revisions of distro '%v': %v", id, err)
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+
+	type revisionWithChanges struct {
+		distro.Revision
+		Changes []distro.FieldChange `json:"changes"`
+	}
+	out := make([]revisionWithChanges, 0, len(revs))
+	for i, rev := range revs {
+		// the first revision is diffed against an empty distro
+		previous := distro.Distro{}
+		if i+1 < len(revs) {
+			previous = revs[i+1].Distro
+		}
+		changes, err := distro.Diff(previous, rev.Distro)
+		if err != nil {
+			message := fmt.Sprintf("error diffing revision %v of distro '%v': %v", rev.Number, id, err)
+			http.Error(w, message, http.StatusInternalServerError)
+			return
+		}
+		out = append(out, revisionWithChanges{rev, changes})
+	}
+
+	uis.WriteJSON(w, http.StatusOK, out)
+}
+
+// restoreDistroRevision rolls a distro back to one of its saved revisions.
+// The restored distro is validated like any other change, and is saved as
+// a new revision.
+func (uis *UIServer) restoreDistroRevision(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["distro_id"]
+
+	u := MustHaveUser(r)
+
+	number, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid revision: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rev, err := distro.FindRevision(id, number)
+	if err != nil {
+		message := fmt.Sprintf("error finding revision %v of distro '%v': %v", number, id, err)
+		PushFlash(uis.CookieStore, r, w, NewErrorFlash(message))
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+	if rev == nil {
+		message := fmt.Sprintf("distro '%v' has no revision %v", id, number)
+		http.Error(w, message, http.StatusNotFound)
+		return
+	}
+
+	current, err := distro.FindOne(distro.ById(id))
+	if err != nil && err != mgo.ErrNotFound {
+		message := fmt.Sprintf("error finding distro: %v", err)
+		PushFlash(uis.CookieStore, r, w, NewErrorFlash(message))
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+	exists := err == nil
+
+	// only the distro's configuration is rolled back, not whether it is
+	// draining; check that the restored distro is still valid
+	restored := rev.Distro
+	if exists {
+		restored.KeepOperationalState(current)
+	} else {
+		restored.Draining = false
+	}
+	vErrs := validator.CheckDistro(&restored, &uis.Settings, !exists)
+	if len(vErrs) != 0 {
+		for _, e := range vErrs {
+			PushFlash(uis.CookieStore, r, w, NewErrorFlash(e.Error()))
+		}
+		uis.WriteJSON(w, http.StatusBadRequest, vErrs)
+		return
+	}
+
+	if exists {
+		err = restored.Update()
+	} else {
+		err = restored.Insert()
+	}
+	if err != nil {
+		message := fmt.Sprintf("error restoring distro '%v': %v", id, err)
+		PushFlash(uis.CookieStore, r, w, NewErrorFlash(message))
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+
+	event.LogDistroModified(id, u.Username(), restored)
+
+	if _, err = distro.SaveRevision(restored, u.Username(), number); err != nil {
+		message := fmt.Sprintf("error saving revision of distro '%v': %v", id, err)
+		PushFlash(uis.CookieStore, r, w, NewErrorFlash(message))
+		evergreen.Logger.Logf(slogger.ERROR, message)
+	}
+
+	PushFlash(uis.CookieStore, r, w, NewSuccessFlash(fmt.Sprintf("Distro %v restored to revision %v.", id, number)))
+	uis.WriteJSON(w, http.StatusOK, "distro successfully restored")
+}
+
+// saveBaselineRevision records the distro's current state as its first
+// revision if it has none yet.
+func saveBaselineRevision(d *distro.Distro) error {
+	latest, err := distro.FindLatestRevision(d.Id)
+	if err != nil {
+		return err
+	}
+	if latest != nil {
+		return nil
+	}
+	_, err = distro.SaveRevision(*d, "", 0)
+	return err
+}
+
 func (uis *UIServer) addDistro(w http.ResponseWriter, r *http.Request) {
 	u := MustHaveUser(r)
 
@@ -170,6 +329,12 @@ func (uis *UIServer) addDistro(w http.ResponseWriter, r *http.Request) {
 
 	event.LogDistroAdded(d.Id, u.Username(), d)
 
+	if _, err = distro.SaveRevision(d, u.Username(), 0); err != nil {
+		message := fmt.Sprintf("error saving revision of distro '%v': %v", d.Id, err)
+		PushFlash(uis.CookieStore, r, w, NewErrorFlash(message))
+		evergreen.Logger.Logf(slogger.ERROR, message)
+	}
+
 	PushFlash(uis.CookieStore, r, w, NewSuccessFlash(fmt.Sprintf("Distro %v successfully added.", d.Id)))
 	uis.WriteJSON(w, http.StatusOK, "distro successfully added")
 }
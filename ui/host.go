@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -23,6 +24,9 @@ var (
 
 const (
 	IncludeSpawnedHosts = "includeSpawnedHosts"
+
+	// how many days of terminations are reported by default
+	DefaultTerminationReportDays = 7
 )
 
 type uiParams struct {
@@ -33,6 +37,9 @@ type uiParams struct {
 
 	// for the update status option
 	Status string `json:"status"`
+
+	// for the set draining option
+	Draining bool `json:"draining"`
 }
 
 func (uis *UIServer) hostPage(w http.ResponseWriter, r *http.Request) {
@@ -79,19 +86,50 @@ func (uis *UIServer) hostsPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	since := time.Now().Add(-DefaultTerminationReportDays * 24 * time.Hour)
+	terminations, err := host.CountTerminations(since)
+	if err != nil {
+		uis.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
 	flashes := PopFlashes(uis.CookieStore, r, w)
 	uis.WriteHTML(w, http.StatusOK, struct {
 		Flashes             []interface{}
 		Hosts               *hostsData
+		Terminations        []host.TerminationCount
+		TerminationDays     int
 		IncludeSpawnedHosts bool
 		User                *user.DBUser
 		ProjectData         projectContext
-	}{flashes, hosts, includeSpawnedHosts, GetUser(r), projCtx},
+	}{flashes, hosts, terminations, DefaultTerminationReportDays, includeSpawnedHosts, GetUser(r), projCtx},
 		"base", "hosts.html", "base_angular.html", "menu.html")
 }
 
+// hostTerminations reports how many hosts of each distro were terminated
+// for each reason over the last "days" days.
+func (uis *UIServer) hostTerminations(w http.ResponseWriter, r *http.Request) {
+	days := DefaultTerminationReportDays
+	if daysStr := r.FormValue("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days <= 0 {
+			http.Error(w, fmt.Sprintf("invalid number of days '%v'", daysStr), http.StatusBadRequest)
+			return
+		}
+	}
+
+	since := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+	terminations, err := host.CountTerminations(since)
+	if err != nil {
+		uis.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	uis.WriteJSON(w, http.StatusOK, terminations)
+}
+
 func (uis *UIServer) modifyHost(w http.ResponseWriter, r *http.Request) {
-	_ = MustHaveUser(r)
+	u := MustHaveUser(r)
 
 	vars := mux.Vars(r)
 	id := vars["host_id"]
@@ -131,13 +169,21 @@ func (uis *UIServer) modifyHost(w http.ResponseWriter, r *http.Request) {
 		msg := NewSuccessFlash(fmt.Sprintf("Host status successfully updated from '%v' to '%v'", currentStatus, host.Status))
 		PushFlash(uis.CookieStore, r, w, msg)
 		uis.WriteJSON(w, http.StatusOK, "Successfully updated host status")
+	case "setDraining":
+		if err := host.SetDraining(opts.Draining, u.Username()); err != nil {
+			uis.LoggedError(w, r, http.StatusInternalServerError, fmt.Errorf("Error updating host: %v", err))
+			return
+		}
+		msg := NewSuccessFlash(fmt.Sprintf("Host %v %v", host.Id, drainingMessage(opts.Draining)))
+		PushFlash(uis.CookieStore, r, w, msg)
+		uis.WriteJSON(w, http.StatusOK, "Successfully updated host drain mode")
 	default:
 		uis.WriteJSON(w, http.StatusBadRequest, fmt.Sprintf("Unrecognized action: %v", opts.Action))
 	}
 }
 
 func (uis *UIServer) modifyHosts(w http.ResponseWriter, r *http.Request) {
-	_ = MustHaveUser(r)
+	u := MustHaveUser(r)
 
 	opts := &uiParams{}
 	err := util.ReadJSONInto(r.Body, opts)
@@ -186,8 +232,30 @@ func (uis *UIServer) modifyHosts(w http.ResponseWriter, r *http.Request) {
 			numHostsUpdated, newStatus))
 		PushFlash(uis.CookieStore, r, w, msg)
 		return
+	case "setDraining":
+		numHostsUpdated := 0
+
+		for _, host := range hosts {
+			if err := host.SetDraining(opts.Draining, u.Username()); err != nil {
+				uis.LoggedError(w, r, http.StatusInternalServerError, fmt.Errorf("Error updating host %v", err))
+				return
+			}
+			numHostsUpdated += 1
+		}
+		msg := NewSuccessFlash(fmt.Sprintf("%v host(s) %v", numHostsUpdated,
+			drainingMessage(opts.Draining)))
+		PushFlash(uis.CookieStore, r, w, msg)
+		return
 	default:
 		http.Error(w, fmt.Sprintf("Unrecognized action: %v", opts.Action), http.StatusBadRequest)
 		return
 	}
 }
+
+// drainingMessage describes a change to hosts' drain mode.
+func drainingMessage(draining bool) string {
+	if draining {
+		return "put into drain mode; no new tasks will be assigned"
+	}
+	return "taken out of drain mode"
+}
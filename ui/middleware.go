@@ -135,13 +135,9 @@ func (uis *UIServer) requireSuperUser(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		if user := GetUser(r); user != nil {
-			for _, id := range uis.Settings.SuperUsers {
-				if id == user.Id {
-					next(w, r)
-					return
-				}
-			}
+		if user := GetUser(r); user != nil && auth.IsSuperUser(uis.Settings.SuperUsers, user.Id) {
+			next(w, r)
+			return
 		}
 		uis.RedirectToLogin(w, r)
 		return
@@ -65,6 +65,11 @@ func (uis *UIServer) projectPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the values of private variables never leave the server
+	if projVars != nil {
+		projVars = projVars.RedactPrivateVars()
+	}
+
 	data := struct {
 		ProjectRef  *model.ProjectRef
 		ProjectVars *model.ProjectVars
@@ -101,6 +106,7 @@ func (uis *UIServer) modifyProject(w http.ResponseWriter, r *http.Request) {
 		DeactivatePrevious bool              `json:"deactivate_previous"`
 		Branch             string            `json:"branch_name"`
 		ProjVarsMap        map[string]string `json:"project_vars"`
+		PrivateVars        map[string]bool   `json:"private_vars"`
 		Enabled            bool              `json:"enabled"`
 		Owner              string            `json:"owner_name"`
 		Repo               string            `json:"repo_name"`
@@ -127,6 +133,26 @@ func (uis *UIServer) modifyProject(w http.ResponseWriter, r *http.Request) {
 	projectRef.Repo = responseRef.Repo
 	projectRef.Identifier = id
 
+	// private values are sent to the UI blanked out, so keep the stored
+	// value of any private variable that comes back without one
+	projectVars := model.ProjectVars{id, responseRef.ProjVarsMap, responseRef.PrivateVars}
+	existingVars, err := model.FindOneProjectVars(id)
+	if err != nil {
+		uis.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if existingVars != nil {
+		for name, value := range projectVars.Vars {
+			if value == "" && projectVars.PrivateVars[name] {
+				projectVars.Vars[name] = existingVars.Vars[name]
+			}
+		}
+	}
+	if err = projectVars.ValidatePrivateVars(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	projectRef.Alerts = map[string][]model.AlertConfig{}
 	for triggerId, alerts := range responseRef.AlertConfig {
 		//TODO validate the triggerID, provider, and settings.
@@ -146,7 +172,6 @@ func (uis *UIServer) modifyProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//modify project vars if necessary
-	projectVars := model.ProjectVars{id, responseRef.ProjVarsMap}
 	_, err = projectVars.Upsert()
 
 	if err != nil {
@@ -29,11 +29,28 @@ func (uis *UIServer) spawnPage(w http.ResponseWriter, r *http.Request) {
 	flashes := PopFlashes(uis.CookieStore, r, w)
 	projCtx := MustHaveProjectContext(r)
 
+	// the task to spawn a host for debugging, if any
+	var spawnTask *model.Task
+	if taskId := r.FormValue("task_id"); taskId != "" {
+		var err error
+		spawnTask, err = model.FindTask(taskId)
+		if err != nil {
+			uis.LoggedError(w, r, http.StatusInternalServerError,
+				fmt.Errorf("Error finding task %v: %v", taskId, err))
+			return
+		}
+		if spawnTask == nil {
+			http.Error(w, fmt.Sprintf("task %v not found", taskId), http.StatusNotFound)
+			return
+		}
+	}
+
 	uis.WriteHTML(w, http.StatusOK, struct {
 		ProjectData projectContext
 		User        *user.DBUser
 		Flashes     []interface{}
-	}{projCtx, GetUser(r), flashes}, "base", "spawned_hosts.html", "base_angular.html", "menu.html")
+		SpawnTask   *model.Task
+	}{projCtx, GetUser(r), flashes, spawnTask}, "base", "spawned_hosts.html", "base_angular.html", "menu.html")
 }
 
 func (uis *UIServer) getSpawnedHosts(w http.ResponseWriter, r *http.Request) {
@@ -84,6 +101,7 @@ func (uis *UIServer) requestNewHost(w http.ResponseWriter, r *http.Request) {
 		PublicKey string `json:"public_key"`
 		SaveKey   bool   `json:"save_key"`
 		UserData  string `json:"userdata"`
+		TaskId    string `json:"task_id"`
 	}{}
 
 	if err := util.ReadJSONInto(r.Body, &putParams); err != nil {
@@ -96,6 +114,7 @@ func (uis *UIServer) requestNewHost(w http.ResponseWriter, r *http.Request) {
 		UserName:  authedUser.Username(),
 		PublicKey: putParams.PublicKey,
 		UserData:  putParams.UserData,
+		TaskId:    putParams.TaskId,
 	}
 
 	spawner := spawn.New(&uis.Settings)
@@ -180,6 +199,7 @@ func (uis *UIServer) modifySpawnHost(w http.ResponseWriter, r *http.Request) {
 			uis.WriteJSON(w, http.StatusBadRequest, fmt.Sprintf("Host %v is already terminated", host.Id))
 			return
 		}
+		host.TerminationReason = evergreen.TerminationReasonUser
 		cloudHost, err := providers.GetCloudHost(host, &uis.Settings)
 		if err != nil {
 			uis.LoggedError(w, r, http.StatusInternalServerError, err)
@@ -34,28 +34,29 @@ const (
 var NumTestsToSearchForTestNames = 100
 
 type uiTaskData struct {
-	Id               string                  `json:"id"`
-	DisplayName      string                  `json:"display_name"`
-	Revision         string                  `json:"gitspec"`
-	BuildVariant     string                  `json:"build_variant"`
-	Distro           string                  `json:"distro"`
-	BuildId          string                  `json:"build_id"`
-	Status           string                  `json:"status"`
-	Activated        bool                    `json:"activated"`
-	Restarts         int                     `json:"restarts"`
-	Execution        int                     `json:"execution"`
-	StartTime        int64                   `json:"start_time"`
-	DispatchTime     int64                   `json:"dispatch_time"`
-	FinishTime       int64                   `json:"finish_time"`
-	Requester        string                  `json:"r"`
-	ExpectedDuration time.Duration           `json:"expected_duration"`
-	Priority         int                     `json:"priority"`
-	PushTime         time.Time               `json:"push_time"`
-	TimeTaken        time.Duration           `json:"time_taken"`
-	TaskEndDetails   apimodels.TaskEndDetail `json:"task_end_details"`
-	TestResults      []model.TestResult      `json:"test_results"`
-	Aborted          bool                    `json:"abort"`
-	MinQueuePos      int                     `json:"min_queue_pos"`
+	Id               string                    `json:"id"`
+	DisplayName      string                    `json:"display_name"`
+	Revision         string                    `json:"gitspec"`
+	BuildVariant     string                    `json:"build_variant"`
+	Distro           string                    `json:"distro"`
+	BuildId          string                    `json:"build_id"`
+	Status           string                    `json:"status"`
+	Activated        bool                      `json:"activated"`
+	Restarts         int                       `json:"restarts"`
+	Execution        int                       `json:"execution"`
+	StartTime        int64                     `json:"start_time"`
+	DispatchTime     int64                     `json:"dispatch_time"`
+	FinishTime       int64                     `json:"finish_time"`
+	Requester        string                    `json:"r"`
+	ExpectedDuration time.Duration             `json:"expected_duration"`
+	Priority         int                       `json:"priority"`
+	PushTime         time.Time                 `json:"push_time"`
+	TimeTaken        time.Duration             `json:"time_taken"`
+	TaskEndDetails   apimodels.TaskEndDetail   `json:"task_end_details"`
+	TestResults      []model.TestResult        `json:"test_results"`
+	CommandRecords   []apimodels.CommandRecord `json:"command_records"`
+	Aborted          bool                      `json:"abort"`
+	MinQueuePos      int                       `json:"min_queue_pos"`
 
 	// from the host doc (the dns name)
 	HostDNS string `json:"host_dns,omitempty"`
@@ -176,6 +177,7 @@ func (uis *UIServer) taskPage(w http.ResponseWriter, r *http.Request) {
 		TimeTaken:           projCtx.Task.TimeTaken,
 		Priority:            projCtx.Task.Priority,
 		TestResults:         projCtx.Task.TestResults,
+		CommandRecords:      projCtx.Task.CommandRecords,
 		Aborted:             projCtx.Task.Aborted,
 		CurrentTime:         time.Now().UnixNano(),
 		BuildVariantDisplay: projCtx.Build.DisplayName,
@@ -338,6 +340,34 @@ func (uis *UIServer) taskDependencies(w http.ResponseWriter, r *http.Request) {
 	uis.WriteJSON(w, http.StatusOK, uiDeps)
 }
 
+// taskResourceStats returns the resource samples the agent took while
+// running an execution of the task.
+func (uis *UIServer) taskResourceStats(w http.ResponseWriter, r *http.Request) {
+	projCtx := MustHaveProjectContext(r)
+
+	if projCtx.Task == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	execution, err := strconv.Atoi(mux.Vars(r)["execution"])
+	if err != nil {
+		http.Error(w, "Invalid execution number", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := model.FindTaskResourceStats(projCtx.Task.Id, execution)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	samples := []apimodels.ResourceSample{}
+	if stats != nil {
+		samples = stats.Samples
+	}
+	uis.WriteJSON(w, http.StatusOK, samples)
+}
+
 // async handler for polling the task log
 type taskLogsWrapper struct {
 	LogMessages []model.LogMessage
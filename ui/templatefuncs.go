@@ -3,8 +3,8 @@ package ui
 import (
 	"crypto/md5"
 	"fmt"
+	"github.com/evergreen-ci/evergreen/auth"
 	"github.com/evergreen-ci/evergreen/model/user"
-	"github.com/evergreen-ci/evergreen/util"
 	"github.com/gorilla/mux"
 	"html/template"
 	"io"
@@ -45,7 +45,7 @@ func MakeTemplateFuncs(fo FuncOptions, superUsers []string) (template.FuncMap, e
 	r := template.FuncMap{
 		// IsSuperUser returns true if the given user Id has super user privileges.
 		"IsSuperUser": func(userName string) bool {
-			return len(superUsers) == 0 || util.SliceContains(superUsers, userName)
+			return auth.IsSuperUser(superUsers, userName)
 		},
 
 		// Gravatar returns a Gravatar URL for the given email string.
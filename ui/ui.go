@@ -90,6 +90,7 @@ func (uis *UIServer) NewRouter() (*mux.Router, error) {
 	r.HandleFunc("/task_log_raw/{task_id}/{execution}", uis.loadCtx(uis.taskLogRaw))
 	r.HandleFunc("/task/dependencies/{task_id}", uis.loadCtx(uis.taskDependencies))
 	r.HandleFunc("/task/dependencies/{task_id}/{execution}", uis.loadCtx(uis.taskDependencies))
+	r.HandleFunc("/task/resource_stats/{task_id}/{execution}", uis.loadCtx(uis.taskResourceStats))
 
 	// Test Logs
 	r.HandleFunc("/test_log/{task_id}/{task_execution}/{test_name}", uis.loadCtx(uis.testLog))
@@ -109,6 +110,7 @@ func (uis *UIServer) NewRouter() (*mux.Router, error) {
 	// Hosts
 	r.HandleFunc("/hosts", uis.loadCtx(uis.hostsPage)).Methods("GET")
 	r.HandleFunc("/hosts", uis.requireUser(uis.loadCtx(uis.modifyHosts))).Methods("PUT")
+	r.HandleFunc("/hosts/terminations", uis.loadCtx(uis.hostTerminations)).Methods("GET")
 	r.HandleFunc("/host/{host_id}", uis.loadCtx(uis.hostPage)).Methods("GET")
 	r.HandleFunc("/host/{host_id}", uis.requireUser(uis.loadCtx(uis.modifyHost))).Methods("PUT")
 
@@ -119,6 +121,8 @@ func (uis *UIServer) NewRouter() (*mux.Router, error) {
 	r.HandleFunc("/distros/{distro_id}", uis.requireSuperUser(uis.loadCtx(uis.getDistro))).Methods("GET")
 	r.HandleFunc("/distros/{distro_id}", uis.requireSuperUser(uis.loadCtx(uis.modifyDistro))).Methods("POST")
 	r.HandleFunc("/distros/{distro_id}", uis.requireSuperUser(uis.loadCtx(uis.removeDistro))).Methods("DELETE")
+	r.HandleFunc("/distros/{distro_id}/revisions", uis.requireSuperUser(uis.loadCtx(uis.distroRevisions))).Methods("GET")
+	r.HandleFunc("/distros/{distro_id}/revisions/{revision:\\d+}/restore", uis.requireSuperUser(uis.loadCtx(uis.restoreDistroRevision))).Methods("POST")
 
 	// Event Logs
 	r.HandleFunc("/event_log/{resource_type}/{resource_id:[\\w_\\-\\:\\.\\@]+}", uis.loadCtx(uis.fullEventLogs))
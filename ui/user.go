@@ -112,6 +112,12 @@ func (uis *UIServer) userSettingsModify(w http.ResponseWriter, r *http.Request)
 		uis.LoggedError(w, r, http.StatusBadRequest, err)
 		return
 	}
+	if userSettings.SpawnHostIdleHours < 0 || userSettings.SpawnHostIdleHours > user.MaxSpawnHostIdleHours {
+		http.Error(w, fmt.Sprintf("Spawn host idle timeout must be between 1 and %v hours, "+
+			"or 0 for the default of %v hours", user.MaxSpawnHostIdleHours,
+			int(user.DefaultSpawnHostIdleTimeout.Hours())), http.StatusBadRequest)
+		return
+	}
 
 	if err := model.SaveUserSettings(currentUser.Username(), userSettings); err != nil {
 		uis.LoggedError(w, r, http.StatusInternalServerError, fmt.Errorf("Error saving user settings: %v", err))
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/cloud/providers/ec2"
 	"github.com/evergreen-ci/evergreen/cloud/providers/static"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	_ "github.com/evergreen-ci/evergreen/plugin/config"
 	"github.com/evergreen-ci/evergreen/util"
 	"github.com/mitchellh/mapstructure"
+	"path/filepath"
 )
 
 type distroValidator func(*distro.Distro, *evergreen.Settings) []ValidationError
@@ -19,6 +21,13 @@ var distroSyntaxValidators = []distroValidator{
 	ensureHasRequiredFields,
 	ensureValidSSHOptions,
 	ensureValidExpansions,
+	ensureValidDispatchMode,
+	ensureValidProvisionMode,
+	ensureValidMaxLifetime,
+	ensureValidSetupConcurrency,
+	ensureValidLabels,
+	ensureValidDiskLimits,
+	ensureValidPluginDir,
 }
 
 // CheckDistro checks if the distro configuration syntax is valid. Returns
@@ -140,3 +149,104 @@ func ensureValidSSHOptions(d *distro.Distro, s *evergreen.Settings) []Validation
 	}
 	return nil
 }
+
+// ensureValidDispatchMode checks that the distro uses a known task dispatch mode.
+func ensureValidDispatchMode(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	switch d.DispatchMode {
+	case "", distro.DispatchModePush, distro.DispatchModePull:
+		return nil
+	}
+	return []ValidationError{{Error, fmt.Sprintf("distro '%v' must be one of '%v' or '%v'",
+		distro.DispatchModeKey, distro.DispatchModePush, distro.DispatchModePull)}}
+}
+
+// ensureValidProvisionMode checks that the distro uses a known provisioning
+// mode, and that userdata provisioning is only used with providers that
+// support it.
+func ensureValidProvisionMode(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	switch d.ProvisionMode {
+	case "", distro.ProvisionModeSSH:
+		return nil
+	case distro.ProvisionModeUserData:
+		if d.Provider != ec2.OnDemandProviderName && d.Provider != ec2.SpotProviderName {
+			return []ValidationError{{Error, fmt.Sprintf("distro '%v' of '%v' is not supported "+
+				"by provider '%v'", distro.ProvisionModeKey, distro.ProvisionModeUserData, d.Provider)}}
+		}
+		return nil
+	}
+	return []ValidationError{{Error, fmt.Sprintf("distro '%v' must be one of '%v' or '%v'",
+		distro.ProvisionModeKey, distro.ProvisionModeSSH, distro.ProvisionModeUserData)}}
+}
+
+// ensureValidSetupConcurrency checks that the distro's setup concurrency is
+// not negative.
+func ensureValidSetupConcurrency(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	if d.SetupConcurrency < 0 {
+		return []ValidationError{{Error, fmt.Sprintf("distro '%v' cannot be negative",
+			distro.SetupConcurrencyKey)}}
+	}
+	return nil
+}
+
+// ensureValidLabels checks that the distro's labels are of the form
+// "key:value", and that no key is given more than one value.
+func ensureValidLabels(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	errs := []ValidationError{}
+	keys := map[string]bool{}
+	for _, label := range d.Labels {
+		key, _, err := distro.ParseLabel(label)
+		if err != nil {
+			errs = append(errs, ValidationError{Error, err.Error()})
+			continue
+		}
+		if keys[key] {
+			errs = append(errs, ValidationError{Error, fmt.Sprintf("distro '%v' has"+
+				" more than one label for '%v'", d.Id, key)})
+		}
+		keys[key] = true
+	}
+	return errs
+}
+
+// ensureValidDiskLimits checks that the distro's minimum free disk space and
+// per-task disk quota are not negative.
+func ensureValidDiskLimits(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	errs := []ValidationError{}
+	if d.MinFreeDisk < 0 {
+		errs = append(errs, ValidationError{Error, fmt.Sprintf("distro '%v' cannot be negative",
+			distro.MinFreeDiskKey)})
+	}
+	if d.DiskQuota < 0 {
+		errs = append(errs, ValidationError{Error, fmt.Sprintf("distro '%v' cannot be negative",
+			distro.DiskQuotaKey)})
+	}
+	return errs
+}
+
+// ensureValidPluginDir checks that the distro's plugin directory, if it has
+// one, is an absolute path outside its working directory, where tasks could
+// replace the plugins.
+func ensureValidPluginDir(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	if d.PluginDir == "" {
+		return nil
+	}
+	if !filepath.IsAbs(d.PluginDir) {
+		return []ValidationError{{Error, fmt.Sprintf("distro '%v' must be an absolute path",
+			distro.PluginDirKey)}}
+	}
+	if d.InWorkDir(d.PluginDir) {
+		return []ValidationError{{Error, fmt.Sprintf("distro '%v' cannot be inside its '%v'",
+			distro.PluginDirKey, distro.WorkDirKey)}}
+	}
+	return nil
+}
+
+// ensureValidMaxLifetime checks that the distro's max host lifetime is not
+// negative.
+func ensureValidMaxLifetime(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	if d.MaxLifetime < 0 {
+		return []ValidationError{{Error, fmt.Sprintf("distro '%v' cannot be negative",
+			distro.MaxLifetimeKey)}}
+	}
+	return nil
+}
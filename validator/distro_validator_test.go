@@ -233,3 +233,128 @@ func TestEnsureValidSSHOptions(t *testing.T) {
 		})
 	})
 }
+
+func TestEnsureValidDispatchMode(t *testing.T) {
+	Convey("When validating a distro's dispatch mode...", t, func() {
+		Convey("if the mode is unknown, an error should be returned", func() {
+			d := &distro.Distro{DispatchMode: "shove"}
+			err := ensureValidDispatchMode(d, conf)
+			So(err, ShouldNotResemble, []ValidationError{})
+			So(len(err), ShouldEqual, 1)
+		})
+		Convey("if the mode is blank, push or pull, no error should be returned", func() {
+			for _, mode := range []string{"", distro.DispatchModePush, distro.DispatchModePull} {
+				d := &distro.Distro{DispatchMode: mode}
+				So(ensureValidDispatchMode(d, conf), ShouldBeNil)
+			}
+		})
+	})
+}
+
+func TestEnsureValidProvisionMode(t *testing.T) {
+	Convey("When validating a distro's provisioning mode...", t, func() {
+		Convey("if the mode is unknown, an error should be returned", func() {
+			d := &distro.Distro{ProvisionMode: "telepathy"}
+			So(len(ensureValidProvisionMode(d, conf)), ShouldEqual, 1)
+		})
+		Convey("if the mode is blank or ssh, no error should be returned", func() {
+			for _, mode := range []string{"", distro.ProvisionModeSSH} {
+				d := &distro.Distro{ProvisionMode: mode, Provider: "static"}
+				So(ensureValidProvisionMode(d, conf), ShouldBeNil)
+			}
+		})
+		Convey("userdata provisioning should only be allowed for ec2 providers", func() {
+			d := &distro.Distro{ProvisionMode: distro.ProvisionModeUserData, Provider: "static"}
+			So(len(ensureValidProvisionMode(d, conf)), ShouldEqual, 1)
+			d.Provider = ec2.OnDemandProviderName
+			So(ensureValidProvisionMode(d, conf), ShouldBeNil)
+			d.Provider = ec2.SpotProviderName
+			So(ensureValidProvisionMode(d, conf), ShouldBeNil)
+		})
+	})
+}
+
+func TestEnsureValidMaxLifetime(t *testing.T) {
+	Convey("When validating a distro's max host lifetime...", t, func() {
+		Convey("a negative lifetime should return an error", func() {
+			d := &distro.Distro{MaxLifetime: -1}
+			So(len(ensureValidMaxLifetime(d, conf)), ShouldEqual, 1)
+		})
+		Convey("a zero or positive lifetime should not return an error", func() {
+			for _, lifetime := range []int{0, 60} {
+				d := &distro.Distro{MaxLifetime: lifetime}
+				So(ensureValidMaxLifetime(d, conf), ShouldBeNil)
+			}
+		})
+	})
+}
+
+func TestEnsureValidDiskLimits(t *testing.T) {
+	Convey("When validating a distro's disk limits...", t, func() {
+		Convey("negative limits should each return an error", func() {
+			d := &distro.Distro{MinFreeDisk: -1, DiskQuota: -1}
+			So(len(ensureValidDiskLimits(d, conf)), ShouldEqual, 2)
+		})
+		Convey("zero or positive limits should not return an error", func() {
+			d := &distro.Distro{MinFreeDisk: 1024, DiskQuota: 0}
+			So(len(ensureValidDiskLimits(d, conf)), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestEnsureValidPluginDir(t *testing.T) {
+	Convey("When validating a distro's plugin directory...", t, func() {
+		Convey("no plugin directory should not return an error", func() {
+			d := &distro.Distro{WorkDir: "/data/mci"}
+			So(ensureValidPluginDir(d, conf), ShouldBeNil)
+		})
+		Convey("a directory outside the working directory should not return an error", func() {
+			for _, dir := range []string{"/opt/evergreen/plugins", "/data/mci-plugins"} {
+				d := &distro.Distro{WorkDir: "/data/mci", PluginDir: dir}
+				So(ensureValidPluginDir(d, conf), ShouldBeNil)
+			}
+		})
+		Convey("a relative directory should return an error", func() {
+			d := &distro.Distro{WorkDir: "/data/mci", PluginDir: "plugins"}
+			So(len(ensureValidPluginDir(d, conf)), ShouldEqual, 1)
+		})
+		Convey("a directory inside the working directory should return an error", func() {
+			for _, dir := range []string{"/data/mci", "/data/mci/plugins", "/data/mci/../mci/plugins"} {
+				d := &distro.Distro{WorkDir: "/data/mci", PluginDir: dir}
+				So(len(ensureValidPluginDir(d, conf)), ShouldEqual, 1)
+			}
+		})
+	})
+}
+
+func TestEnsureValidSetupConcurrency(t *testing.T) {
+	Convey("When validating a distro's setup concurrency...", t, func() {
+		Convey("a negative concurrency should return an error", func() {
+			d := &distro.Distro{SetupConcurrency: -1}
+			So(len(ensureValidSetupConcurrency(d, conf)), ShouldEqual, 1)
+		})
+		Convey("a zero or positive concurrency should not return an error", func() {
+			for _, concurrency := range []int{0, 10} {
+				d := &distro.Distro{SetupConcurrency: concurrency}
+				So(ensureValidSetupConcurrency(d, conf), ShouldBeNil)
+			}
+		})
+	})
+}
+
+func TestEnsureValidLabels(t *testing.T) {
+	Convey("When validating a distro's labels...", t, func() {
+		Convey("well formed labels with distinct keys should not return an error", func() {
+			d := &distro.Distro{Labels: []string{"os:linux", "arch:amd64"}}
+			So(ensureValidLabels(d, conf), ShouldResemble, []ValidationError{})
+		})
+		Convey("a malformed label should return an error", func() {
+			d := &distro.Distro{Labels: []string{"linux", "arch:amd64"}}
+			So(len(ensureValidLabels(d, conf)), ShouldEqual, 1)
+		})
+		Convey("a key with more than one value should return an error", func() {
+			d := &distro.Distro{Labels: []string{"os:linux", "os:windows"}}
+			So(len(ensureValidLabels(d, conf)), ShouldEqual, 1)
+		})
+	})
+}
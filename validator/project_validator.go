@@ -276,17 +276,18 @@ func ensureHasNecessaryBVFields(project *model.Project) []ValidationError {
 			)
 		}
 		for _, task := range buildVariant.Tasks {
-			if len(task.Distros) == 0 {
+			if len(task.Distros) == 0 && len(task.Labels) == 0 {
 				hasTaskWithoutDistro = true
 				break
 			}
 		}
-		if hasTaskWithoutDistro && len(buildVariant.RunOn) == 0 {
+		if hasTaskWithoutDistro && len(buildVariant.RunOn) == 0 &&
+			len(buildVariant.RunOnLabels) == 0 {
 			errs = append(errs,
 				ValidationError{
 					Message: fmt.Sprintf("buildvariant '%v' in project '%v' "+
-						"must either specify run_on field or have every task "+
-						"specify a distro.\nValid distros include: \n\t- %v",
+						"must either specify run_on or run_on_labels field or have "+
+						"every task specify a distro or labels.\nValid distros include: \n\t- %v",
 						buildVariant.Name, project.Identifier, strings.Join(
 							distroIds, "\n\t- ")),
 				},
@@ -309,6 +310,25 @@ func ensureHasNecessaryProjectFields(project *model.Project) []ValidationError {
 		)
 	}
 
+	blockTimeouts := []struct {
+		field string
+		secs  int
+	}{
+		{"pre_timeout_secs", project.PreTimeoutSecs},
+		{"post_timeout_secs", project.PostTimeoutSecs},
+		{"callback_timeout_secs", project.CallbackTimeoutSecs},
+	}
+	for _, timeout := range blockTimeouts {
+		if timeout.secs < 0 {
+			errs = append(errs,
+				ValidationError{
+					Message: fmt.Sprintf("project '%v' must have a "+
+						"non-negative '%v' set", project.Identifier, timeout.field),
+				},
+			)
+		}
+	}
+
 	if project.CommandType != "" {
 		if project.CommandType != model.SystemCommandType &&
 			project.CommandType != model.TestCommandType {
@@ -329,6 +349,18 @@ func ensureHasNecessaryProjectFields(project *model.Project) []ValidationError {
 // 2. any referenced distro exists within the current setting's distro directory
 func ensureReferentialIntegrity(project *model.Project) []ValidationError {
 	errs := []ValidationError{}
+
+	// the distros are only needed to check the labels asked for
+	var distros []distro.Distro
+	if usesLabels(project) {
+		var err error
+		if distros, err = distro.Find(distro.All); err != nil {
+			return []ValidationError{{
+				Message: fmt.Sprintf("error finding distros: %v", err),
+				Level:   Error,
+			}}
+		}
+	}
 	// create a set of all the task names
 	allTaskNames := map[string]bool{}
 	for _, task := range project.Tasks {
@@ -359,6 +391,9 @@ func ensureReferentialIntegrity(project *model.Project) []ValidationError {
 				}
 			}
 			buildVariantTasks[task.Name] = true
+			errs = append(errs, validateLabels(distros, task.Labels,
+				fmt.Sprintf("task '%v' in buildvariant '%v' in project '%v'",
+					task.Name, buildVariant.Name, project.Identifier))...)
 			for _, distroId := range task.Distros {
 				if !util.SliceContains(distroIds, distroId) {
 					errs = append(errs,
@@ -374,6 +409,9 @@ func ensureReferentialIntegrity(project *model.Project) []ValidationError {
 				}
 			}
 		}
+		errs = append(errs, validateLabels(distros, buildVariant.RunOnLabels,
+			fmt.Sprintf("buildvariant '%v' in project '%v'", buildVariant.Name,
+				project.Identifier))...)
 		for _, distroId := range buildVariant.RunOn {
 			if !util.SliceContains(distroIds, distroId) {
 				errs = append(errs,
@@ -391,6 +429,49 @@ func ensureReferentialIntegrity(project *model.Project) []ValidationError {
 	return errs
 }
 
+// usesLabels returns whether any buildvariant or task of the project asks
+// for distros by label.
+func usesLabels(project *model.Project) bool {
+	for _, buildVariant := range project.BuildVariants {
+		if len(buildVariant.RunOnLabels) != 0 {
+			return true
+		}
+		for _, task := range buildVariant.Tasks {
+			if len(task.Labels) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateLabels checks that the labels asked for by the named buildvariant
+// or task are well formed, and that one of the distros has them all.
+func validateLabels(distros []distro.Distro, labels []string, owner string) []ValidationError {
+	if len(labels) == 0 {
+		return nil
+	}
+	errs := []ValidationError{}
+	for _, label := range labels {
+		if _, _, err := distro.ParseLabel(label); err != nil {
+			errs = append(errs,
+				ValidationError{
+					Message: fmt.Sprintf("%v has an invalid label: %v", owner, err),
+				},
+			)
+		}
+	}
+	if len(errs) == 0 && len(distro.MatchLabels(distros, labels)) == 0 {
+		errs = append(errs,
+			ValidationError{
+				Message: fmt.Sprintf("%v asks for labels '%v', which no distro "+
+					"has", owner, strings.Join(labels, "', '")),
+			},
+		)
+	}
+	return errs
+}
+
 // Ensures there aren't any duplicate buildvariant names specified in the given
 // project
 func validateBVNames(project *model.Project) []ValidationError {
@@ -546,6 +627,12 @@ func validatePluginCommands(project *model.Project) []ValidationError {
 		errs = append(errs, validateCommands("timeout", project, pluginRegistry, project.Timeout.List())...)
 	}
 
+	if project.TimeoutDiagnostics != nil && project.TimeoutDiagnostics.Commands != nil {
+		// validate project timeout diagnostics section
+		errs = append(errs, validateCommands("timeout_diagnostics", project, pluginRegistry,
+			project.TimeoutDiagnostics.Commands.List())...)
+	}
+
 	// validate project tasks section
 	for _, task := range project.Tasks {
 		errs = append(errs, validateCommands("tasks", project, pluginRegistry, task.Commands)...)
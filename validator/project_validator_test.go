@@ -738,6 +738,65 @@ func TestEnsureReferentialIntegrity(t *testing.T) {
 			}
 			So(ensureReferentialIntegrity(project), ShouldResemble, []ValidationError{})
 		})
+
+		Convey("no error should be thrown if some distro has the labels a"+
+			" buildvariant or task asks for", func() {
+			d := distro.Distro{Id: "rhel55", Labels: []string{"os:linux"}}
+			So(d.Insert(), ShouldBeNil)
+			project := &model.Project{
+				Tasks: []model.ProjectTask{{Name: "compile"}},
+				BuildVariants: []model.BuildVariant{
+					{
+						Name:        "enterprise",
+						RunOnLabels: []string{"os:linux"},
+						Tasks: []model.BuildVariantTask{
+							{Name: "compile", Labels: []string{"os:linux"}},
+						},
+					},
+				},
+			}
+			So(ensureReferentialIntegrity(project), ShouldResemble, []ValidationError{})
+		})
+
+		Convey("an error should be thrown if no distro has the labels a"+
+			" buildvariant asks for, or they are malformed", func() {
+			d := distro.Distro{Id: "rhel55", Labels: []string{"os:linux"}}
+			So(d.Insert(), ShouldBeNil)
+			project := &model.Project{
+				BuildVariants: []model.BuildVariant{
+					{
+						Name:        "enterprise",
+						RunOnLabels: []string{"os:windows"},
+					},
+					{
+						Name:        "legacy",
+						RunOnLabels: []string{"linux"},
+					},
+				},
+			}
+			So(len(ensureReferentialIntegrity(project)), ShouldEqual, 2)
+		})
+
+		Reset(func() {
+			db.Clear(distro.Collection)
+		})
+	})
+}
+
+func TestValidateLabels(t *testing.T) {
+	Convey("When validating the labels a task asks for", t, func() {
+		distros := []distro.Distro{{Id: "rhel55", Labels: []string{"os:linux", "arch:x86_64"}}}
+
+		Convey("labels some distro has should be valid", func() {
+			So(validateLabels(distros, []string{"os:linux", "arch:x86_64"}, "task"),
+				ShouldResemble, []ValidationError{})
+		})
+
+		Convey("labels no distro has all of should be invalid", func() {
+			So(len(validateLabels(distros, []string{"os:linux", "arch:arm"}, "task")),
+				ShouldEqual, 1)
+			So(len(validateLabels(nil, []string{"os:linux"}, "task")), ShouldEqual, 1)
+		})
 	})
 }
 
@@ -970,6 +1029,22 @@ func TestValidatePluginCommands(t *testing.T) {
 
 			So(validatePluginCommands(project), ShouldResemble, []ValidationError{})
 		})
+		Convey("an error should be thrown if a referenced timeout diagnostics plugin command is invalid", func() {
+			project := &model.Project{
+				TimeoutDiagnostics: &model.TimeoutDiagnostics{
+					Commands: &model.YAMLCommandSet{
+						MultiCommand: []model.PluginCommandConf{
+							{
+								Function: "",
+								Command:  "gotest.run",
+								Params:   map[string]interface{}{},
+							},
+						},
+					},
+				},
+			}
+			So(len(validatePluginCommands(project)), ShouldEqual, 1)
+		})
 		Convey("no error should be thrown if a referenced plugin for a task does exist", func() {
 			project := &model.Project{
 				Tasks: []model.ProjectTask{
@@ -1211,6 +1286,16 @@ func TestEnsureHasNecessaryProjectFields(t *testing.T) {
 					So(len(ensureHasNecessaryProjectFields(project)),
 						ShouldEqual, 1)
 				})
+				Convey("an error should be thrown for each block timeout "+
+					"set to a negative value", func() {
+					project := &model.Project{
+						PreTimeoutSecs:      -1,
+						PostTimeoutSecs:     60,
+						CallbackTimeoutSecs: -60,
+					}
+					So(len(ensureHasNecessaryProjectFields(project)),
+						ShouldEqual, 2)
+				})
 			})
 		})
 }